@@ -24,32 +24,48 @@ const (
 	pluginMountPath      = "/var/lib/kubelet/device-plugins"
 	kubeletEndpoint      = "kubelet.sock"
 	pluginEndpointPrefix = "rmddp"
-	resourceName         = "intel.com/l3_cache_ways"
 	localHostAdd         = "127.0.0.1"
 	httpPrefix           = "http"
 	httpsPrefix          = "https"
 	guaranteedPool       = "guaranteed"
+	besteffortPool       = "besteffort"
 )
 
+// resourceNames are the extended resources advertised for the cache ways of each RMD pool
+var resourceNames = map[string]string{
+	guaranteedPool: "intel.com/l3_cache_ways",
+	besteffortPool: "intel.com/l3_cache_ways_besteffort",
+}
+
 type pluginManager struct {
-	rmdClient   *rmd.OperatorRmdClient
-	socketFile  string
-	devices     map[string]*pluginapi.Device
-	deviceFiles []string
-	grpcServer  *grpc.Server
+	rmdClient    *rmd.OperatorRmdClient
+	pool         string
+	resourceName string
+	socketFile   string
+	devices      map[string]*pluginapi.Device
+	deviceFiles  []string
+	grpcServer   *grpc.Server
 }
 
-func newPluginManager() *pluginManager {
+// newPluginManager returns the plugin manager advertising the cache ways of pool. The guaranteed
+// pool keeps the socket of previous releases.
+func newPluginManager(pool string) *pluginManager {
+	socketFile := fmt.Sprintf("%s.sock", pluginEndpointPrefix)
+	if pool != guaranteedPool {
+		socketFile = fmt.Sprintf("%s-%s.sock", pluginEndpointPrefix, pool)
+	}
 	return &pluginManager{
-		rmdClient:   rmd.NewClient(),
-		socketFile:  fmt.Sprintf("%s.sock", pluginEndpointPrefix),
-		devices:     make(map[string]*pluginapi.Device),
-		deviceFiles: []string{"/root/otherpmdevice"},
+		rmdClient:    rmd.NewClient(),
+		pool:         pool,
+		resourceName: resourceNames[pool],
+		socketFile:   socketFile,
+		devices:      make(map[string]*pluginapi.Device),
+		deviceFiles:  []string{"/root/otherpmdevice"},
 	}
 }
 
 func (pm *pluginManager) discoverResources() error {
-	devices, err := pm.rmdClient.GetGuaranteedCacheWayPools()
+	devices, err := pm.rmdClient.GetCacheWayPools(pm.pool)
 	if err != nil {
 		return err
 	}
@@ -67,7 +83,7 @@ func (pm *pluginManager) GetDeviceState(DeviceName string) string {
 }
 
 func (pm *pluginManager) Start() error {
-	log.Printf("Discovering RMD guaranteed cache way[s]")
+	log.Printf("Discovering RMD %s cache way[s]", pm.pool)
 	if err := pm.discoverResources(); err != nil {
 		return err
 	}
@@ -209,7 +225,7 @@ func (pm *pluginManager) Allocate(ctx context.Context, rqt *pluginapi.AllocateRe
 			ids = ids + "{" + id + ", " + strconv.Itoa(int(dev.Topology.Nodes[0].ID)) + "}"
 		}
 		envmap := make(map[string]string)
-		envmap[resourceName] = ids
+		envmap[pm.resourceName] = ids
 		containerResp.Envs = envmap
 		resp.ContainerResponses = append(resp.ContainerResponses, containerResp)
 	}
@@ -219,37 +235,45 @@ func (pm *pluginManager) Allocate(ctx context.Context, rqt *pluginapi.AllocateRe
 func main() {
 	flag.Parse()
 	log.Printf("Starting Device Plugin...")
-	pm := newPluginManager()
-	if pm == nil {
-		log.Printf("Unable to get instance")
-		return
+	pms := make([]*pluginManager, 0)
+	for _, pool := range []string{guaranteedPool, besteffortPool} {
+		pm := newPluginManager(pool)
+		if pm == nil {
+			log.Printf("Unable to get instance")
+			return
+		}
+		pm.cleanup()
+		pms = append(pms, pm)
 	}
-	pm.cleanup()
 
 	// respond to syscalls for termination
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
-	// Start server
-	if err := pm.Start(); err != nil {
-		log.Printf("Device plugin Start() failed with error %v", err)
-		return
-	}
-	log.Printf("Started RMD device plugin...")
+	for _, pm := range pms {
+		// Start server
+		if err := pm.Start(); err != nil {
+			log.Printf("Device plugin Start() failed with error %v", err)
+			return
+		}
+		log.Printf("Started RMD device plugin for %s...", pm.resourceName)
 
-	// Registers with Kubelet.
-	err := Register(path.Join(pluginMountPath, kubeletEndpoint), pm.socketFile, resourceName)
-	if err != nil {
-		log.Printf("Device Plugin failed to register with the Kubelet. Error: %v", err)
-		return
+		// Registers with Kubelet.
+		err := Register(path.Join(pluginMountPath, kubeletEndpoint), pm.socketFile, pm.resourceName)
+		if err != nil {
+			log.Printf("Device Plugin failed to register with the Kubelet. Error: %v", err)
+			return
+		}
+		log.Printf("Device Plugin registered with the Kubelet for %s", pm.resourceName)
 	}
-	log.Printf("Device Plugin registered with the Kubelet")
 
 	// Catch termination signals
 	select {
 	case sig := <-sigCh:
 		log.Printf("Received signal %v", sig)
-		pm.Stop()
+		for _, pm := range pms {
+			pm.Stop()
+		}
 		return
 	}
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/intel/rmd-operator/pkg/apis"
+	"github.com/intel/rmd-operator/pkg/kubectlrmd"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const usage = `Inspect RDT resources managed by the RMD operator.
+
+Usage:
+  kubectl rmd nodes                     Show l3 cache capacity and usage per node
+  kubectl rmd workloads [-n namespace]  Show the state of RmdWorkloads per node
+  kubectl rmd describe node <node>      Draw the core and cache way bitmaps of a node
+  kubectl rmd why <rmdworkload> [-n namespace]
+                                        Explain why an RmdWorkload is not applied
+  kubectl rmd validate -f <rmdworkloads.yaml> --inventory [<node>=]<file> [-o json|yaml]
+                                        Validate RmdWorkloads against an inventory snapshot
+                                        without cluster access
+  kubectl rmd backup                    Write RmdConfigs, RmdWorkloads and the workloads on
+                                        RMD of each node to stdout as YAML
+  kubectl rmd restore -f <backup.yaml> [--timeout duration]
+                                        Recreate RmdConfigs and RmdWorkloads from a backup and
+                                        report differences between the backup and live RMD state
+
+Flags:
+`
+
+func main() {
+	flags := pflag.NewFlagSet("kubectl-rmd", pflag.ExitOnError)
+	// Add the --kubeconfig flag registered by controller-runtime
+	flags.AddGoFlagSet(flag.CommandLine)
+	namespace := flags.StringP("namespace", "n", "", "Namespace of RmdWorkloads, all namespaces if not set. Defaults to \"default\" for why.")
+	filenames := flags.StringSliceP("filename", "f", nil, "RmdWorkload YAML or JSON files to validate, or the backup to restore.")
+	inventory := flags.StringSlice("inventory", nil, "Inventory snapshot files of Nodes and RmdNodeStates, or <node>=<file> of RMD /v1/cache/l3 JSON.")
+	output := flags.StringP("output", "o", "json", "Output format of validate, json or yaml.")
+	timeout := flags.Duration("timeout", 2*time.Minute, "Time restore waits for the live RMD state to match the backup.")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if args[0] == "validate" && len(args) == 1 {
+		valid, err := validate(*filenames, *inventory, *namespace, *output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+		return
+	}
+
+	c, err := newClient()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch {
+	case args[0] == "nodes" && len(args) == 1:
+		err = kubectlrmd.Nodes(c, os.Stdout)
+	case args[0] == "workloads" && len(args) == 1:
+		err = kubectlrmd.Workloads(c, *namespace, os.Stdout)
+	case args[0] == "describe" && len(args) == 3 && args[1] == "node":
+		err = kubectlrmd.DescribeNode(c, args[2], os.Stdout)
+	case args[0] == "why" && len(args) == 2:
+		if *namespace == "" {
+			*namespace = "default"
+		}
+		err = kubectlrmd.Why(c, *namespace, args[1], os.Stdout)
+	case args[0] == "backup" && len(args) == 1:
+		err = kubectlrmd.Backup(c, os.Stdout)
+	case args[0] == "restore" && len(args) == 1 && len(*filenames) == 1:
+		var archive *kubectlrmd.Archive
+		archive, err = kubectlrmd.LoadArchive((*filenames)[0])
+		if err == nil {
+			err = kubectlrmd.Restore(c, archive, *timeout, os.Stdout)
+		}
+	default:
+		flags.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// validate writes the results of validating the RmdWorkloads of filenames against the inventory
+// snapshot and returns true if all RmdWorkloads are valid
+func validate(filenames []string, inventory []string, namespace string, output string) (bool, error) {
+	if len(filenames) == 0 || len(inventory) == 0 {
+		return false, fmt.Errorf("validate requires --filename and --inventory")
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	rmdWorkloads, err := kubectlrmd.LoadRmdWorkloads(filenames, namespace)
+	if err != nil {
+		return false, err
+	}
+	snapshot, err := kubectlrmd.LoadSnapshot(inventory)
+	if err != nil {
+		return false, err
+	}
+	results := kubectlrmd.Validate(rmdWorkloads, snapshot)
+	err = kubectlrmd.WriteValidationResults(results, output, os.Stdout)
+	if err != nil {
+		return false, err
+	}
+	for _, result := range results {
+		if !result.Valid {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func newClient() (client.Client, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: s})
+}
@@ -14,7 +14,9 @@ import (
 
 	"github.com/intel/rmd-operator/pkg/apis"
 	"github.com/intel/rmd-operator/pkg/controller"
+	"github.com/intel/rmd-operator/pkg/controller/rmdworkload"
 	"github.com/intel/rmd-operator/pkg/rmd"
+	"github.com/intel/rmd-operator/pkg/schedulerextender"
 	"github.com/intel/rmd-operator/pkg/state"
 	"github.com/intel/rmd-operator/version"
 
@@ -35,9 +37,11 @@ import (
 
 // Change below variables to serve metrics on different host or port.
 var (
-	metricsHost               = "0.0.0.0"
-	metricsPort         int32 = 8383
-	operatorMetricsPort int32 = 8686
+	metricsHost                 = "0.0.0.0"
+	metricsPort           int32 = 8383
+	operatorMetricsPort   int32 = 8686
+	webhookPort                 = 9443
+	schedulerExtenderPort       = 8888
 )
 var log = logf.Log.WithName("cmd")
 
@@ -57,6 +61,10 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	enableWebhook := pflag.Bool("enable-admission-webhook", false, "Serve the RmdWorkload validating admission webhook")
+	webhookCertDir := pflag.String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Directory containing tls.crt and tls.key for the admission webhook")
+	enableSchedulerExtender := pflag.Bool("enable-scheduler-extender", false, "Serve the scheduler extender placing pods requesting l3 cache ways using the RmdNodeState inventory")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -96,6 +104,8 @@ func main() {
 	mgr, err := manager.New(cfg, manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
+		CertDir:            *webhookCertDir,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -121,6 +131,22 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup the RmdWorkload admission webhook
+	if *enableWebhook {
+		if err := rmdworkload.AddWebhook(mgr, rmdClient, rmdNodeData); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	// Setup the scheduler extender
+	if *enableSchedulerExtender {
+		if err := schedulerextender.Add(mgr, schedulerExtenderPort); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg, namespace)
 
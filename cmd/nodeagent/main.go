@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis"
@@ -14,12 +15,13 @@ import (
 	"k8s.io/client-go/rest"
 
 	"github.com/intel/rmd-operator/pkg/apis"
+	"github.com/intel/rmd-operator/pkg/cpudiscovery"
 	"github.com/intel/rmd-operator/pkg/nodeagent"
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
 	"github.com/intel/rmd-operator/version"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	kubemetrics "github.com/operator-framework/operator-sdk/pkg/kube-metrics"
-	"github.com/operator-framework/operator-sdk/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
@@ -30,6 +32,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 // Change below variables to serve metrics on different host or port.
@@ -56,6 +59,16 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	monitoringInterval := pflag.Duration("monitoring-interval", 0, "Interval at which RmdWorkload monitoring data is reported, 0 disables monitoring")
+	resctrlPath := pflag.String("resctrl-path", nodeagent.DefaultResctrlPath, "Mount point of resctrl on the node")
+	cpuRecheckInterval := pflag.Duration("cpu-recheck-interval", 30*time.Second, "Interval at which container CPUs are re-discovered, 0 disables the recheck")
+	cpuDiscoveryConfig := cpudiscovery.Config{}
+	pflag.StringVar(&cpuDiscoveryConfig.Backend, "cpu-discovery", cpudiscovery.AutoBackend, "Backend discovering container CPUs: podresources, cgroup or auto to fall back to cgroup if the podresources endpoint is unavailable")
+	pflag.StringVar(&cpuDiscoveryConfig.PodResourcesSocket, "podresources-socket", podresourcesclient.DefaultSocket, "Kubelet podresources endpoint")
+	pflag.DurationVar(&cpuDiscoveryConfig.PodResourcesTimeout, "podresources-timeout", podresourcesclient.DefaultTimeout, "Timeout for connecting to the kubelet podresources endpoint")
+	pflag.StringVar(&cpuDiscoveryConfig.CgroupPath, "cgroup-path", cpudiscovery.DefaultCgroupPath, "Mount point of the node's cgroup hierarchy, read by the cgroup backend")
+	pflag.StringVar(&cpuDiscoveryConfig.CPUManagerStateFile, "cpu-manager-state-file", cpudiscovery.DefaultCPUManagerStateFile, "Kubelet CPU manager state file, read by the cgroup backend if the container cgroup cannot be read")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -83,18 +96,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "intel-rmd-cat-node-agent-lock")
-	if err != nil {
-		log.Error(err, "")
+	// The node agent runs on every RMD node and only handles the Pods of its own node,
+	// so there is no leader election.
+	nodeName := os.Getenv(nodeagent.NodeNameEnv)
+	if nodeName == "" {
+		log.Error(fmt.Errorf("%s must be set", nodeagent.NodeNameEnv), "Failed to get node name")
 		os.Exit(1)
 	}
 
-	// Create a new Cmd to provide shared dependencies and start components
+	ctx := context.TODO()
+
+	// Create a new Cmd to provide shared dependencies and start components. Only the Pods
+	// scheduled on the node are cached.
 	mgr, err := manager.New(cfg, manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		NewCache:           nodeagent.NewNodeCacheFunc(nodeName),
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -115,12 +132,36 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup container CPU discovery
+	cpuDiscoverer, err := cpudiscovery.New(cpuDiscoveryConfig, mgr.GetClient())
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
-	if err := nodeagent.AddToManager(mgr); err != nil {
+	if err := nodeagent.AddToManager(mgr, cpuDiscoverer); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Setup the RmdWorkload monitor and per-container RDT metrics
+	ctrlmetrics.Registry.MustRegister(nodeagent.NewRdtExporter(mgr.GetClient(), nodeName, *resctrlPath))
+	if *monitoringInterval > 0 {
+		if err := mgr.Add(nodeagent.NewWorkloadMonitor(mgr.GetClient(), nodeName, *resctrlPath, *monitoringInterval)); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	// Setup the tracker patching RmdWorkloads of containers whose CPUs were reassigned
+	if *cpuRecheckInterval > 0 {
+		if err := mgr.Add(nodeagent.NewCPUTracker(mgr.GetClient(), cpuDiscoverer, nodeName, *cpuRecheckInterval)); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg, namespace)
 
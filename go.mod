@@ -4,10 +4,13 @@ go 1.13
 
 require (
 	github.com/go-bindata/go-bindata v3.1.2+incompatible // indirect
+	github.com/gogo/protobuf v1.3.1
 	github.com/gojp/goreportcard v0.0.0-20200415071653-59167b516f3f // indirect
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
+	github.com/golang/protobuf v1.4.2
 	github.com/intel/rmd v0.0.0-20200911162347-989db48d641c
 	github.com/operator-framework/operator-sdk v0.15.2
+	github.com/prometheus/client_golang v1.2.1
 	github.com/spf13/pflag v1.0.5
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f
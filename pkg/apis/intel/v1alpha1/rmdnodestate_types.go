@@ -10,6 +10,9 @@ import (
 // WorkloadMap stores string values of workload data for RmdNodeStatus
 type WorkloadMap map[string]string
 
+// CacheMap stores string values of cache inventory data for RmdNodeStatus
+type CacheMap map[string]string
+
 // RmdNodeStateSpec defines the desired state of RmdNodeState
 type RmdNodeStateSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -25,6 +28,18 @@ type RmdNodeStateStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 	Workloads map[string]WorkloadMap `json:"workloads"`
+	// L3Caches lists the l3 cache inventory per cache ID as reported by RMD
+	L3Caches map[string]CacheMap `json:"l3Caches,omitempty"`
+	// L2Caches lists the l2 cache inventory per cache ID if l2 cache allocation is supported by RMD
+	L2Caches map[string]CacheMap `json:"l2Caches,omitempty"`
+	// CdpSupported and CdpEnabled report Code and Data Prioritization support and mode of the node
+	CdpSupported bool `json:"cdpSupported,omitempty"`
+	CdpEnabled   bool `json:"cdpEnabled,omitempty"`
+	// MbaSupported and MbaEnabled report Memory Bandwidth Allocation support and mode of the node,
+	// MbaMin is the minimum MBA percentage supported
+	MbaSupported bool `json:"mbaSupported,omitempty"`
+	MbaEnabled   bool `json:"mbaEnabled,omitempty"`
+	MbaMin       int  `json:"mbaMin,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -17,8 +19,25 @@ type Rdt struct {
 type Cache struct {
 	Max int `json:"max,omitempty"`
 	Min int `json:"min,omitempty"`
+	// MaxBytes and MinBytes request cache by size instead of ways. The size is converted to ways
+	// on each node according to Rounding. MinBytes defaults to MaxBytes.
+	MaxBytes *resource.Quantity `json:"maxBytes,omitempty"`
+	MinBytes *resource.Quantity `json:"minBytes,omitempty"`
+	Rounding CacheRounding      `json:"rounding,omitempty"`
 }
 
+// CacheRounding is the policy used to convert cache sizes to ways
+type CacheRounding string
+
+const (
+	// RoundUp requests at least the specified size. This is the default.
+	RoundUp CacheRounding = "Up"
+	// RoundDown requests at most the specified size
+	RoundDown CacheRounding = "Down"
+	// RoundNearest requests the number of ways closest to the specified size
+	RoundNearest CacheRounding = "Nearest"
+)
+
 // Mba defines mba parameters for workload
 type Mba struct {
 	Percentage int `json:"percentage,omitempty"`
@@ -46,6 +65,55 @@ type WorkloadState struct {
 	Policy   string   `json:"policy,omitempty"`
 	Rdt      Rdt      `json:"rdt,omitempty"`
 	Plugins  Plugins  `json:"plugins,omitempty"`
+	// Monitoring is reported by the node agent if workload monitoring is enabled
+	Monitoring *WorkloadMonitoring `json:"monitoring,omitempty"`
+}
+
+// WorkloadMonitoring holds the cache and memory bandwidth monitoring data of a workload on a node
+type WorkloadMonitoring struct {
+	// LlcOccupancy is the l3 cache occupancy of the workload in bytes
+	LlcOccupancy int64 `json:"llcOccupancy"`
+	// LocalBandwidth and TotalBandwidth are the local and total memory bandwidth of the workload
+	// in bytes per second over the last monitoring interval
+	LocalBandwidth int64 `json:"localBandwidth"`
+	TotalBandwidth int64 `json:"totalBandwidth"`
+	// Pstate is the P-State monitoring output reported by RMD
+	Pstate     string      `json:"pstate,omitempty"`
+	LastUpdate metav1.Time `json:"lastUpdate"`
+}
+
+// RmdWorkloadConditionType is a valid value for RmdWorkloadCondition.Type
+type RmdWorkloadConditionType string
+
+const (
+	// InsufficientCapacity means one or more targeted nodes cannot fit the requested cache ways or MBA
+	InsufficientCapacity RmdWorkloadConditionType = "InsufficientCapacity"
+	// Conflict means the workload's cores overlap those of another RmdWorkload on one or more targeted nodes
+	Conflict RmdWorkloadConditionType = "Conflict"
+	// CoresReassigned means the kubelet CPU manager reassigned the CPUs of the workload's container and
+	// the node agent patched the workload's cores
+	CoresReassigned RmdWorkloadConditionType = "CoresReassigned"
+)
+
+// RmdWorkloadCondition describes the state of a RmdWorkload at a certain point
+type RmdWorkloadCondition struct {
+	Type               RmdWorkloadConditionType `json:"type"`
+	Status             corev1.ConditionStatus   `json:"status"`
+	LastTransitionTime metav1.Time              `json:"lastTransitionTime,omitempty"`
+	Reason             string                   `json:"reason,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+}
+
+// RmdWorkloadOverride replaces RmdWorkloadSpec fields on the nodes it targets. Nodes are targeted
+// by name or by labels matching NodeSelector. Only fields that are set are replaced.
+type RmdWorkloadOverride struct {
+	Nodes           []string          `json:"nodes,omitempty"`
+	NodeSelector    map[string]string `json:"nodeSelector,omitempty"`
+	CoreIds         []string          `json:"coreIds,omitempty"`
+	ReservedCoreIds []string          `json:"reservedCoreIds,omitempty"`
+	Cache           *Cache            `json:"cache,omitempty"`
+	Mba             *Mba              `json:"mba,omitempty"`
+	Pstate          *Pstate           `json:"pstate,omitempty"`
 }
 
 // RmdWorkloadSpec defines the desired state of RmdWorkload
@@ -61,6 +129,26 @@ type RmdWorkloadSpec struct {
 	Plugins         Plugins           `json:"plugins,omitempty"`
 	NodeSelector    map[string]string `json:"nodeSelector,omitempty"`
 	Nodes           []string          `json:"nodes,omitempty"`
+	// CoreCount requests the controller to select this many free cores sharing one l3 cache
+	// on each targeted node, instead of listing CoreIds. The selection can be restricted to a
+	// NUMA node or l3 cache ID. The selected cores are recorded in the WorkloadState of each node.
+	CoreCount int  `json:"coreCount,omitempty"`
+	NumaNode  *int `json:"numaNode,omitempty"`
+	CacheID   *int `json:"cacheId,omitempty"`
+	// Overrides are applied in order to the nodes they target, later overrides take precedence.
+	Overrides []RmdWorkloadOverride `json:"overrides,omitempty"`
+	// MaxNodes limits a NodeSelector workload to this many matching nodes. The nodes with the most
+	// free guaranteed l3 cache ways are selected and kept until they no longer match or cannot fit
+	// the workload. The selected nodes are recorded in SelectedNodes of the status.
+	MaxNodes *int `json:"maxNodes,omitempty"`
+}
+
+// CacheWayAllocation records the l3 cache way devices allocated by the device plugin on a NUMA node
+// and the workload's cores on that node. Ways and cores on the same node are aligned.
+type CacheWayAllocation struct {
+	NumaNode  int64    `json:"numaNode"`
+	DeviceIds []string `json:"deviceIds,omitempty"`
+	CoreIds   []string `json:"coreIds,omitempty"`
 }
 
 // RmdWorkloadStatus defines the observed state of RmdWorkload
@@ -69,6 +157,12 @@ type RmdWorkloadStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 	WorkloadStates map[string]WorkloadState `json:"workloadStates,omitempty"`
+	Conditions     []RmdWorkloadCondition   `json:"conditions,omitempty"`
+	// SelectedNodes are the nodes selected for a workload limited by MaxNodes
+	SelectedNodes []string `json:"selectedNodes,omitempty"`
+	// CacheWayAllocations map the cache ways allocated to the container of a workload created from a
+	// pod spec to NUMA nodes. They are set by the node agent from the device plugin topology.
+	CacheWayAllocations []CacheWayAllocation `json:"cacheWayAllocations,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
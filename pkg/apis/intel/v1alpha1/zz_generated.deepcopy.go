@@ -11,6 +11,16 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cache) DeepCopyInto(out *Cache) {
 	*out = *in
+	if in.MaxBytes != nil {
+		in, out := &in.MaxBytes, &out.MaxBytes
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinBytes != nil {
+		in, out := &in.MinBytes, &out.MinBytes
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	return
 }
 
@@ -24,6 +34,54 @@ func (in *Cache) DeepCopy() *Cache {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in CacheMap) DeepCopyInto(out *CacheMap) {
+	{
+		in := &in
+		*out = make(CacheMap, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheMap.
+func (in CacheMap) DeepCopy() CacheMap {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheMap)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CacheWayAllocation) DeepCopyInto(out *CacheWayAllocation) {
+	*out = *in
+	if in.DeviceIds != nil {
+		in, out := &in.DeviceIds, &out.DeviceIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CoreIds != nil {
+		in, out := &in.CoreIds, &out.CoreIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheWayAllocation.
+func (in *CacheWayAllocation) DeepCopy() *CacheWayAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheWayAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mba) DeepCopyInto(out *Mba) {
 	*out = *in
@@ -76,7 +134,7 @@ func (in *Pstate) DeepCopy() *Pstate {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rdt) DeepCopyInto(out *Rdt) {
 	*out = *in
-	out.Cache = in.Cache
+	in.Cache.DeepCopyInto(&out.Cache)
 	out.Mba = in.Mba
 	return
 }
@@ -293,6 +351,40 @@ func (in *RmdNodeStateStatus) DeepCopyInto(out *RmdNodeStateStatus) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.L3Caches != nil {
+		in, out := &in.L3Caches, &out.L3Caches
+		*out = make(map[string]CacheMap, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(CacheMap, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.L2Caches != nil {
+		in, out := &in.L2Caches, &out.L2Caches
+		*out = make(map[string]CacheMap, len(*in))
+		for key, val := range *in {
+			var outVal map[string]string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(CacheMap, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
 	return
 }
 
@@ -334,6 +426,23 @@ func (in *RmdWorkload) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RmdWorkloadCondition) DeepCopyInto(out *RmdWorkloadCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RmdWorkloadCondition.
+func (in *RmdWorkloadCondition) DeepCopy() *RmdWorkloadCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(RmdWorkloadCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RmdWorkloadList) DeepCopyInto(out *RmdWorkloadList) {
 	*out = *in
@@ -367,6 +476,59 @@ func (in *RmdWorkloadList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RmdWorkloadOverride) DeepCopyInto(out *RmdWorkloadOverride) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CoreIds != nil {
+		in, out := &in.CoreIds, &out.CoreIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReservedCoreIds != nil {
+		in, out := &in.ReservedCoreIds, &out.ReservedCoreIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(Cache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mba != nil {
+		in, out := &in.Mba, &out.Mba
+		*out = new(Mba)
+		**out = **in
+	}
+	if in.Pstate != nil {
+		in, out := &in.Pstate, &out.Pstate
+		*out = new(Pstate)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RmdWorkloadOverride.
+func (in *RmdWorkloadOverride) DeepCopy() *RmdWorkloadOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(RmdWorkloadOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RmdWorkloadSpec) DeepCopyInto(out *RmdWorkloadSpec) {
 	*out = *in
@@ -380,7 +542,7 @@ func (in *RmdWorkloadSpec) DeepCopyInto(out *RmdWorkloadSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	out.Rdt = in.Rdt
+	in.Rdt.DeepCopyInto(&out.Rdt)
 	out.Plugins = in.Plugins
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
@@ -394,6 +556,28 @@ func (in *RmdWorkloadSpec) DeepCopyInto(out *RmdWorkloadSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NumaNode != nil {
+		in, out := &in.NumaNode, &out.NumaNode
+		*out = new(int)
+		**out = **in
+	}
+	if in.CacheID != nil {
+		in, out := &in.CacheID, &out.CacheID
+		*out = new(int)
+		**out = **in
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]RmdWorkloadOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MaxNodes != nil {
+		in, out := &in.MaxNodes, &out.MaxNodes
+		*out = new(int)
+		**out = **in
+	}
 	return
 }
 
@@ -417,6 +601,25 @@ func (in *RmdWorkloadStatus) DeepCopyInto(out *RmdWorkloadStatus) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]RmdWorkloadCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SelectedNodes != nil {
+		in, out := &in.SelectedNodes, &out.SelectedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CacheWayAllocations != nil {
+		in, out := &in.CacheWayAllocations, &out.CacheWayAllocations
+		*out = make([]CacheWayAllocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -452,6 +655,23 @@ func (in WorkloadMap) DeepCopy() WorkloadMap {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadMonitoring) DeepCopyInto(out *WorkloadMonitoring) {
+	*out = *in
+	in.LastUpdate.DeepCopyInto(&out.LastUpdate)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadMonitoring.
+func (in *WorkloadMonitoring) DeepCopy() *WorkloadMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadState) DeepCopyInto(out *WorkloadState) {
 	*out = *in
@@ -460,8 +680,13 @@ func (in *WorkloadState) DeepCopyInto(out *WorkloadState) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	out.Rdt = in.Rdt
+	in.Rdt.DeepCopyInto(&out.Rdt)
 	out.Plugins = in.Plugins
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(WorkloadMonitoring)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
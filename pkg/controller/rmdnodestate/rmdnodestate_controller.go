@@ -7,6 +7,7 @@ import (
 	"time"
 
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/metrics"
 	"github.com/intel/rmd-operator/pkg/rmd"
 	"github.com/intel/rmd-operator/pkg/state"
 	"github.com/intel/rmd-operator/pkg/util"
@@ -103,6 +104,7 @@ func (r *ReconcileRmdNodeState) Reconcile(request reconcile.Request) (reconcile.
 			// Remove associated RmdNodeData entry
 			nodeName := strings.ReplaceAll(request.Name, "rmd-node-state-", "")
 			r.rmdNodeData.DeleteRmdNodeData(nodeName)
+			metrics.DeleteNode(nodeName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -139,10 +141,20 @@ func (r *ReconcileRmdNodeState) Reconcile(request reconcile.Request) (reconcile.
 	}
 	addressPrefix := r.rmdClient.GetAddressPrefix()
 	address := fmt.Sprintf("%s%s%s%d", addressPrefix, rmdPod.Status.PodIP, ":", rmdPod.Spec.Containers[0].Ports[0].ContainerPort)
+	metrics.SetRmdNodeAddress(rmdNodeState.Spec.Node, address)
 
+	rmdNodeState.Status.L3Caches = nil
 	existingWorkloads, err := r.rmdClient.GetWorkloads(address)
 	if err != nil {
 		reqLogger.Info("Could not GET workloads.", "Error:", err)
+		metrics.SetNodeDown(rmdNodeState.Spec.Node)
+	} else {
+		allCacheInfo, err := r.rmdClient.GetCacheInfo(address)
+		if err != nil {
+			reqLogger.Info("Could not GET cache info.", "Error:", err)
+		}
+		metrics.SetNodeInventory(rmdNodeState.Spec.Node, allCacheInfo, existingWorkloads)
+		rmdNodeState.Status.L3Caches = rmd.UpdateNodeStatusCaches(allCacheInfo)
 	}
 
 	workloadMap := make(map[string]intelv1alpha1.WorkloadMap)
@@ -155,6 +167,28 @@ func (r *ReconcileRmdNodeState) Reconcile(request reconcile.Request) (reconcile.
 
 	rmdNodeState.Status.Workloads = workloadMap
 
+	// L2 cache inventory is only reported by RMD instances supporting l2 cache allocation.
+	rmdNodeState.Status.L2Caches = nil
+	l2CacheInfo, err := r.rmdClient.GetL2CacheInfo(address)
+	if err == nil {
+		rmdNodeState.Status.L2Caches = rmd.UpdateNodeStatusCaches(l2CacheInfo)
+	}
+
+	cachesSummary, err := r.rmdClient.GetCachesSummary(address)
+	if err != nil {
+		reqLogger.Info("Could not GET cache summary.", "Error:", err)
+	}
+	rmdNodeState.Status.CdpSupported = cachesSummary.Cdp
+	rmdNodeState.Status.CdpEnabled = cachesSummary.CdpOn
+
+	mbaInfo, err := r.rmdClient.GetMbaInfo(address)
+	if err != nil {
+		reqLogger.Info("Could not GET MBA info.", "Error:", err)
+	}
+	rmdNodeState.Status.MbaSupported = mbaInfo.Mba
+	rmdNodeState.Status.MbaEnabled = mbaInfo.MbaOn
+	rmdNodeState.Status.MbaMin = mbaInfo.MbaMin
+
 	err = r.client.Status().Update(context.TODO(), rmdNodeState)
 	if err != nil {
 		reqLogger.Error(err, "Failed to update RmdNodeState")
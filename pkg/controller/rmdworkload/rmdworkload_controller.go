@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/metrics"
 	rmd "github.com/intel/rmd-operator/pkg/rmd"
 	"github.com/intel/rmd-operator/pkg/state"
 	"github.com/intel/rmd-operator/pkg/util"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -20,6 +25,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -27,6 +34,8 @@ const (
 	defaultNamespace = "default"
 	rmdPodNameConst  = "rmd-pod"
 	rmdConfigConst   = "rmdconfig"
+	pendingConst     = "Pending"
+	conflictConst    = "Conflict"
 )
 
 var log = logf.Log.WithName("controller_rmdworkload")
@@ -122,6 +131,7 @@ func (r *ReconcileRmdWorkload) Reconcile(request reconcile.Request) (reconcile.R
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found (i.e. deleted)
+			metrics.DeleteWorkload(request.Namespace, request.Name)
 			obseleteWorkloads, err := r.findObseleteWorkloads(request)
 			if err != nil {
 				return reconcile.Result{}, err
@@ -147,7 +157,57 @@ func (r *ReconcileRmdWorkload) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, err
 	}
 
+	// Workloads whose cores are already owned by another RmdWorkload on a node, or that
+	// do not fit into a node's free cache ways or MBA capabilities, are not sent to RMD.
+	// They are marked as conflicting or pending on that node instead.
+	conflictingNodes := make(map[string]string)
+	insufficientNodes := make(map[string]string)
 	for _, targetedNode := range targetedNodes {
+		// Cache requested by size is converted to the node's cache ways on every reconcile.
+		if rmd.CacheRequestedInBytes(r.applyOverrides(rmdWorkload, targetedNode.nodeName).Spec.Rdt.Cache) {
+			maxWays, minWays, err := r.resolveCacheWays(rmdWorkload, targetedNode.nodeName, targetedNode.rmdAddress)
+			if err != nil {
+				reqLogger.Info("Could not resolve cache size to ways on node, mark pending.", "node", targetedNode.nodeName, "reason", err.Error())
+				insufficientNodes[targetedNode.nodeName] = err.Error()
+				setWorkloadState(rmdWorkload, targetedNode.nodeName, pendingConst, err.Error())
+				continue
+			}
+			setWorkloadCacheWays(rmdWorkload, targetedNode.nodeName, maxWays, minWays)
+		}
+		// Cores are selected once per node for coreCount workloads and kept while the
+		// workload exists on the node's RMD instance.
+		if r.applyOverrides(rmdWorkload, targetedNode.nodeName).Spec.CoreCount != 0 && (!targetedNode.workloadExists || len(rmdWorkload.Status.WorkloadStates[targetedNode.nodeName].CoreIds) == 0) {
+			coreIDs, err := r.selectCores(rmdWorkload, targetedNode.nodeName, targetedNode.rmdAddress)
+			if err != nil {
+				reqLogger.Info("No cores available for workload on node, mark pending.", "node", targetedNode.nodeName, "reason", err.Error())
+				insufficientNodes[targetedNode.nodeName] = err.Error()
+				setWorkloadState(rmdWorkload, targetedNode.nodeName, pendingConst, err.Error())
+				continue
+			}
+			setWorkloadCores(rmdWorkload, targetedNode.nodeName, coreIDs)
+		}
+		conflicts, err := r.findCoreConflicts(rmdWorkload, targetedNode.nodeName, targetedNode.rmdAddress)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(conflicts) != 0 {
+			reason := conflictReason(conflicts)
+			reqLogger.Info("Workload cores owned by other RmdWorkloads on node, mark conflict.", "node", targetedNode.nodeName, "reason", reason)
+			conflictingNodes[targetedNode.nodeName] = reason
+			setWorkloadState(rmdWorkload, targetedNode.nodeName, conflictConst, reason)
+			continue
+		}
+		err = r.checkCapacity(targetedNode, rmdWorkload)
+		if errors.IsServiceUnavailable(err) {
+			reqLogger.Info("RMD inventory not available, requeue.", "node", targetedNode.nodeName, "reason", err.Error())
+			return reconcile.Result{}, err
+		}
+		if err != nil {
+			reqLogger.Info("Workload does not fit on node, mark pending.", "node", targetedNode.nodeName, "reason", err.Error())
+			insufficientNodes[targetedNode.nodeName] = err.Error()
+			setWorkloadState(rmdWorkload, targetedNode.nodeName, pendingConst, err.Error())
+			continue
+		}
 		if !targetedNode.workloadExists {
 			reqLogger.Info("Workload not found on RMD instance, create.")
 			err := r.addWorkload(targetedNode.rmdAddress, rmdWorkload, targetedNode.nodeName)
@@ -173,11 +233,15 @@ func (r *ReconcileRmdWorkload) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, err
 	}
 
+	setConflictCondition(&rmdWorkload.Status, conflictingNodes)
+	setCapacityCondition(&rmdWorkload.Status, insufficientNodes)
+
 	err = r.removeWorkload(rmdWorkload, removedNodes)
 	if err != nil {
 		reqLogger.Error(err, "Failed to remove workload")
 		return reconcile.Result{}, err
 	}
+	metrics.SetWorkloadStates(rmdWorkload)
 
 	// Requeue after 60 seconds. This is to account a change in node labels.
 	// Should a node no longer possess the feature label(s) specified in the
@@ -281,9 +345,104 @@ func (r *ReconcileRmdWorkload) findTargetedNodes(request reconcile.Request, rmdW
 		targetedNodes = append(targetedNodes, targetedNode)
 	}
 
+	if rmdWorkload.Spec.MaxNodes != nil {
+		targetedNodes = r.selectNodes(rmdWorkload, targetedNodes)
+	}
+
 	return targetedNodes, nil
 }
 
+// selectNodes limits the nodes matching the RmdWorkload NodeSelector to Spec.MaxNodes. Nodes selected
+// by a previous reconcile are kept unless the workload is pending on them. Free places are filled
+// with the nodes with the most free guaranteed l3 cache ways, nodes the workload is moved off are
+// only selected again if no other node is left. The selection is recorded in the RmdWorkload status.
+func (r *ReconcileRmdWorkload) selectNodes(rmdWorkload *intelv1alpha1.RmdWorkload, matchingNodes []targetedNodeInfo) []targetedNodeInfo {
+	logger := log.WithName("selectNodes")
+	maxNodes := *rmdWorkload.Spec.MaxNodes
+
+	matching := make(map[string]targetedNodeInfo)
+	for _, matchingNode := range matchingNodes {
+		matching[matchingNode.nodeName] = matchingNode
+	}
+	selectedNodes := make([]targetedNodeInfo, 0)
+	selected := make(map[string]bool)
+	movedOff := make(map[string]bool)
+	for _, nodeName := range rmdWorkload.Status.SelectedNodes {
+		matchingNode, ok := matching[nodeName]
+		if !ok {
+			// Node no longer matches the NodeSelector.
+			continue
+		}
+		if len(selectedNodes) == maxNodes || rmdWorkload.Status.WorkloadStates[nodeName].Status == pendingConst {
+			movedOff[nodeName] = true
+			continue
+		}
+		selectedNodes = append(selectedNodes, matchingNode)
+		selected[nodeName] = true
+	}
+
+	if len(selectedNodes) < maxNodes {
+		candidates := make([]targetedNodeInfo, 0)
+		freeWays := make(map[string]int)
+		for _, matchingNode := range matchingNodes {
+			if selected[matchingNode.nodeName] {
+				continue
+			}
+			allCacheInfo, err := r.rmdClient.GetCacheInfo(matchingNode.rmdAddress)
+			if err != nil {
+				logger.Info("Could not GET cache info, rank node last.", "node", matchingNode.nodeName, "Error:", err)
+			}
+			freeWays[matchingNode.nodeName] = rmd.FreeGuaranteedWays(allCacheInfo)
+			candidates = append(candidates, matchingNode)
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			iName, jName := candidates[i].nodeName, candidates[j].nodeName
+			if movedOff[iName] != movedOff[jName] {
+				return !movedOff[iName]
+			}
+			if freeWays[iName] != freeWays[jName] {
+				return freeWays[iName] > freeWays[jName]
+			}
+			return iName < jName
+		})
+		for _, candidate := range candidates {
+			if len(selectedNodes) == maxNodes {
+				break
+			}
+			selectedNodes = append(selectedNodes, candidate)
+			selected[candidate.nodeName] = true
+		}
+	}
+
+	// Nodes not selected are removed from RMD by findRemovedNodes. The state of nodes the
+	// workload was never sent to is dropped here.
+	for _, matchingNode := range matchingNodes {
+		if !selected[matchingNode.nodeName] && !matchingNode.workloadExists {
+			delete(rmdWorkload.Status.WorkloadStates, matchingNode.nodeName)
+		}
+	}
+	rmdWorkload.Status.SelectedNodes = make([]string, 0)
+	for _, selectedNode := range selectedNodes {
+		rmdWorkload.Status.SelectedNodes = append(rmdWorkload.Status.SelectedNodes, selectedNode.nodeName)
+	}
+	sort.Strings(rmdWorkload.Status.SelectedNodes)
+	return selectedNodes
+}
+
+// nodeSelected returns true if nodeName is selected for the RmdWorkload, ie the workload is not
+// limited by MaxNodes or nodeName is one of its SelectedNodes.
+func nodeSelected(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) bool {
+	if rmdWorkload.Spec.MaxNodes == nil {
+		return true
+	}
+	for _, selectedNode := range rmdWorkload.Status.SelectedNodes {
+		if selectedNode == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
 // findRemovedNodes finds Nodes that have the reconciled workload actively running, but those Nodes have been
 // removed from the RmdWorkload spec. Such instances are returned as a map of address (of RMD Pod) to workload
 // ID so that the workload can be deleted from RMD.
@@ -327,7 +486,8 @@ func (r *ReconcileRmdWorkload) findRemovedNodes(request reconcile.Request, rmdWo
 				continue
 			} else {
 				// Check if this Node's labels still match those specified in the
-				// reconciled RmdWorkload NodeSelector field.
+				// reconciled RmdWorkload NodeSelector field and the Node is still
+				// selected for a workload limited by MaxNodes.
 				// If not, append details to 'removedNodes'.
 				node := &corev1.Node{}
 				err := r.client.Get(context.TODO(), types.NamespacedName{Name: nodeName}, node)
@@ -336,7 +496,7 @@ func (r *ReconcileRmdWorkload) findRemovedNodes(request reconcile.Request, rmdWo
 					return nil, err
 				}
 				nodeLabels := labels.Set(node.GetObjectMeta().GetLabels())
-				if !labels.AreLabelsInWhiteList(labels.Set(rmdWorkload.Spec.NodeSelector), nodeLabels) {
+				if !labels.AreLabelsInWhiteList(labels.Set(rmdWorkload.Spec.NodeSelector), nodeLabels) || !nodeSelected(rmdWorkload, nodeName) {
 					address, err := r.getPodAddress(nodeName)
 					if err != nil {
 						return nil, err
@@ -349,6 +509,356 @@ func (r *ReconcileRmdWorkload) findRemovedNodes(request reconcile.Request, rmdWo
 	return removedNodes, nil
 }
 
+// checkCapacity verifies that the RmdWorkload fits into the free cache ways and MBA capabilities reported
+// by the RMD instance on the targeted node. Should the cache or MBA info not be available from RMD that
+// check is skipped and RMD is left to accept or reject the workload. Updates of a workload already on RMD
+// are only checked with its applied ways credited back, so if the workloads cannot be listed a
+// ServiceUnavailable error is returned and the request is requeued.
+func (r *ReconcileRmdWorkload) checkCapacity(targetedNode targetedNodeInfo, rmdWorkload *intelv1alpha1.RmdWorkload) error {
+	logger := log.WithName("checkCapacity")
+
+	nodeWorkload := r.workloadForNode(rmdWorkload, targetedNode.nodeName)
+	allCacheInfo, err := r.rmdClient.GetCacheInfo(targetedNode.rmdAddress)
+	if err != nil {
+		logger.Info("Could not GET cache info, skip capacity check.", "Error:", err)
+		return nil
+	}
+	coreIDs, err := rmd.ResolveCoreIDs(nodeWorkload, allCacheInfo)
+	if err != nil {
+		return err
+	}
+
+	var existingWorkload *rmdtypes.RDTWorkLoad
+	if targetedNode.workloadExists {
+		activeWorkloads, err := r.rmdClient.GetWorkloads(targetedNode.rmdAddress)
+		if err != nil {
+			// Without the applied workload its ways cannot be credited back and an update
+			// would be checked against partial data.
+			return errors.NewServiceUnavailable(fmt.Sprintf("workloads not available: %v", err))
+		}
+		existingWorkload = rmd.FindWorkloadByName(activeWorkloads, rmdWorkload.GetObjectMeta().GetName())
+	}
+	err = rmd.CheckCacheCapacity(nodeWorkload, coreIDs, allCacheInfo, existingWorkload)
+	if err != nil {
+		return err
+	}
+
+	if nodeWorkload.Spec.Rdt.Mba.Percentage == 0 && nodeWorkload.Spec.Rdt.Mba.Mbps == 0 {
+		return nil
+	}
+	mbaInfo, err := r.rmdClient.GetMbaInfo(targetedNode.rmdAddress)
+	if err != nil {
+		logger.Info("Could not GET MBA info, skip MBA capacity check.", "Error:", err)
+		return nil
+	}
+	return rmd.CheckMbaCapacity(nodeWorkload, mbaInfo)
+}
+
+// findCoreConflicts returns the RmdWorkloads on nodeName that own cores also targeted by rmdWorkload,
+// mapped to the overlapping cores. Cores belong to the oldest RmdWorkload requesting them, so a new
+// object that is not yet persisted conflicts with every existing RmdWorkload sharing its cores.
+func (r *ReconcileRmdWorkload) findCoreConflicts(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName, address string) (map[string]string, error) {
+	logger := log.WithName("findCoreConflicts")
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err := r.client.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		logger.Error(err, "Failed to list RmdWorkloads")
+		return nil, err
+	}
+
+	nodeCores := &nodeCoreResolver{r: r, nodeName: nodeName, address: address}
+	coreIDs, err := nodeCores.resolve(rmdWorkload)
+	if err != nil {
+		logger.Info("Could not resolve workload cores, skip conflict check.", "Error:", err)
+		return nil, nil
+	}
+
+	coreOwnership := make(map[string][]string)
+	for i := range rmdWorkloads.Items {
+		owner := &rmdWorkloads.Items[i]
+		if !ownsCoresBefore(owner, rmdWorkload) || !workloadTargetsNode(owner, nodeName) {
+			continue
+		}
+		ownedCoreIDs, err := nodeCores.resolve(owner)
+		if err != nil {
+			logger.Info("Could not resolve workload cores, skip workload.", "RmdWorkload", owner.GetObjectMeta().GetName(), "Error:", err)
+			continue
+		}
+		coreOwnership[fmt.Sprintf("%s/%s", owner.GetObjectMeta().GetNamespace(), owner.GetObjectMeta().GetName())] = ownedCoreIDs
+	}
+
+	return rmd.FindCoreConflicts(coreIDs, coreOwnership)
+}
+
+// selectCores picks free cores for a coreCount RmdWorkload on nodeName. Cores of every other
+// RmdWorkload targeting the node are excluded from the selection.
+func (r *ReconcileRmdWorkload) selectCores(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName, address string) ([]string, error) {
+	logger := log.WithName("selectCores")
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err := r.client.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		logger.Error(err, "Failed to list RmdWorkloads")
+		return nil, err
+	}
+
+	nodeCores := &nodeCoreResolver{r: r, nodeName: nodeName, address: address}
+	allCacheInfo, err := nodeCores.cacheInfo()
+	if err != nil {
+		logger.Error(err, "Failed to get cache info")
+		return nil, err
+	}
+
+	unavailableCores := cpuset.NewCPUSet()
+	for i := range rmdWorkloads.Items {
+		owner := &rmdWorkloads.Items[i]
+		if owner.GetObjectMeta().GetNamespace() == rmdWorkload.GetObjectMeta().GetNamespace() &&
+			owner.GetObjectMeta().GetName() == rmdWorkload.GetObjectMeta().GetName() {
+			continue
+		}
+		if !workloadTargetsNode(owner, nodeName) {
+			continue
+		}
+		ownedCoreIDs, err := nodeCores.resolve(owner)
+		if err != nil {
+			logger.Info("Could not resolve workload cores, skip workload.", "RmdWorkload", owner.GetObjectMeta().GetName(), "Error:", err)
+			continue
+		}
+		ownedCPUSet, err := cpuset.Parse(strings.Join(ownedCoreIDs, ","))
+		if err != nil {
+			logger.Info("Could not parse workload cores, skip workload.", "RmdWorkload", owner.GetObjectMeta().GetName(), "Error:", err)
+			continue
+		}
+		unavailableCores = unavailableCores.Union(ownedCPUSet)
+	}
+
+	nodeWorkload := r.workloadForNode(rmdWorkload, nodeName).DeepCopy()
+	nodeWorkload.Spec.CoreIds = nil
+	return rmd.SelectCores(nodeWorkload, allCacheInfo, unavailableCores)
+}
+
+// nodeCoreResolver resolves the cores of RmdWorkloads on a single node. Cache info is only
+// fetched from the node's RMD instance when needed and then reused.
+type nodeCoreResolver struct {
+	r            *ReconcileRmdWorkload
+	nodeName     string
+	address      string
+	allCacheInfo *rmdCache.Infos
+}
+
+func (n *nodeCoreResolver) cacheInfo() (rmdCache.Infos, error) {
+	if n.allCacheInfo == nil {
+		allCacheInfo, err := n.r.rmdClient.GetCacheInfo(n.address)
+		if err != nil {
+			return rmdCache.Infos{}, err
+		}
+		n.allCacheInfo = &allCacheInfo
+	}
+	return *n.allCacheInfo, nil
+}
+
+func (n *nodeCoreResolver) resolve(rmdWorkload *intelv1alpha1.RmdWorkload) ([]string, error) {
+	nodeWorkload := n.r.workloadForNode(rmdWorkload, n.nodeName)
+	if !nodeWorkload.Spec.AllCores {
+		return rmd.ResolveCoreIDs(nodeWorkload, rmdCache.Infos{})
+	}
+	allCacheInfo, err := n.cacheInfo()
+	if err != nil {
+		return nil, err
+	}
+	return rmd.ResolveCoreIDs(nodeWorkload, allCacheInfo)
+}
+
+// workloadForNode returns the RmdWorkload as sent to nodeName's RMD instance, with the overrides
+// targeting the node applied. Cache ways resolved from a cache size and, for coreCount workloads,
+// the cores selected for the node are taken from the WorkloadState.
+func (r *ReconcileRmdWorkload) workloadForNode(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) *intelv1alpha1.RmdWorkload {
+	nodeWorkload := r.applyOverrides(rmdWorkload, nodeName)
+	requestedInBytes := rmd.CacheRequestedInBytes(nodeWorkload.Spec.Rdt.Cache)
+	if nodeWorkload.Spec.CoreCount == 0 && !requestedInBytes {
+		return nodeWorkload
+	}
+	if nodeWorkload == rmdWorkload {
+		nodeWorkload = rmdWorkload.DeepCopy()
+	}
+	workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+	if requestedInBytes {
+		// Only the cache size is replaced by the resolved ways.
+		cache := &nodeWorkload.Spec.Rdt.Cache
+		cache.Max, cache.Min = workloadState.Rdt.Cache.Max, workloadState.Rdt.Cache.Min
+		cache.MaxBytes, cache.MinBytes, cache.Rounding = nil, nil, ""
+	}
+	if nodeWorkload.Spec.CoreCount != 0 {
+		nodeWorkload.Spec.CoreIds = workloadState.CoreIds
+	}
+	return nodeWorkload
+}
+
+// resolveCacheWays converts the cache size requested by the RmdWorkload on nodeName to the
+// max and min cache ways of the node.
+func (r *ReconcileRmdWorkload) resolveCacheWays(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName, address string) (int, int, error) {
+	logger := log.WithName("resolveCacheWays")
+
+	allCacheInfo, err := r.rmdClient.GetCacheInfo(address)
+	if err != nil {
+		logger.Error(err, "Failed to get cache info")
+		return 0, 0, err
+	}
+	return rmd.ResolveCacheWays(r.applyOverrides(rmdWorkload, nodeName).Spec.Rdt.Cache, allCacheInfo)
+}
+
+// applyOverrides returns the RmdWorkload with the overrides targeting nodeName applied. Should the
+// node not be found, only overrides listing the node by name are applied.
+func (r *ReconcileRmdWorkload) applyOverrides(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) *intelv1alpha1.RmdWorkload {
+	logger := log.WithName("applyOverrides")
+	if len(rmdWorkload.Spec.Overrides) == 0 {
+		return rmdWorkload
+	}
+	node := &corev1.Node{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: nodeName}, node)
+	if err != nil {
+		logger.Info("Could not get node, apply overrides by node name only.", "node", nodeName, "Error:", err)
+	}
+	return rmd.ApplyOverrides(rmdWorkload, nodeName, node.GetObjectMeta().GetLabels())
+}
+
+// setWorkloadCacheWays records the cache ways resolved for the workload on nodeName.
+func setWorkloadCacheWays(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string, maxWays, minWays int) {
+	if len(rmdWorkload.Status.WorkloadStates) == 0 {
+		rmdWorkload.Status.WorkloadStates = make(map[string]intelv1alpha1.WorkloadState)
+	}
+	workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+	workloadState.Rdt.Cache.Max = maxWays
+	workloadState.Rdt.Cache.Min = minWays
+	rmdWorkload.Status.WorkloadStates[nodeName] = workloadState
+}
+
+// setWorkloadCores records the cores selected for the workload on nodeName.
+func setWorkloadCores(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string, coreIDs []string) {
+	if len(rmdWorkload.Status.WorkloadStates) == 0 {
+		rmdWorkload.Status.WorkloadStates = make(map[string]intelv1alpha1.WorkloadState)
+	}
+	workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+	workloadState.CoreIds = coreIDs
+	rmdWorkload.Status.WorkloadStates[nodeName] = workloadState
+}
+
+// ownsCoresBefore returns true if owner takes precedence over rmdWorkload for shared cores.
+// The older RmdWorkload wins, ties are broken by namespace and name.
+func ownsCoresBefore(owner, rmdWorkload *intelv1alpha1.RmdWorkload) bool {
+	if owner.GetObjectMeta().GetNamespace() == rmdWorkload.GetObjectMeta().GetNamespace() &&
+		owner.GetObjectMeta().GetName() == rmdWorkload.GetObjectMeta().GetName() {
+		return false
+	}
+	ownerCreated := owner.GetObjectMeta().GetCreationTimestamp()
+	workloadCreated := rmdWorkload.GetObjectMeta().GetCreationTimestamp()
+	if workloadCreated.IsZero() {
+		return true
+	}
+	if !ownerCreated.Equal(&workloadCreated) {
+		return ownerCreated.Before(&workloadCreated)
+	}
+	if owner.GetObjectMeta().GetNamespace() != rmdWorkload.GetObjectMeta().GetNamespace() {
+		return owner.GetObjectMeta().GetNamespace() < rmdWorkload.GetObjectMeta().GetNamespace()
+	}
+	return owner.GetObjectMeta().GetName() < rmdWorkload.GetObjectMeta().GetName()
+}
+
+// workloadTargetsNode returns true if nodeName is listed in the RmdWorkload spec or the
+// RmdWorkload has already been reconciled onto nodeName via its NodeSelector.
+func workloadTargetsNode(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) bool {
+	for _, rmdNodeName := range rmdWorkload.Spec.Nodes {
+		if rmdNodeName == nodeName {
+			return true
+		}
+	}
+	_, ok := rmdWorkload.Status.WorkloadStates[nodeName]
+	return ok
+}
+
+// conflictReason formats the conflicts returned by findCoreConflicts in a stable order.
+func conflictReason(conflicts map[string]string) string {
+	owners := make([]string, 0)
+	for owner := range conflicts {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	reports := make([]string, 0)
+	for _, owner := range owners {
+		reports = append(reports, fmt.Sprintf("cores %s owned by %s", conflicts[owner], owner))
+	}
+	return strings.Join(reports, ", ")
+}
+
+// setWorkloadState records that the workload was not sent to RMD on nodeName and why.
+// Any existing allocation on the node is left untouched.
+func setWorkloadState(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName, state, reason string) {
+	if len(rmdWorkload.Status.WorkloadStates) == 0 {
+		rmdWorkload.Status.WorkloadStates = make(map[string]intelv1alpha1.WorkloadState)
+	}
+	workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+	workloadState.Status = state
+	workloadState.Response = fmt.Sprintf("%s%v", "Fail: ", reason)
+	rmdWorkload.Status.WorkloadStates[nodeName] = workloadState
+}
+
+// setCapacityCondition reports the nodes on which the workload does not fit.
+func setCapacityCondition(status *intelv1alpha1.RmdWorkloadStatus, insufficientNodes map[string]string) {
+	setNodeCondition(status, intelv1alpha1.InsufficientCapacity, "NodesCannotFitWorkload", "CapacityAvailable", insufficientNodes)
+}
+
+// setConflictCondition reports the nodes on which the workload's cores are owned by other RmdWorkloads.
+func setConflictCondition(status *intelv1alpha1.RmdWorkloadStatus, conflictingNodes map[string]string) {
+	setNodeCondition(status, intelv1alpha1.Conflict, "CoresOwnedByOtherWorkloads", "NoConflict", conflictingNodes)
+}
+
+// setNodeCondition reports per node failures as a condition of condType. The condition is only
+// added once a node has failed and is set to False when all nodes succeed again.
+func setNodeCondition(status *intelv1alpha1.RmdWorkloadStatus, condType intelv1alpha1.RmdWorkloadConditionType, trueReason, falseReason string, nodeReports map[string]string) {
+	if len(nodeReports) == 0 {
+		for _, condition := range status.Conditions {
+			if condition.Type == condType {
+				setCondition(status, condType, corev1.ConditionFalse, falseReason, "")
+			}
+		}
+		return
+	}
+	nodeNames := make([]string, 0)
+	for nodeName := range nodeReports {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	reports := make([]string, 0)
+	for _, nodeName := range nodeNames {
+		reports = append(reports, fmt.Sprintf("%s: %s", nodeName, nodeReports[nodeName]))
+	}
+	setCondition(status, condType, corev1.ConditionTrue, trueReason, strings.Join(reports, ", "))
+}
+
+// setCondition adds or updates the condition of condType. LastTransitionTime only changes with the status.
+func setCondition(status *intelv1alpha1.RmdWorkloadStatus, condType intelv1alpha1.RmdWorkloadConditionType, condStatus corev1.ConditionStatus, reason, message string) {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type != condType {
+			continue
+		}
+		if status.Conditions[i].Status != condStatus {
+			status.Conditions[i].LastTransitionTime = metav1.Now()
+		}
+		status.Conditions[i].Status = condStatus
+		status.Conditions[i].Reason = reason
+		status.Conditions[i].Message = message
+		return
+	}
+	status.Conditions = append(status.Conditions, intelv1alpha1.RmdWorkloadCondition{
+		Type:               condType,
+		Status:             condStatus,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
 // getPodAddress fetches the IP address and port of the desired service.
 func (r *ReconcileRmdWorkload) getPodAddress(nodeName string) (string, error) {
 	logger := log.WithName("getPodAddress")
@@ -390,12 +900,13 @@ func (r *ReconcileRmdWorkload) getPodAddress(nodeName string) (string, error) {
 	}
 	addressPrefix := r.rmdClient.GetAddressPrefix()
 	address := fmt.Sprintf("%s%s%s%d", addressPrefix, podIP, ":", rmdPod.Spec.Containers[0].Ports[0].ContainerPort)
+	metrics.SetRmdNodeAddress(nodeName, address)
 	return address, nil
 }
 
 func (r *ReconcileRmdWorkload) addWorkload(address string, rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) error {
 	logger := log.WithName("postWorkload")
-	response, err := r.rmdClient.PostWorkload(rmdWorkload, address)
+	response, err := r.rmdClient.PostWorkload(r.workloadForNode(rmdWorkload, nodeName), address)
 	if err != nil {
 		logger.Error(err, "Failed to post workload to RMD", "Response:", response)
 	}
@@ -409,7 +920,7 @@ func (r *ReconcileRmdWorkload) addWorkload(address string, rmdWorkload *intelv1a
 
 func (r *ReconcileRmdWorkload) updateWorkload(address string, rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) error {
 	logger := log.WithName("updateWorkload")
-	response, err := r.rmdClient.PatchWorkload(rmdWorkload, address, rmdWorkload.Status.WorkloadStates[nodeName].ID)
+	response, err := r.rmdClient.PatchWorkload(r.workloadForNode(rmdWorkload, nodeName), address, rmdWorkload.Status.WorkloadStates[nodeName].ID)
 	if err != nil {
 		logger.Error(err, "Failed to patch workload to RMD")
 		// do not requeue
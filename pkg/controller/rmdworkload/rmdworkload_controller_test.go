@@ -8,8 +8,10 @@ import (
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
 	"github.com/intel/rmd-operator/pkg/rmd"
 	"github.com/intel/rmd-operator/pkg/state"
+	rmdCache "github.com/intel/rmd/modules/cache"
 	rmdtypes "github.com/intel/rmd/modules/workload/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -20,7 +22,9 @@ import (
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
 	"testing"
+	"time"
 )
 
 func createReconcileRmdWorkloadObject(rmdWorkload *intelv1alpha1.RmdWorkload) (*ReconcileRmdWorkload, error) {
@@ -1735,6 +1739,7 @@ func TestFindTargetedNodes(t *testing.T) {
 }
 
 func TestFindRemovedNodes(t *testing.T) {
+	maxNodes := 1
 	tcases := []struct {
 		name                 string
 		request              reconcile.Request
@@ -2372,6 +2377,134 @@ func TestFindRemovedNodes(t *testing.T) {
 			},
 			expectedError: false,
 		},
+		{
+			name: "test case 6 - workload moved off node no longer selected",
+			request: reconcile.Request{
+				types.NamespacedName{
+					Namespace: "default",
+					Name:      "rmd-workload-2",
+				},
+			},
+			rmdNodeData: []string{"example-node.com", "example-node-2.com"},
+			rmdNodes: &corev1.NodeList{
+				Items: []corev1.Node{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "example-node.com",
+							Namespace: "default",
+							Labels: map[string]string{
+								"label1": "true",
+								"label2": "true",
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "example-node-2.com",
+							Namespace: "default",
+							Labels: map[string]string{
+								"label1": "true",
+								"label2": "true",
+							},
+						},
+					},
+				},
+			},
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-2",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					NodeSelector: map[string]string{
+						"label1": "true",
+						"label2": "true",
+					},
+					MaxNodes: &maxNodes,
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					SelectedNodes: []string{"example-node.com"},
+				},
+			},
+			rmdPods: &corev1.PodList{
+				Items: []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "rmd-example-node.com",
+							Namespace: "default",
+							Labels:    map[string]string{"name": "rmd-pod"},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Ports: []corev1.ContainerPort{
+										{
+											ContainerPort: 8080,
+										},
+									},
+								},
+							},
+							NodeName: "example-node.com",
+						},
+						Status: corev1.PodStatus{
+							PodIPs: []corev1.PodIP{
+								{
+									IP: "127.0.0.1",
+								},
+							},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "rmd-example-node-2.com",
+							Namespace: "default",
+							Labels:    map[string]string{"name": "rmd-pod"},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Ports: []corev1.ContainerPort{
+										{
+											ContainerPort: 8082,
+										},
+									},
+								},
+							},
+							NodeName: "example-node-2.com",
+						},
+						Status: corev1.PodStatus{
+							PodIPs: []corev1.PodIP{
+								{
+									IP: "127.0.0.2",
+								},
+							},
+						},
+					},
+				},
+			},
+			getWorkloadsResponse: map[string]([]rmdtypes.RDTWorkLoad){
+				"127.0.0.1:8080": {
+					{
+						UUID: "rmd-workload-2",
+						ID:   "1",
+					},
+				},
+				"127.0.0.2:8082": {
+					{
+						UUID: "rmd-workload-2",
+						ID:   "2",
+					},
+				},
+			},
+			expectedRemovedNodes: []removedNodeInfo{
+				{
+					nodeName:   "example-node-2.com",
+					rmdAddress: "http://127.0.0.2:8082",
+					workloadID: "2",
+				},
+			},
+			expectedError: false,
+		},
 	}
 
 	for _, tc := range tcases {
@@ -3208,3 +3341,850 @@ func TestUpdateWorkload(t *testing.T) {
 
 	}
 }
+
+func TestCheckCapacity(t *testing.T) {
+	cacheInfo := rmdCache.Infos{
+		Num: 1,
+		Caches: map[uint32]rmdCache.Info{
+			0: {
+				ID:           0,
+				NumWays:      11,
+				ShareCPUList: "0-7",
+				AvailableWaysPool: map[string]string{
+					"guaranteed": "0-10",
+				},
+			},
+		},
+	}
+	tcases := []struct {
+		name           string
+		rmdWorkload    *intelv1alpha1.RmdWorkload
+		cacheInfo      *rmdCache.Infos
+		workloadExists bool
+		expectedErr    bool
+	}{
+		{
+			name: "test case 1 - workload fits",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0", "1"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 11, Min: 11},
+					},
+				},
+			},
+			cacheInfo:   &cacheInfo,
+			expectedErr: false,
+		},
+		{
+			name: "test case 2 - workload requests more ways than available",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0", "1"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 20, Min: 20},
+					},
+				},
+			},
+			cacheInfo:   &cacheInfo,
+			expectedErr: true,
+		},
+		{
+			name: "test case 3 - cache info unavailable, check skipped",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0", "1"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 20, Min: 20},
+					},
+				},
+			},
+			cacheInfo:   nil,
+			expectedErr: false,
+		},
+		{
+			name: "test case 4 - workload exists, workloads unavailable",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0", "1"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+					},
+				},
+			},
+			cacheInfo:      &cacheInfo,
+			workloadExists: true,
+			expectedErr:    true,
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcileRmdWorkloadObject(tc.rmdWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		mux := http.NewServeMux()
+		if tc.cacheInfo != nil {
+			mux.HandleFunc("/v1/cache/l3", (func(w http.ResponseWriter, r *http.Request) {
+				b, err := json.Marshal(tc.cacheInfo)
+				if err == nil {
+					fmt.Fprintln(w, string(b[:]))
+				}
+			}))
+		}
+		ts := httptest.NewServer(mux)
+
+		targetedNode := targetedNodeInfo{
+			nodeName:       "example-node.com",
+			rmdAddress:     ts.URL,
+			workloadExists: tc.workloadExists,
+		}
+		err = r.checkCapacity(targetedNode, tc.rmdWorkload)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error: %v, Error gotten: %v\n", tc.name, tc.expectedErr, err)
+		}
+		ts.Close()
+	}
+}
+
+func TestSetCapacityCondition(t *testing.T) {
+	tcases := []struct {
+		name               string
+		status             *intelv1alpha1.RmdWorkloadStatus
+		insufficientNodes  map[string]string
+		expectedConditions int
+		expectedStatus     corev1.ConditionStatus
+	}{
+		{
+			name:               "test case 1 - all nodes fit, no condition added",
+			status:             &intelv1alpha1.RmdWorkloadStatus{},
+			insufficientNodes:  map[string]string{},
+			expectedConditions: 0,
+		},
+		{
+			name:   "test case 2 - node cannot fit workload",
+			status: &intelv1alpha1.RmdWorkloadStatus{},
+			insufficientNodes: map[string]string{
+				"example-node-1.com": "insufficient cache capacity",
+			},
+			expectedConditions: 1,
+			expectedStatus:     corev1.ConditionTrue,
+		},
+		{
+			name: "test case 3 - capacity available again",
+			status: &intelv1alpha1.RmdWorkloadStatus{
+				Conditions: []intelv1alpha1.RmdWorkloadCondition{
+					{
+						Type:   intelv1alpha1.InsufficientCapacity,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+			insufficientNodes:  map[string]string{},
+			expectedConditions: 1,
+			expectedStatus:     corev1.ConditionFalse,
+		},
+	}
+	for _, tc := range tcases {
+		setCapacityCondition(tc.status, tc.insufficientNodes)
+		if len(tc.status.Conditions) != tc.expectedConditions {
+			t.Errorf("%v failed: Expected %v conditions, got %v", tc.name, tc.expectedConditions, len(tc.status.Conditions))
+			continue
+		}
+		if tc.expectedConditions != 0 && tc.status.Conditions[0].Status != tc.expectedStatus {
+			t.Errorf("%v failed: Expected condition status %v, got %v", tc.name, tc.expectedStatus, tc.status.Conditions[0].Status)
+		}
+	}
+}
+
+func TestFindCoreConflicts(t *testing.T) {
+	tcases := []struct {
+		name              string
+		rmdWorkload       *intelv1alpha1.RmdWorkload
+		existingWorkloads []runtime.Object
+		expectedConflicts map[string]string
+	}{
+		{
+			name: "test case 1 - no overlapping cores",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rmd-workload-2",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Unix(200, 0)),
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"2", "3"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"0-1"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{},
+		},
+		{
+			name: "test case 2 - cores owned by older workload",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rmd-workload-2",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Unix(200, 0)),
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1", "2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"0-1"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{
+				"default/rmd-workload-1": "1",
+			},
+		},
+		{
+			name: "test case 3 - overlapping cores of newer workload ignored",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rmd-workload-2",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1", "2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(200, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"0-1"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{},
+		},
+		{
+			name: "test case 4 - overlapping workload on different node",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rmd-workload-2",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Unix(200, 0)),
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1", "2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"0-1"},
+						Nodes:   []string{"example-node-2.com"},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{},
+		},
+		{
+			name: "test case 5 - new workload conflicts with allCores workload applied via nodeSelector",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-2",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"2-5"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "other",
+						CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						AllCores:        true,
+						ReservedCoreIds: []string{"0-3"},
+						NodeSelector:    map[string]string{"feature.node.kubernetes.io/cpu-rdt.RDTL3CA": "true"},
+					},
+					Status: intelv1alpha1.RmdWorkloadStatus{
+						WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+							"example-node.com": {ID: "1"},
+						},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{
+				"other/rmd-workload-1": "4-5",
+			},
+		},
+		{
+			name: "test case 6 - older workload with malformed cores skipped",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "rmd-workload-3",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(time.Unix(300, 0)),
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1", "2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-1",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"one"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "rmd-workload-2",
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Unix(200, 0)),
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"2-3"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+			},
+			expectedConflicts: map[string]string{
+				"default/rmd-workload-2": "2",
+			},
+		},
+	}
+	cacheInfo := rmdCache.Infos{
+		Num: 1,
+		Caches: map[uint32]rmdCache.Info{
+			0: {
+				ID:           0,
+				ShareCPUList: "0-7",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcileRmdWorkloadObject(tc.rmdWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		r.client = fake.NewFakeClient(append(tc.existingWorkloads, tc.rmdWorkload)...)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/v1/cache/l3", (func(w http.ResponseWriter, r *http.Request) {
+			b, err := json.Marshal(cacheInfo)
+			if err == nil {
+				fmt.Fprintln(w, string(b[:]))
+			}
+		}))
+		ts := httptest.NewServer(mux)
+
+		conflicts, err := r.findCoreConflicts(tc.rmdWorkload, "example-node.com", ts.URL)
+		if err != nil {
+			t.Errorf("%v failed: Unexpected error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(tc.expectedConflicts, conflicts) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedConflicts, conflicts)
+		}
+		ts.Close()
+	}
+}
+
+func TestSetConflictCondition(t *testing.T) {
+	tcases := []struct {
+		name               string
+		status             *intelv1alpha1.RmdWorkloadStatus
+		conflictingNodes   map[string]string
+		expectedConditions int
+		expectedStatus     corev1.ConditionStatus
+		expectedMessage    string
+	}{
+		{
+			name:               "test case 1 - no conflicts, no condition added",
+			status:             &intelv1alpha1.RmdWorkloadStatus{},
+			conflictingNodes:   map[string]string{},
+			expectedConditions: 0,
+		},
+		{
+			name:   "test case 2 - conflicts on two nodes",
+			status: &intelv1alpha1.RmdWorkloadStatus{},
+			conflictingNodes: map[string]string{
+				"example-node-2.com": "cores 1 owned by default/rmd-workload-1",
+				"example-node-1.com": "cores 0 owned by default/rmd-workload-1",
+			},
+			expectedConditions: 1,
+			expectedStatus:     corev1.ConditionTrue,
+			expectedMessage:    "example-node-1.com: cores 0 owned by default/rmd-workload-1, example-node-2.com: cores 1 owned by default/rmd-workload-1",
+		},
+		{
+			name: "test case 3 - conflict resolved",
+			status: &intelv1alpha1.RmdWorkloadStatus{
+				Conditions: []intelv1alpha1.RmdWorkloadCondition{
+					{
+						Type:   intelv1alpha1.Conflict,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+			conflictingNodes:   map[string]string{},
+			expectedConditions: 1,
+			expectedStatus:     corev1.ConditionFalse,
+		},
+	}
+	for _, tc := range tcases {
+		setConflictCondition(tc.status, tc.conflictingNodes)
+		if len(tc.status.Conditions) != tc.expectedConditions {
+			t.Errorf("%v failed: Expected %v conditions, got %v", tc.name, tc.expectedConditions, len(tc.status.Conditions))
+			continue
+		}
+		if tc.expectedConditions == 0 {
+			continue
+		}
+		if tc.status.Conditions[0].Status != tc.expectedStatus {
+			t.Errorf("%v failed: Expected condition status %v, got %v", tc.name, tc.expectedStatus, tc.status.Conditions[0].Status)
+		}
+		if tc.status.Conditions[0].Message != tc.expectedMessage {
+			t.Errorf("%v failed: Expected condition message %v, got %v", tc.name, tc.expectedMessage, tc.status.Conditions[0].Message)
+		}
+	}
+}
+
+func TestSelectCores(t *testing.T) {
+	tcases := []struct {
+		name              string
+		rmdWorkload       *intelv1alpha1.RmdWorkload
+		existingWorkloads []runtime.Object
+		expectedCoreIDs   []string
+		expectedErr       bool
+	}{
+		{
+			name: "test case 1 - cores of other workloads on node excluded",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-3",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					Nodes:     []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "rmd-workload-1",
+						Namespace: "default",
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"0-1"},
+						Nodes:   []string{"example-node.com"},
+					},
+				},
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "rmd-workload-2",
+						Namespace: "default",
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreCount: 1,
+						Nodes:     []string{"example-node.com"},
+					},
+					Status: intelv1alpha1.RmdWorkloadStatus{
+						WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+							"example-node.com": {CoreIds: []string{"2"}},
+						},
+					},
+				},
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "rmd-workload-4",
+						Namespace: "default",
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						CoreIds: []string{"3"},
+						Nodes:   []string{"example-node-2.com"},
+					},
+				},
+			},
+			expectedCoreIDs: []string{"3-4"},
+		},
+		{
+			name: "test case 2 - no free cores",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-3",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					Nodes:     []string{"example-node.com"},
+				},
+			},
+			existingWorkloads: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "rmd-workload-1",
+						Namespace: "default",
+					},
+					Spec: intelv1alpha1.RmdWorkloadSpec{
+						AllCores: true,
+						Nodes:    []string{"example-node.com"},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+	cacheInfo := rmdCache.Infos{
+		Num: 1,
+		Caches: map[uint32]rmdCache.Info{
+			0: {
+				ID:           0,
+				ShareCPUList: "0-7",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcileRmdWorkloadObject(tc.rmdWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		r.client = fake.NewFakeClient(append(tc.existingWorkloads, tc.rmdWorkload)...)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/v1/cache/l3", (func(w http.ResponseWriter, r *http.Request) {
+			b, err := json.Marshal(cacheInfo)
+			if err == nil {
+				fmt.Fprintln(w, string(b[:]))
+			}
+		}))
+		ts := httptest.NewServer(mux)
+
+		coreIDs, err := r.selectCores(tc.rmdWorkload, "example-node.com", ts.URL)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error: %v, Error gotten: %v\n", tc.name, tc.expectedErr, err)
+		}
+		if !reflect.DeepEqual(tc.expectedCoreIDs, coreIDs) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedCoreIDs, coreIDs)
+		}
+		ts.Close()
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	tcases := []struct {
+		name                   string
+		matchingNodes          []string
+		maxNodes               int
+		status                 intelv1alpha1.RmdWorkloadStatus
+		expectedNodes          []string
+		expectedWorkloadStates map[string]intelv1alpha1.WorkloadState
+	}{
+		{
+			name:          "test case 1 - nodes with most free ways selected",
+			matchingNodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			maxNodes:      2,
+			expectedNodes: []string{"example-node-2.com", "example-node-3.com"},
+		},
+		{
+			name:          "test case 2 - selected node kept",
+			matchingNodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			maxNodes:      1,
+			status: intelv1alpha1.RmdWorkloadStatus{
+				SelectedNodes: []string{"example-node-1.com"},
+			},
+			expectedNodes: []string{"example-node-1.com"},
+		},
+		{
+			name:          "test case 3 - selected node no longer matching replaced",
+			matchingNodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			maxNodes:      1,
+			status: intelv1alpha1.RmdWorkloadStatus{
+				SelectedNodes: []string{"example-node-4.com"},
+			},
+			expectedNodes: []string{"example-node-2.com"},
+		},
+		{
+			name:          "test case 4 - selected node without capacity replaced",
+			matchingNodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			maxNodes:      1,
+			status: intelv1alpha1.RmdWorkloadStatus{
+				SelectedNodes: []string{"example-node-2.com"},
+				WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+					"example-node-2.com": {Status: "Pending"},
+				},
+			},
+			expectedNodes:          []string{"example-node-3.com"},
+			expectedWorkloadStates: map[string]intelv1alpha1.WorkloadState{},
+		},
+		{
+			name:          "test case 5 - selected node without capacity kept if no other node left",
+			matchingNodes: []string{"example-node-2.com"},
+			maxNodes:      1,
+			status: intelv1alpha1.RmdWorkloadStatus{
+				SelectedNodes: []string{"example-node-2.com"},
+				WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+					"example-node-2.com": {Status: "Pending"},
+				},
+			},
+			expectedNodes: []string{"example-node-2.com"},
+			expectedWorkloadStates: map[string]intelv1alpha1.WorkloadState{
+				"example-node-2.com": {Status: "Pending"},
+			},
+		},
+	}
+	guaranteedWays := map[string]string{
+		"example-node-1.com": "0-3",
+		"example-node-2.com": "0-7",
+		"example-node-3.com": "2-9",
+	}
+	servers := make(map[string]*httptest.Server)
+	for nodeName, ways := range guaranteedWays {
+		cacheInfo := rmdCache.Infos{
+			Num: 1,
+			Caches: map[uint32]rmdCache.Info{
+				0: {
+					ID:                0,
+					ShareCPUList:      "0-7",
+					AvailableWaysPool: map[string]string{"guaranteed": ways},
+				},
+			},
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/v1/cache/l3", (func(w http.ResponseWriter, r *http.Request) {
+			b, err := json.Marshal(cacheInfo)
+			if err == nil {
+				fmt.Fprintln(w, string(b[:]))
+			}
+		}))
+		servers[nodeName] = httptest.NewServer(mux)
+		defer servers[nodeName].Close()
+	}
+
+	for _, tc := range tcases {
+		rmdWorkload := &intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "rmd-workload-1",
+				Namespace: "default",
+			},
+			Spec: intelv1alpha1.RmdWorkloadSpec{
+				NodeSelector: map[string]string{"label1": "true"},
+				MaxNodes:     &tc.maxNodes,
+			},
+			Status: tc.status,
+		}
+		r, err := createReconcileRmdWorkloadObject(rmdWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		matchingNodes := make([]targetedNodeInfo, 0)
+		for _, nodeName := range tc.matchingNodes {
+			matchingNodes = append(matchingNodes, targetedNodeInfo{nodeName, servers[nodeName].URL, false})
+		}
+
+		selectedNodes := r.selectNodes(rmdWorkload, matchingNodes)
+		selectedNames := make([]string, 0)
+		for _, selectedNode := range selectedNodes {
+			selectedNames = append(selectedNames, selectedNode.nodeName)
+		}
+		sort.Strings(selectedNames)
+		if !reflect.DeepEqual(tc.expectedNodes, selectedNames) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedNodes, selectedNames)
+		}
+		if !reflect.DeepEqual(tc.expectedNodes, rmdWorkload.Status.SelectedNodes) {
+			t.Errorf("%v failed: Expected selected nodes:  %v, Got:  %v\n", tc.name, tc.expectedNodes, rmdWorkload.Status.SelectedNodes)
+		}
+		if !reflect.DeepEqual(tc.expectedWorkloadStates, rmdWorkload.Status.WorkloadStates) {
+			t.Errorf("%v failed: Expected workload states:  %v, Got:  %v\n", tc.name, tc.expectedWorkloadStates, rmdWorkload.Status.WorkloadStates)
+		}
+	}
+}
+
+func TestWorkloadForNode(t *testing.T) {
+	cacheSize := resource.MustParse("4Mi")
+	tcases := []struct {
+		name            string
+		rmdWorkload     *intelv1alpha1.RmdWorkload
+		node            *corev1.Node
+		nodeName        string
+		expectedCoreIds []string
+		expectedCache   intelv1alpha1.Cache
+	}{
+		{
+			name: "test case 1 - no overrides",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0-3"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+					},
+				},
+			},
+			nodeName:        "example-node.com",
+			expectedCoreIds: []string{"0-3"},
+			expectedCache:   intelv1alpha1.Cache{Max: 2, Min: 2},
+		},
+		{
+			name: "test case 2 - override by node labels",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0-3"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+					},
+					Overrides: []intelv1alpha1.RmdWorkloadOverride{
+						{
+							NodeSelector: map[string]string{"l3-size": "large"},
+							CoreIds:      []string{"0-7"},
+							Cache:        &intelv1alpha1.Cache{Max: 4, Min: 4},
+						},
+					},
+				},
+			},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "example-node.com",
+					Labels: map[string]string{"l3-size": "large"},
+				},
+			},
+			nodeName:        "example-node.com",
+			expectedCoreIds: []string{"0-7"},
+			expectedCache:   intelv1alpha1.Cache{Max: 4, Min: 4},
+		},
+		{
+			name: "test case 3 - coreCount with override cache and selected cores",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+					},
+					Overrides: []intelv1alpha1.RmdWorkloadOverride{
+						{
+							Nodes: []string{"example-node.com"},
+							Cache: &intelv1alpha1.Cache{Max: 3, Min: 3},
+						},
+					},
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node.com": {CoreIds: []string{"4-5"}},
+					},
+				},
+			},
+			nodeName:        "example-node.com",
+			expectedCoreIds: []string{"4-5"},
+			expectedCache:   intelv1alpha1.Cache{Max: 3, Min: 3},
+		},
+		{
+			name: "test case 4 - cache size with resolved ways",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0-3"},
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{MaxBytes: &cacheSize},
+					},
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node.com": {Rdt: intelv1alpha1.Rdt{Cache: intelv1alpha1.Cache{Max: 3, Min: 3}}},
+					},
+				},
+			},
+			nodeName:        "example-node.com",
+			expectedCoreIds: []string{"0-3"},
+			expectedCache:   intelv1alpha1.Cache{Max: 3, Min: 3},
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcileRmdWorkloadObject(tc.rmdWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		if tc.node != nil {
+			err = r.client.Create(context.TODO(), tc.node)
+			if err != nil {
+				t.Fatalf("Failed to create dummy node")
+			}
+		}
+		nodeWorkload := r.workloadForNode(tc.rmdWorkload, tc.nodeName)
+		if !reflect.DeepEqual(tc.expectedCoreIds, nodeWorkload.Spec.CoreIds) {
+			t.Errorf("%v failed: Expected core IDs:  %v, Got:  %v\n", tc.name, tc.expectedCoreIds, nodeWorkload.Spec.CoreIds)
+		}
+		if !reflect.DeepEqual(tc.expectedCache, nodeWorkload.Spec.Rdt.Cache) {
+			t.Errorf("%v failed: Expected cache:  %v, Got:  %v\n", tc.name, tc.expectedCache, nodeWorkload.Spec.Rdt.Cache)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package rmdworkload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmd "github.com/intel/rmd-operator/pkg/rmd"
+	"github.com/intel/rmd-operator/pkg/state"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path on which the RmdWorkload validating webhook is served
+const ValidatePath = "/validate-intel-com-v1alpha1-rmdworkload"
+
+// AddWebhook registers the RmdWorkload validating webhook with the Manager's webhook server.
+// New RmdWorkloads whose cores are already owned by another RmdWorkload on a targeted node are denied.
+func AddWebhook(mgr manager.Manager, rmdClient *rmd.OperatorRmdClient, rmdNodeData *state.RmdNodeData) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	reconciler := &ReconcileRmdWorkload{client: mgr.GetClient(), rmdClient: rmdClient, scheme: mgr.GetScheme(), rmdNodeData: rmdNodeData}
+	mgr.GetWebhookServer().Register(ValidatePath, &webhook.Admission{Handler: &rmdWorkloadValidator{reconciler: reconciler, decoder: decoder}})
+	return nil
+}
+
+// rmdWorkloadValidator denies the creation of RmdWorkloads with conflicting cores
+type rmdWorkloadValidator struct {
+	reconciler *ReconcileRmdWorkload
+	decoder    *admission.Decoder
+}
+
+// blank assignment to verify that rmdWorkloadValidator implements admission.Handler
+var _ admission.Handler = &rmdWorkloadValidator{}
+
+// Handle validates RmdWorkload create requests. Existing RmdWorkloads are reported via their
+// Conflict condition instead. Should the conflict check itself fail, the request is allowed
+// and left to the controller.
+func (v *rmdWorkloadValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.WithName("rmdWorkloadValidator")
+	if req.Operation != admissionv1beta1.Create {
+		return admission.Allowed("")
+	}
+
+	rmdWorkload := &intelv1alpha1.RmdWorkload{}
+	err := v.decoder.Decode(req, rmdWorkload)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if rmdWorkload.GetObjectMeta().GetNamespace() == "" {
+		rmdWorkload.SetNamespace(req.Namespace)
+	}
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: rmdWorkload.GetObjectMeta().GetNamespace(), Name: rmdWorkload.GetObjectMeta().GetName()}}
+	targetedNodes, err := v.reconciler.findTargetedNodes(request, rmdWorkload)
+	if err != nil {
+		logger.Error(err, "Failed to find targeted nodes, skip conflict check")
+		return admission.Allowed("")
+	}
+
+	nodeReports := make([]string, 0)
+	for _, targetedNode := range targetedNodes {
+		conflicts, err := v.reconciler.findCoreConflicts(rmdWorkload, targetedNode.nodeName, targetedNode.rmdAddress)
+		if err != nil {
+			logger.Error(err, "Failed to find core conflicts, skip conflict check", "node", targetedNode.nodeName)
+			return admission.Allowed("")
+		}
+		if len(conflicts) != 0 {
+			nodeReports = append(nodeReports, fmt.Sprintf("%s: %s", targetedNode.nodeName, conflictReason(conflicts)))
+		}
+	}
+	if len(nodeReports) != 0 {
+		sort.Strings(nodeReports)
+		return admission.Denied(fmt.Sprintf("core conflict: %s", strings.Join(nodeReports, ", ")))
+	}
+
+	return admission.Allowed("")
+}
@@ -0,0 +1,148 @@
+package rmdworkload
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestRmdWorkloadValidatorHandle(t *testing.T) {
+	existingWorkload := &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rmd-workload-1",
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(time.Unix(100, 0)),
+		},
+		Spec: intelv1alpha1.RmdWorkloadSpec{
+			CoreIds: []string{"0-1"},
+			Nodes:   []string{"example-node.com"},
+		},
+	}
+	tcases := []struct {
+		name            string
+		operation       admissionv1beta1.Operation
+		rmdWorkload     *intelv1alpha1.RmdWorkload
+		expectedAllowed bool
+	}{
+		{
+			name:      "test case 1 - create without conflict",
+			operation: admissionv1beta1.Create,
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-2",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"2-3"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			expectedAllowed: true,
+		},
+		{
+			name:      "test case 2 - create with conflicting cores",
+			operation: admissionv1beta1.Create,
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-2",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1-2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			expectedAllowed: false,
+		},
+		{
+			name:      "test case 3 - update with conflicting cores left to controller",
+			operation: admissionv1beta1.Update,
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-2",
+					Namespace: "default",
+				},
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"1-2"},
+					Nodes:   []string{"example-node.com"},
+				},
+			},
+			expectedAllowed: true,
+		},
+	}
+	rmdPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rmd-example-node.com",
+			Namespace: "default",
+			Labels:    map[string]string{"name": "rmd-pod"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Ports: []corev1.ContainerPort{
+						{
+							ContainerPort: 8080,
+						},
+					},
+				},
+			},
+			NodeName: "example-node.com",
+		},
+		Status: corev1.PodStatus{
+			PodIPs: []corev1.PodIP{
+				{
+					IP: "127.0.0.1",
+				},
+			},
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcileRmdWorkloadObject(existingWorkload)
+		if err != nil {
+			t.Fatalf("error creating ReconcileRmdWorkload object: (%v)", err)
+		}
+		r.client = fake.NewFakeClient([]runtime.Object{existingWorkload, rmdPod}...)
+
+		ts, err := createListeners("127.0.0.1:8080", nil)
+		if err != nil {
+			t.Fatalf("error creating listeners: (%v)", err)
+		}
+
+		decoder, err := admission.NewDecoder(scheme.Scheme)
+		if err != nil {
+			t.Fatalf("error creating decoder: (%v)", err)
+		}
+		raw, err := json.Marshal(tc.rmdWorkload)
+		if err != nil {
+			t.Fatalf("error marshalling RmdWorkload: (%v)", err)
+		}
+		req := admission.Request{
+			AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Operation: tc.operation,
+				Namespace: "default",
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		}
+
+		validator := &rmdWorkloadValidator{reconciler: r, decoder: decoder}
+		response := validator.Handle(context.TODO(), req)
+		if response.Allowed != tc.expectedAllowed {
+			t.Errorf("%v failed: Expected allowed: %v, got: %v (%v)", tc.name, tc.expectedAllowed, response.Allowed, response.Result)
+		}
+		if !response.Allowed && response.Result.Code != http.StatusForbidden {
+			t.Errorf("%v failed: Expected code %v, got %v", tc.name, http.StatusForbidden, response.Result.Code)
+		}
+		ts.Close()
+	}
+}
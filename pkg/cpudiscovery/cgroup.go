@@ -0,0 +1,203 @@
+package cpudiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultCgroupPath is the mount point of the node's cgroup hierarchy
+	DefaultCgroupPath = "/sys/fs/cgroup"
+	// DefaultCPUManagerStateFile is the state file of the kubelet CPU manager
+	DefaultCPUManagerStateFile = "/var/lib/kubelet/cpu_manager_state"
+
+	cpusetFile          = "cpuset.cpus"
+	effectiveCpusetFile = "cpuset.cpus.effective"
+	// cgroupV2Controllers only exists at the root of a cgroup v2 (unified) hierarchy
+	cgroupV2Controllers = "cgroup.controllers"
+)
+
+// Cgroup discovers the CPUs of containers from the cpuset of their cgroup, or from the kubelet CPU
+// manager state file if the cgroup cannot be read. Both the cgroupfs and systemd cgroup drivers are
+// supported on cgroup v1 and v2. Devices are not discovered.
+//
+// The cpuset of a container without exclusive CPUs is the shared pool of the CPU manager, CPUs should
+// only be discovered for containers with exclusive CPUs.
+type Cgroup struct {
+	client              client.Reader
+	cgroupPath          string
+	cpuManagerStateFile string
+}
+
+// cpuManagerState is the checkpoint written by the kubelet CPU manager
+type cpuManagerState struct {
+	PolicyName    string                       `json:"policyName"`
+	DefaultCPUSet string                       `json:"defaultCpuSet"`
+	Entries       map[string]map[string]string `json:"entries,omitempty"`
+}
+
+// NewCgroup returns a Cgroup reading pods with c, cgroups below cgroupPath and the CPU manager state
+// from cpuManagerStateFile
+func NewCgroup(c client.Reader, cgroupPath, cpuManagerStateFile string) *Cgroup {
+	return &Cgroup{
+		client:              c,
+		cgroupPath:          cgroupPath,
+		cpuManagerStateFile: cpuManagerStateFile,
+	}
+}
+
+// GetContainerResources returns the CPUs of container containerName of pod podName in namespace
+func (c *Cgroup) GetContainerResources(namespace, podName, containerName string) (*podresourcesclient.Resources, error) {
+	logger := log.WithName("GetContainerResources")
+	pod := &corev1.Pod{}
+	err := c.client.Get(context.TODO(), types.NamespacedName{Name: podName, Namespace: namespace}, pod)
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := c.cgroupCPUs(pod, containerName)
+	if err != nil {
+		logger.Info("Could not read container cpuset, reading CPU manager state.", "pod", podName, "container", containerName, "Error:", err)
+		cpus, err = c.stateFileCPUs(pod, containerName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &podresourcesclient.Resources{
+		CPUs:      cpus,
+		Devices:   make(map[string][]podresourcesclient.Device),
+		Memory:    make([]podresourcesclient.Memory, 0),
+		NUMANodes: make([]int64, 0),
+	}, nil
+}
+
+// GetContainerCPUs returns the CPUs of container containerName of pod podName in namespace
+func (c *Cgroup) GetContainerCPUs(namespace, podName, containerName string) ([]string, error) {
+	resources, err := c.GetContainerResources(namespace, podName, containerName)
+	if err != nil {
+		return nil, err
+	}
+	return resources.CPUs, nil
+}
+
+// cgroupCPUs reads the cpuset of the cgroup of container containerName of pod
+func (c *Cgroup) cgroupCPUs(pod *corev1.Pod, containerName string) ([]string, error) {
+	containerID := containerID(pod, containerName)
+	if containerID == "" {
+		return nil, fmt.Errorf("container %s has not been started", containerName)
+	}
+	hierarchy := filepath.Join(c.cgroupPath, "cpuset")
+	cgroupV2 := false
+	if _, err := os.Stat(filepath.Join(c.cgroupPath, cgroupV2Controllers)); err == nil {
+		hierarchy = c.cgroupPath
+		cgroupV2 = true
+	}
+	for _, podDir := range podCgroupDirs(hierarchy, string(pod.GetObjectMeta().GetUID())) {
+		// The container cgroup is named after the container ID, prefixed with the container
+		// runtime and suffixed with .scope by the systemd driver.
+		containerDirs, err := filepath.Glob(filepath.Join(podDir, "*"+containerID+"*"))
+		if err != nil || len(containerDirs) == 0 {
+			continue
+		}
+		cpus, err := readCPUs(filepath.Join(containerDirs[0], cpusetFile))
+		if err != nil && cgroupV2 {
+			// cpuset.cpus is empty in cgroup v2 unless written, the effective cpuset is inherited
+			cpus, err = readCPUs(filepath.Join(containerDirs[0], effectiveCpusetFile))
+		}
+		return cpus, err
+	}
+	return nil, fmt.Errorf("cgroup of container %s not found in %s", containerName, hierarchy)
+}
+
+// stateFileCPUs reads the exclusive CPUs assigned to container containerName of pod from the CPU
+// manager state file
+func (c *Cgroup) stateFileCPUs(pod *corev1.Pod, containerName string) ([]string, error) {
+	content, err := ioutil.ReadFile(c.cpuManagerStateFile)
+	if err != nil {
+		return nil, err
+	}
+	state := cpuManagerState{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("invalid CPU manager state %s: %v", c.cpuManagerStateFile, err)
+	}
+	assignment, ok := state.Entries[string(pod.GetObjectMeta().GetUID())][containerName]
+	if !ok {
+		return nil, fmt.Errorf("no CPUs assigned to container %s of pod %s in %s", containerName, pod.GetObjectMeta().GetName(), c.cpuManagerStateFile)
+	}
+	return parseCPUs(assignment)
+}
+
+// containerID returns the ID of container containerName of pod without the container runtime prefix,
+// or an empty string if the container has not been started
+func containerID(pod *corev1.Pod, containerName string) string {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.Name != containerName {
+			continue
+		}
+		id := status.ContainerID
+		if i := strings.Index(id, "://"); i != -1 {
+			id = id[i+len("://"):]
+		}
+		return id
+	}
+	return ""
+}
+
+// podCgroupDirs returns the directories the cgroup of the pod with UID podUID may have in hierarchy.
+// Guaranteed pods are placed directly below kubepods, other pods below their QoS class.
+func podCgroupDirs(hierarchy, podUID string) []string {
+	systemdUID := strings.Replace(podUID, "-", "_", -1)
+	patterns := []string{
+		// cgroupfs driver
+		filepath.Join(hierarchy, "kubepods", "pod"+podUID),
+		filepath.Join(hierarchy, "kubepods", "*", "pod"+podUID),
+		// systemd driver
+		filepath.Join(hierarchy, "kubepods.slice", "kubepods-pod"+systemdUID+".slice"),
+		filepath.Join(hierarchy, "kubepods.slice", "kubepods-*.slice", "kubepods-*-pod"+systemdUID+".slice"),
+	}
+	podDirs := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		podDirs = append(podDirs, matches...)
+	}
+	return podDirs
+}
+
+// readCPUs reads the cpuset in cpuset file path
+func readCPUs(path string) ([]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUs(string(content))
+}
+
+// parseCPUs returns the CPUs of cpuset cpus in ascending order
+func parseCPUs(cpus string) ([]string, error) {
+	set, err := cpuset.Parse(strings.TrimSpace(cpus))
+	if err != nil {
+		return nil, err
+	}
+	if set.IsEmpty() {
+		return nil, fmt.Errorf("cpuset is empty")
+	}
+	cpuIDs := make([]string, 0)
+	for _, cpu := range set.ToSlice() {
+		cpuIDs = append(cpuIDs, strconv.Itoa(cpu))
+	}
+	return cpuIDs, nil
+}
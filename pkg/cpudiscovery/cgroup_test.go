@@ -0,0 +1,157 @@
+package cpudiscovery
+
+import (
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+const cpuManagerStateContent = `{"policyName":"static","defaultCpuSet":"0-1,8-15","entries":{"pod-1-uid":{"container-1":"4-5"}},"checksum":1234}`
+
+const systemdPodUID = "3f7c1e5a-9d1b-4c8e-a0f2-6b2d4e8c1a90"
+
+func cgroupPod(uid, containerID string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			UID:       types.UID(uid),
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "container-1"}},
+		},
+	}
+	if containerID != "" {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "container-1", ContainerID: containerID}}
+	}
+	return pod
+}
+
+// writeFakeSysfs writes files, keyed by path relative to root, to a new temporary directory
+func writeFakeSysfs(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatalf("error creating fake sysfs: (%v)", err)
+	}
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error creating fake sysfs: (%v)", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error creating fake sysfs: (%v)", err)
+		}
+	}
+	return root
+}
+
+func TestCgroupGetContainerResources(t *testing.T) {
+	tcases := []struct {
+		name          string
+		pod           *corev1.Pod
+		files         map[string]string
+		expectedCPUs  []string
+		expectedError bool
+	}{
+		{
+			name: "test case 1 - cgroup v1, cgroupfs driver, guaranteed pod",
+			pod:  cgroupPod("pod-1-uid", "docker://abc123"),
+			files: map[string]string{
+				"cgroup/cpuset/kubepods/podpod-1-uid/abc123/cpuset.cpus": "2-3\n",
+				"cgroup/cpuset/kubepods/podpod-1-uid/def456/cpuset.cpus": "6-7\n",
+			},
+			expectedCPUs: []string{"2", "3"},
+		},
+		{
+			name: "test case 2 - cgroup v1, systemd driver, burstable pod",
+			pod:  cgroupPod(systemdPodUID, "containerd://abc123"),
+			files: map[string]string{
+				"cgroup/cpuset/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod3f7c1e5a_9d1b_4c8e_a0f2_6b2d4e8c1a90.slice/cri-containerd-abc123.scope/cpuset.cpus": "0-1,8-15\n",
+			},
+			expectedCPUs: []string{"0", "1", "8", "9", "10", "11", "12", "13", "14", "15"},
+		},
+		{
+			name: "test case 3 - cgroup v2, systemd driver, guaranteed pod",
+			pod:  cgroupPod(systemdPodUID, "cri-o://abc123"),
+			files: map[string]string{
+				"cgroup/cgroup.controllers": "cpuset cpu io memory pids\n",
+				"cgroup/kubepods.slice/kubepods-pod3f7c1e5a_9d1b_4c8e_a0f2_6b2d4e8c1a90.slice/crio-abc123.scope/cpuset.cpus": "6,2\n",
+			},
+			expectedCPUs: []string{"2", "6"},
+		},
+		{
+			name: "test case 4 - cgroup v2, cgroupfs driver, effective cpuset",
+			pod:  cgroupPod("pod-1-uid", "containerd://abc123"),
+			files: map[string]string{
+				"cgroup/cgroup.controllers":                                            "cpuset cpu io memory pids\n",
+				"cgroup/kubepods/besteffort/podpod-1-uid/abc123/cpuset.cpus":           "\n",
+				"cgroup/kubepods/besteffort/podpod-1-uid/abc123/cpuset.cpus.effective": "0-3\n",
+			},
+			expectedCPUs: []string{"0", "1", "2", "3"},
+		},
+		{
+			name: "test case 5 - container not started, CPU manager state",
+			pod:  cgroupPod("pod-1-uid", ""),
+			files: map[string]string{
+				"cpu_manager_state": cpuManagerStateContent,
+			},
+			expectedCPUs: []string{"4", "5"},
+		},
+		{
+			name: "test case 6 - cgroup not found, CPU manager state",
+			pod:  cgroupPod("pod-1-uid", "containerd://abc123"),
+			files: map[string]string{
+				"cgroup/cpuset/kubepods/podpod-1-uid/def456/cpuset.cpus": "6-7\n",
+				"cpu_manager_state": cpuManagerStateContent,
+			},
+			expectedCPUs: []string{"4", "5"},
+		},
+		{
+			name: "test case 7 - container without exclusive CPUs in CPU manager state",
+			pod:  cgroupPod(systemdPodUID, ""),
+			files: map[string]string{
+				"cpu_manager_state": cpuManagerStateContent,
+			},
+			expectedError: true,
+		},
+		{
+			name:          "test case 8 - no cgroup and no CPU manager state",
+			pod:           cgroupPod("pod-1-uid", "containerd://abc123"),
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		root := writeFakeSysfs(t, tc.files)
+		c := NewCgroup(fake.NewFakeClientWithScheme(scheme.Scheme, tc.pod), filepath.Join(root, "cgroup"), filepath.Join(root, "cpu_manager_state"))
+		resources, err := c.GetContainerResources("default", "pod-1", "container-1")
+		os.RemoveAll(root)
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("%v failed: Expected error, got CPUs %v", tc.name, resources.CPUs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(resources.CPUs, tc.expectedCPUs) {
+			t.Errorf("%v failed: Expected CPUs %v, got %v", tc.name, tc.expectedCPUs, resources.CPUs)
+		}
+	}
+}
+
+func TestCgroupPodNotFound(t *testing.T) {
+	c := NewCgroup(fake.NewFakeClientWithScheme(scheme.Scheme), DefaultCgroupPath, DefaultCPUManagerStateFile)
+	_, err := c.GetContainerCPUs("default", "pod-1", "container-1")
+	if err == nil {
+		t.Errorf("Expected error for pod not found")
+	}
+}
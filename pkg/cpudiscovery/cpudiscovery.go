@@ -0,0 +1,94 @@
+// Package cpudiscovery discovers the CPUs and devices the kubelet assigned to the containers of the
+// node, either from the kubelet podresources endpoint or from the container cgroups and the CPU
+// manager state file.
+package cpudiscovery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// CPU discovery backends
+const (
+	// PodResourcesBackend reads container resources from the kubelet podresources endpoint
+	PodResourcesBackend = "podresources"
+	// CgroupBackend reads container CPUs from the container cgroups or the CPU manager state file
+	CgroupBackend = "cgroup"
+	// AutoBackend reads container resources from the kubelet podresources endpoint and falls back
+	// to the cgroup backend if the endpoint is unavailable
+	AutoBackend = "auto"
+)
+
+var log = logf.Log.WithName("cpudiscovery")
+
+// Discoverer returns the resources assigned to a container by the kubelet
+type Discoverer interface {
+	GetContainerResources(namespace, podName, containerName string) (*podresourcesclient.Resources, error)
+	GetContainerCPUs(namespace, podName, containerName string) ([]string, error)
+}
+
+// blank assignment to verify that PodResourcesClient implements Discoverer
+var _ Discoverer = &podresourcesclient.PodResourcesClient{}
+
+// Config selects and configures the CPU discovery backend
+type Config struct {
+	// Backend is PodResourcesBackend, CgroupBackend or AutoBackend
+	Backend string
+	// PodResourcesSocket is the kubelet podresources socket
+	PodResourcesSocket string
+	// PodResourcesTimeout is the timeout for connecting to PodResourcesSocket
+	PodResourcesTimeout time.Duration
+	// CgroupPath is the mount point of the node's cgroup hierarchy
+	CgroupPath string
+	// CPUManagerStateFile is the kubelet CPU manager state file
+	CPUManagerStateFile string
+}
+
+// New returns the Discoverer selected by config. Pods are read with c by the cgroup backend.
+func New(config Config, c client.Reader) (Discoverer, error) {
+	switch config.Backend {
+	case PodResourcesBackend:
+		return podresourcesclient.NewPodResourcesClientForSocket(config.PodResourcesSocket, config.PodResourcesTimeout)
+	case CgroupBackend:
+		return NewCgroup(c, config.CgroupPath, config.CPUManagerStateFile), nil
+	case AutoBackend:
+		cgroup := NewCgroup(c, config.CgroupPath, config.CPUManagerStateFile)
+		podResourcesClient, err := podresourcesclient.NewPodResourcesClientForSocket(config.PodResourcesSocket, config.PodResourcesTimeout)
+		if err != nil {
+			log.Info("Kubelet podresources endpoint unavailable, discovering CPUs from cgroups.", "socket", config.PodResourcesSocket, "Error:", err)
+			return cgroup, nil
+		}
+		return Fallback{podResourcesClient, cgroup}, nil
+	}
+	return nil, fmt.Errorf("unknown CPU discovery backend %q, must be %s, %s or %s", config.Backend, PodResourcesBackend, CgroupBackend, AutoBackend)
+}
+
+// Fallback tries each Discoverer in turn and returns the resources of the first that succeeds
+type Fallback []Discoverer
+
+// GetContainerResources returns the resources of container containerName of pod podName in namespace
+func (f Fallback) GetContainerResources(namespace, podName, containerName string) (*podresourcesclient.Resources, error) {
+	errs := make([]string, 0)
+	for _, discoverer := range f {
+		resources, err := discoverer.GetContainerResources(namespace, podName, containerName)
+		if err == nil {
+			return resources, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("resources for Pod:%v/%v Container:%v not discovered: %s", namespace, podName, containerName, strings.Join(errs, "; "))
+}
+
+// GetContainerCPUs returns the CPUs of container containerName of pod podName in namespace
+func (f Fallback) GetContainerCPUs(namespace, podName, containerName string) ([]string, error) {
+	resources, err := f.GetContainerResources(namespace, podName, containerName)
+	if err != nil {
+		return nil, err
+	}
+	return resources.CPUs, nil
+}
@@ -0,0 +1,121 @@
+package cpudiscovery
+
+import (
+	"fmt"
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
+	"k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// fakeDiscoverer returns the CPUs of container-1 of pod-1, or an error if cpus is nil
+type fakeDiscoverer struct {
+	cpus []string
+}
+
+func (f fakeDiscoverer) GetContainerResources(namespace, podName, containerName string) (*podresourcesclient.Resources, error) {
+	if f.cpus == nil {
+		return nil, fmt.Errorf("endpoint unavailable")
+	}
+	return &podresourcesclient.Resources{CPUs: f.cpus}, nil
+}
+
+func (f fakeDiscoverer) GetContainerCPUs(namespace, podName, containerName string) ([]string, error) {
+	resources, err := f.GetContainerResources(namespace, podName, containerName)
+	if err != nil {
+		return nil, err
+	}
+	return resources.CPUs, nil
+}
+
+func TestFallback(t *testing.T) {
+	tcases := []struct {
+		name          string
+		fallback      Fallback
+		expectedCPUs  []string
+		expectedError string
+	}{
+		{
+			name:         "test case 1 - first discoverer succeeds",
+			fallback:     Fallback{fakeDiscoverer{cpus: []string{"2", "3"}}, fakeDiscoverer{cpus: []string{"4", "5"}}},
+			expectedCPUs: []string{"2", "3"},
+		},
+		{
+			name:         "test case 2 - falling back to second discoverer",
+			fallback:     Fallback{fakeDiscoverer{}, fakeDiscoverer{cpus: []string{"4", "5"}}},
+			expectedCPUs: []string{"4", "5"},
+		},
+		{
+			name:          "test case 3 - all discoverers fail",
+			fallback:      Fallback{fakeDiscoverer{}, fakeDiscoverer{}},
+			expectedError: "resources for Pod:default/pod-1 Container:container-1 not discovered: endpoint unavailable; endpoint unavailable",
+		},
+	}
+	for _, tc := range tcases {
+		cpus, err := tc.fallback.GetContainerCPUs("default", "pod-1", "container-1")
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != tc.expectedError {
+			t.Errorf("%v failed: Expected error %q, got %q", tc.name, tc.expectedError, message)
+		}
+		if !reflect.DeepEqual(cpus, tc.expectedCPUs) {
+			t.Errorf("%v failed: Expected CPUs %v, got %v", tc.name, tc.expectedCPUs, cpus)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tcases := []struct {
+		name          string
+		backend       string
+		expectedType  string
+		expectedError bool
+	}{
+		{
+			name:         "test case 1 - podresources backend",
+			backend:      PodResourcesBackend,
+			expectedType: "*podresourcesclient.PodResourcesClient",
+		},
+		{
+			name:         "test case 2 - cgroup backend",
+			backend:      CgroupBackend,
+			expectedType: "*cpudiscovery.Cgroup",
+		},
+		{
+			name:         "test case 3 - auto backend",
+			backend:      AutoBackend,
+			expectedType: "cpudiscovery.Fallback",
+		},
+		{
+			name:          "test case 4 - unknown backend",
+			backend:       "kubelet",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		config := Config{
+			Backend:             tc.backend,
+			PodResourcesSocket:  podresourcesclient.DefaultSocket,
+			PodResourcesTimeout: podresourcesclient.DefaultTimeout,
+			CgroupPath:          DefaultCgroupPath,
+			CPUManagerStateFile: DefaultCPUManagerStateFile,
+		}
+		discoverer, err := New(config, fake.NewFakeClientWithScheme(scheme.Scheme))
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("%v failed: Expected error, got %T", tc.name, discoverer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+			continue
+		}
+		if fmt.Sprintf("%T", discoverer) != tc.expectedType {
+			t.Errorf("%v failed: Expected %v, got %T", tc.name, tc.expectedType, discoverer)
+		}
+	}
+}
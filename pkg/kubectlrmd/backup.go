@@ -0,0 +1,259 @@
+package kubectlrmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	restoreInterval  = 5 * time.Second
+	mbaPercentageKey = "MBA Percentage"
+	mbaMbpsKey       = "MBA Mbps"
+	policyKey        = "Policy"
+)
+
+// specKeys are the keys of a workload on RMD derived from the RmdWorkload spec. Keys assigned by RMD,
+// such as the ID and COS name, change when a workload is recreated and are not compared.
+var specKeys = []string{coreIDsKey, cacheMaxKey, cacheMinKey, mbaPercentageKey, mbaMbpsKey, policyKey}
+
+// Archive is a backup of the RDT configuration of a cluster
+type Archive struct {
+	Created      metav1.Time                 `json:"created"`
+	RmdConfigs   []intelv1alpha1.RmdConfig   `json:"rmdConfigs"`
+	RmdWorkloads []intelv1alpha1.RmdWorkload `json:"rmdWorkloads"`
+	// NodeWorkloads maps each node to the workloads on RMD of RmdWorkloads as recorded in its RmdNodeState
+	NodeWorkloads map[string]map[string]intelv1alpha1.WorkloadMap `json:"nodeWorkloads"`
+}
+
+// Backup writes an Archive of all RmdConfigs and RmdWorkloads and their workloads on RMD of each node
+// to out as YAML. RmdWorkloads owned by another object (ie created by the operator from pod annotations)
+// are recreated by their owner and are not included.
+func Backup(c client.Client, out io.Writer) error {
+	archive := Archive{
+		Created:       metav1.Now(),
+		RmdConfigs:    make([]intelv1alpha1.RmdConfig, 0),
+		RmdWorkloads:  make([]intelv1alpha1.RmdWorkload, 0),
+		NodeWorkloads: make(map[string]map[string]intelv1alpha1.WorkloadMap),
+	}
+
+	rmdConfigs := &intelv1alpha1.RmdConfigList{}
+	err := c.List(context.TODO(), rmdConfigs)
+	if err != nil {
+		return err
+	}
+	for _, rmdConfig := range rmdConfigs.Items {
+		archive.RmdConfigs = append(archive.RmdConfigs, intelv1alpha1.RmdConfig{
+			TypeMeta:   metav1.TypeMeta{APIVersion: intelv1alpha1.SchemeGroupVersion.String(), Kind: rmdConfigKind},
+			ObjectMeta: backupObjectMeta(rmdConfig.ObjectMeta),
+			Spec:       rmdConfig.Spec,
+		})
+	}
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err = c.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		return err
+	}
+	workloadNames := make(map[string]bool)
+	for _, rmdWorkload := range rmdWorkloads.Items {
+		if len(rmdWorkload.GetObjectMeta().GetOwnerReferences()) != 0 {
+			continue
+		}
+		workloadNames[rmdWorkload.GetObjectMeta().GetName()] = true
+		archive.RmdWorkloads = append(archive.RmdWorkloads, intelv1alpha1.RmdWorkload{
+			TypeMeta:   metav1.TypeMeta{APIVersion: intelv1alpha1.SchemeGroupVersion.String(), Kind: rmdWorkloadKind},
+			ObjectMeta: backupObjectMeta(rmdWorkload.ObjectMeta),
+			Spec:       rmdWorkload.Spec,
+		})
+	}
+
+	rmdNodeStates := &intelv1alpha1.RmdNodeStateList{}
+	err = c.List(context.TODO(), rmdNodeStates)
+	if err != nil {
+		return err
+	}
+	for _, rmdNodeState := range rmdNodeStates.Items {
+		archive.NodeWorkloads[rmdNodeState.Spec.Node] = filterWorkloads(rmdNodeState.Status.Workloads, workloadNames)
+	}
+
+	data, err := yaml.Marshal(archive)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// backupObjectMeta returns the fields of objectMeta needed to recreate an object
+func backupObjectMeta(objectMeta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        objectMeta.Name,
+		Namespace:   objectMeta.Namespace,
+		Labels:      objectMeta.Labels,
+		Annotations: objectMeta.Annotations,
+	}
+}
+
+// LoadArchive reads an Archive written by Backup from file
+func LoadArchive(file string) (*Archive, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{}
+	err = yaml.Unmarshal(data, archive)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return archive, nil
+}
+
+// Restore creates the RmdConfigs and RmdWorkloads of archive that do not exist and waits up to timeout
+// for the workloads on RMD of each node to match those of the archive. Only the workloads of the
+// RmdWorkloads in archive are compared. Each difference between the archive and the live state
+// remaining after timeout is written to out and an error is returned.
+func Restore(c client.Client, archive *Archive, timeout time.Duration, out io.Writer) error {
+	workloadNames := make(map[string]bool)
+	for _, rmdWorkload := range archive.RmdWorkloads {
+		workloadNames[rmdWorkload.GetObjectMeta().GetName()] = true
+	}
+	expected := make(map[string]map[string]intelv1alpha1.WorkloadMap)
+	for nodeName, workloads := range archive.NodeWorkloads {
+		expected[nodeName] = filterWorkloads(workloads, workloadNames)
+	}
+
+	for i := range archive.RmdConfigs {
+		rmdConfig := archive.RmdConfigs[i].DeepCopy()
+		err := restoreObject(c, rmdConfig, rmdConfigKind, out)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range archive.RmdWorkloads {
+		rmdWorkload := archive.RmdWorkloads[i].DeepCopy()
+		err := restoreObject(c, rmdWorkload, rmdWorkloadKind, out)
+		if err != nil {
+			return err
+		}
+	}
+
+	var diffs []string
+	err := wait.PollImmediate(restoreInterval, timeout, func() (bool, error) {
+		rmdNodeStates := &intelv1alpha1.RmdNodeStateList{}
+		err := c.List(context.TODO(), rmdNodeStates)
+		if err != nil {
+			return false, err
+		}
+		live := make(map[string]map[string]intelv1alpha1.WorkloadMap)
+		for _, rmdNodeState := range rmdNodeStates.Items {
+			live[rmdNodeState.Spec.Node] = filterWorkloads(rmdNodeState.Status.Workloads, workloadNames)
+		}
+		diffs = DiffNodeWorkloads(expected, live)
+		return len(diffs) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		for _, diff := range diffs {
+			fmt.Fprintln(out, diff)
+		}
+		return fmt.Errorf("live RMD state does not match the backup after %v", timeout)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "live RMD state matches the backup")
+	return nil
+}
+
+// restoreObject creates obj unless an object of the same name exists
+func restoreObject(c client.Client, obj interface {
+	runtime.Object
+	metav1.Object
+}, kind string, out io.Writer) error {
+	name := obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = fmt.Sprintf("%s/%s", obj.GetNamespace(), name)
+	}
+	err := c.Create(context.TODO(), obj)
+	if errors.IsAlreadyExists(err) {
+		fmt.Fprintf(out, "%s %s exists, not restored\n", kind, name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", kind, name, err)
+	}
+	fmt.Fprintf(out, "%s %s created\n", kind, name)
+	return nil
+}
+
+// filterWorkloads returns the workloads of workloadNames in workloads
+func filterWorkloads(workloads map[string]intelv1alpha1.WorkloadMap, workloadNames map[string]bool) map[string]intelv1alpha1.WorkloadMap {
+	filtered := make(map[string]intelv1alpha1.WorkloadMap)
+	for workloadName, workload := range workloads {
+		if workloadNames[workloadName] {
+			filtered[workloadName] = workload
+		}
+	}
+	return filtered
+}
+
+// DiffNodeWorkloads returns the differences between the workloads on RMD of each node in expected and
+// live, sorted by node and workload. Only the keys derived from the RmdWorkload spec are compared.
+func DiffNodeWorkloads(expected, live map[string]map[string]intelv1alpha1.WorkloadMap) []string {
+	nodeNames := make(map[string]bool)
+	for nodeName := range expected {
+		nodeNames[nodeName] = true
+	}
+	for nodeName := range live {
+		nodeNames[nodeName] = true
+	}
+
+	diffs := make([]string, 0)
+	for _, nodeName := range sortedKeys(nodeNames) {
+		workloadNames := make(map[string]bool)
+		for workloadName := range expected[nodeName] {
+			workloadNames[workloadName] = true
+		}
+		for workloadName := range live[nodeName] {
+			workloadNames[workloadName] = true
+		}
+		for _, workloadName := range sortedKeys(workloadNames) {
+			expectedWorkload, inExpected := expected[nodeName][workloadName]
+			liveWorkload, inLive := live[nodeName][workloadName]
+			switch {
+			case !inLive:
+				diffs = append(diffs, fmt.Sprintf("%s: workload %s missing on RMD", nodeName, workloadName))
+			case !inExpected:
+				diffs = append(diffs, fmt.Sprintf("%s: workload %s on RMD not in backup", nodeName, workloadName))
+			default:
+				for _, key := range specKeys {
+					if expectedWorkload[key] != liveWorkload[key] {
+						diffs = append(diffs, fmt.Sprintf("%s: workload %s %s is %q, %q in backup",
+							nodeName, workloadName, key, liveWorkload[key], expectedWorkload[key]))
+					}
+				}
+			}
+		}
+	}
+	return diffs
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
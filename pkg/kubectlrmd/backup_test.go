@@ -0,0 +1,238 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+func createBackupClient(t *testing.T, objects ...runtime.Object) client.Client {
+	s := scheme.Scheme
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatalf("error adding scheme: (%v)", err)
+	}
+	return fake.NewFakeClient(objects...)
+}
+
+func backupNodeState(workloads map[string]intelv1alpha1.WorkloadMap) *intelv1alpha1.RmdNodeState {
+	return &intelv1alpha1.RmdNodeState{
+		ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-1.com", Namespace: "default"},
+		Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-1.com"},
+		Status:     intelv1alpha1.RmdNodeStateStatus{Workloads: workloads},
+	}
+}
+
+func TestBackup(t *testing.T) {
+	c := createBackupClient(t,
+		&intelv1alpha1.RmdConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmdconfig", Namespace: "default", ResourceVersion: "10"},
+			Spec:       intelv1alpha1.RmdConfigSpec{RmdImage: "rmd", RmdNodeSelector: map[string]string{"rdt": "true"}},
+			Status:     intelv1alpha1.RmdConfigStatus{Nodes: []string{"example-node-1.com"}},
+		},
+		&intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-1", Namespace: "default", Labels: map[string]string{"app": "db"}},
+			Spec:       intelv1alpha1.RmdWorkloadSpec{Nodes: []string{"example-node-1.com"}, CoreIds: []string{"0-1"}},
+			Status:     intelv1alpha1.RmdWorkloadStatus{WorkloadStates: map[string]intelv1alpha1.WorkloadState{"example-node-1.com": {Status: "Successful"}}},
+		},
+		&intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "pod-1-rmd-workload-container-1",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "pod-1"}},
+			},
+			Spec: intelv1alpha1.RmdWorkloadSpec{Nodes: []string{"example-node-1.com"}, CoreIds: []string{"2-3"}},
+		},
+		backupNodeState(map[string]intelv1alpha1.WorkloadMap{
+			"rmd-workload-1":                 {"Core IDs": "0-1", "Status": "Successful"},
+			"pod-1-rmd-workload-container-1": {"Core IDs": "2-3", "Status": "Successful"},
+		}),
+	)
+
+	var out bytes.Buffer
+	err := Backup(c, &out)
+	if err != nil {
+		t.Fatalf("Backup failed: (%v)", err)
+	}
+	archive := &Archive{}
+	err = yaml.Unmarshal(out.Bytes(), archive)
+	if err != nil {
+		t.Fatalf("error reading archive: (%v)", err)
+	}
+
+	expectedRmdConfigs := []intelv1alpha1.RmdConfig{
+		{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "intel.com/v1alpha1", Kind: "RmdConfig"},
+			ObjectMeta: metav1.ObjectMeta{Name: "rmdconfig", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdConfigSpec{RmdImage: "rmd", RmdNodeSelector: map[string]string{"rdt": "true"}},
+		},
+	}
+	expectedRmdWorkloads := []intelv1alpha1.RmdWorkload{
+		{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "intel.com/v1alpha1", Kind: "RmdWorkload"},
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-1", Namespace: "default", Labels: map[string]string{"app": "db"}},
+			Spec:       intelv1alpha1.RmdWorkloadSpec{Nodes: []string{"example-node-1.com"}, CoreIds: []string{"0-1"}},
+		},
+	}
+	expectedNodeWorkloads := map[string]map[string]intelv1alpha1.WorkloadMap{
+		"example-node-1.com": {
+			"rmd-workload-1": {"Core IDs": "0-1", "Status": "Successful"},
+		},
+	}
+	if !reflect.DeepEqual(archive.RmdConfigs, expectedRmdConfigs) {
+		t.Errorf("Expected RmdConfigs %+v, got %+v", expectedRmdConfigs, archive.RmdConfigs)
+	}
+	if !reflect.DeepEqual(archive.RmdWorkloads, expectedRmdWorkloads) {
+		t.Errorf("Expected RmdWorkloads %+v, got %+v", expectedRmdWorkloads, archive.RmdWorkloads)
+	}
+	if !reflect.DeepEqual(archive.NodeWorkloads, expectedNodeWorkloads) {
+		t.Errorf("Expected node workloads %v, got %v", expectedNodeWorkloads, archive.NodeWorkloads)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	archive := &Archive{
+		RmdConfigs: []intelv1alpha1.RmdConfig{
+			{ObjectMeta: metav1.ObjectMeta{Name: "rmdconfig", Namespace: "default"}},
+		},
+		RmdWorkloads: []intelv1alpha1.RmdWorkload{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-1", Namespace: "default"},
+				Spec:       intelv1alpha1.RmdWorkloadSpec{CoreIds: []string{"0-1"}},
+			},
+		},
+		NodeWorkloads: map[string]map[string]intelv1alpha1.WorkloadMap{
+			"example-node-1.com": {"rmd-workload-1": {"Core IDs": "0-1", "Status": "Successful"}},
+		},
+	}
+
+	tcases := []struct {
+		name          string
+		objects       []runtime.Object
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:    "test case 1 - live state converged",
+			objects: []runtime.Object{backupNodeState(map[string]intelv1alpha1.WorkloadMap{"rmd-workload-1": {"Core IDs": "0-1"}})},
+			expected: `RmdConfig default/rmdconfig created
+RmdWorkload default/rmd-workload-1 created
+live RMD state matches the backup
+`,
+		},
+		{
+			name: "test case 2 - existing RmdWorkload, live state differs",
+			objects: []runtime.Object{
+				&intelv1alpha1.RmdWorkload{ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-1", Namespace: "default"}},
+				backupNodeState(map[string]intelv1alpha1.WorkloadMap{
+					"rmd-workload-1": {"Core IDs": "0-3"},
+				}),
+			},
+			expected: `RmdConfig default/rmdconfig created
+RmdWorkload default/rmd-workload-1 exists, not restored
+example-node-1.com: workload rmd-workload-1 Core IDs is "0-3", "0-1" in backup
+`,
+			expectedError: true,
+		},
+		{
+			name: "test case 3 - workloads of RmdWorkloads not in backup ignored",
+			objects: []runtime.Object{
+				backupNodeState(map[string]intelv1alpha1.WorkloadMap{
+					"rmd-workload-1":                 {"Core IDs": "0-1"},
+					"pod-1-rmd-workload-container-1": {"Core IDs": "2-3"},
+				}),
+			},
+			expected: `RmdConfig default/rmdconfig created
+RmdWorkload default/rmd-workload-1 created
+live RMD state matches the backup
+`,
+		},
+		{
+			name:    "test case 4 - node not running RMD",
+			objects: []runtime.Object{},
+			expected: `RmdConfig default/rmdconfig created
+RmdWorkload default/rmd-workload-1 created
+example-node-1.com: workload rmd-workload-1 missing on RMD
+`,
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		c := createBackupClient(t, tc.objects...)
+		var out bytes.Buffer
+		err := Restore(c, archive, 10*time.Millisecond, &out)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+		}
+		if out.String() != tc.expected {
+			t.Errorf("%v failed: Expected:\n%v\ngot:\n%v", tc.name, tc.expected, out.String())
+		}
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = c.Get(context.TODO(), client.ObjectKey{Name: "rmd-workload-1", Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Errorf("%v failed: Expected RmdWorkload to exist, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestDiffNodeWorkloads(t *testing.T) {
+	tcases := []struct {
+		name     string
+		expected map[string]map[string]intelv1alpha1.WorkloadMap
+		live     map[string]map[string]intelv1alpha1.WorkloadMap
+		diffs    []string
+	}{
+		{
+			name: "test case 1 - equal apart from status",
+			expected: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"Core IDs": "0-1", "Cache Max": "2", "Status": "Successful"}},
+			},
+			live: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"Core IDs": "0-1", "Cache Max": "2", "Status": "Failed"}},
+			},
+			diffs: []string{},
+		},
+		{
+			name: "test case 2 - equal apart from keys assigned by RMD",
+			expected: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"ID": "1", "Core IDs": "0-1", "Cos Name": "workload-1-guarantee", "Policy": "gold"}},
+			},
+			live: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"ID": "7", "Core IDs": "0-1", "Cos Name": "workload-1-guarantee-2", "Policy": "gold"}},
+			},
+			diffs: []string{},
+		},
+		{
+			name: "test case 3 - missing, unexpected and changed workloads",
+			expected: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"Core IDs": "0-1", "Cache Max": "2"}, "workload-2": {"Core IDs": "2"}},
+				"node-2": {"workload-3": {"Core IDs": "0"}},
+			},
+			live: map[string]map[string]intelv1alpha1.WorkloadMap{
+				"node-1": {"workload-1": {"Core IDs": "0-1", "Cache Max": "3"}, "workload-4": {"Core IDs": "3"}},
+			},
+			diffs: []string{
+				`node-1: workload workload-1 Cache Max is "3", "2" in backup`,
+				"node-1: workload workload-2 missing on RMD",
+				"node-1: workload workload-4 on RMD not in backup",
+				"node-2: workload workload-3 missing on RMD",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		diffs := DiffNodeWorkloads(tc.expected, tc.live)
+		if !reflect.DeepEqual(diffs, tc.diffs) {
+			t.Errorf("%v failed: Expected %v, got %v", tc.name, tc.diffs, diffs)
+		}
+	}
+}
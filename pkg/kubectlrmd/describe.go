@@ -0,0 +1,114 @@
+package kubectlrmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// coresPerLine is the number of cores drawn per line of the core bitmap
+const coresPerLine = 32
+
+// DescribeNode writes the RDT inventory of nodeName: bitmaps of the cores used by RMD workloads and of
+// the used ways of each cache, followed by the workloads configured on RMD.
+// Bitmaps are drawn from index 0 on the left, '#' marks used cores and ways.
+func DescribeNode(c client.Client, nodeName string, out io.Writer) error {
+	inv, err := getInventory(c, "")
+	if err != nil {
+		return err
+	}
+	rmdNodeState, ok := inv.nodeStates[nodeName]
+	if !ok {
+		return errors.NewNotFound(intelv1alpha1.SchemeGroupVersion.WithResource("rmdnodestates").GroupResource(), nodeName)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Node:\t%s\n", nodeName)
+	fmt.Fprintf(w, "CDP:\t%s\n", cdpString(rmdNodeState))
+
+	cores := usedCores(rmdNodeState)
+	numCores := inv.nodeCPUs(nodeName)
+	if coreIDs := cores.ToSlice(); len(coreIDs) != 0 && coreIDs[len(coreIDs)-1] >= numCores {
+		// Node capacity unknown or smaller than the cores in use.
+		numCores = coreIDs[len(coreIDs)-1] + 1
+	}
+	fmt.Fprintf(w, "Cores:\t%d/%d used\n", cores.Size(), numCores)
+	for start := 0; start < numCores; start += coresPerLine {
+		end := start + coresPerLine
+		if end > numCores {
+			end = numCores
+		}
+		fmt.Fprintf(w, "  %d-%d\t[%s]\n", start, end-1, bitmap(shiftSet(cores, start), end-start))
+	}
+
+	writeCaches(w, "L3 Cache", rmdNodeState.Status.L3Caches)
+	writeCaches(w, "L2 Cache", rmdNodeState.Status.L2Caches)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Workloads:")
+	if len(rmdNodeState.Status.Workloads) == 0 {
+		fmt.Fprintln(out, "  <none>")
+		return nil
+	}
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSTATUS\tCOS\tCORES\tCACHE MAX/MIN")
+	names := make([]string, 0)
+	for name := range rmdNodeState.Status.Workloads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		workloadMap := rmdNodeState.Status.Workloads[name]
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s/%s\n", name, valueOrDash(workloadMap[statusKey]), valueOrDash(workloadMap[cosNameKey]),
+			valueOrDash(workloadMap[coreIDsKey]), valueOrDash(workloadMap[cacheMaxKey]), valueOrDash(workloadMap[cacheMinKey]))
+	}
+	return w.Flush()
+}
+
+// writeCaches writes the way bitmap and free ways per pool of each cache
+func writeCaches(w io.Writer, title string, caches map[string]intelv1alpha1.CacheMap) {
+	for _, id := range cacheIDs(caches) {
+		numWays, free := cacheWays(caches[id])
+		used := usedWays(numWays, free)
+		fmt.Fprintf(w, "%s %s:\t%d/%d ways used\n", title, id, used.Size(), numWays)
+		fmt.Fprintf(w, "  ways\t[%s]\n", bitmap(used, numWays))
+		pools := make([]string, 0)
+		for pool := range free {
+			pools = append(pools, pool)
+		}
+		sort.Strings(pools)
+		for _, pool := range pools {
+			fmt.Fprintf(w, "  free %s\t%s\n", pool, setString(free[pool]))
+		}
+	}
+}
+
+// shiftSet returns the members of set shifted down by offset, dropping negative members
+func shiftSet(set cpuset.CPUSet, offset int) cpuset.CPUSet {
+	builder := cpuset.NewBuilder()
+	for _, member := range set.ToSlice() {
+		if member >= offset {
+			builder.Add(member - offset)
+		}
+	}
+	return builder.Result()
+}
+
+func cdpString(rmdNodeState *intelv1alpha1.RmdNodeState) string {
+	switch {
+	case !rmdNodeState.Status.CdpSupported:
+		return "not supported"
+	case rmdNodeState.Status.CdpEnabled:
+		return "supported, enabled"
+	default:
+		return "supported, disabled"
+	}
+}
@@ -0,0 +1,58 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDescribeNode(t *testing.T) {
+	tcases := []struct {
+		name          string
+		nodeName      string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:     "test case 1 - node with workload",
+			nodeName: "example-node-1.com",
+			expected: `Node:              example-node-1.com
+CDP:               supported, disabled
+Cores:             2/8 used
+  0-7              [##......]
+L3 Cache 0:        2/11 ways used
+  ways             [##.........]
+  free guaranteed  2-9
+  free shared      10
+Workloads:
+  NAME            STATUS      COS            CORES  CACHE MAX/MIN
+  rmd-workload-1  Successful  0-1-guarantee  0-1    2/2
+`,
+		},
+		{
+			name:     "test case 2 - node without inventory",
+			nodeName: "example-node-2.com",
+			expected: `Node:   example-node-2.com
+CDP:    not supported
+Cores:  0/0 used
+Workloads:
+  <none>
+`,
+		},
+		{
+			name:          "test case 3 - node not running RMD",
+			nodeName:      "example-node-3.com",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		var out bytes.Buffer
+		err := DescribeNode(createInventoryClient(t), tc.nodeName, &out)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+			continue
+		}
+		if err == nil && out.String() != tc.expected {
+			t.Errorf("%v failed: Expected:\n%v\ngot:\n%v", tc.name, tc.expected, out.String())
+		}
+	}
+}
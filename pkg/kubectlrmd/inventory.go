@@ -0,0 +1,182 @@
+package kubectlrmd
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keys of the RmdNodeState cache and workload maps
+const (
+	numWaysKey      = "Num Ways"
+	wayPrefixKey    = "Available "
+	waySuffixKey    = " Ways"
+	coreIDsKey      = "Core IDs"
+	cosNameKey      = "Cos Name"
+	cacheMaxKey     = "Cache Max"
+	cacheMinKey     = "Cache Min"
+	statusKey       = "Status"
+	successfulConst = "Successful"
+	guaranteedPool  = "guaranteed"
+	besteffortPool  = "besteffort"
+	sharedPool      = "shared"
+)
+
+// inventory holds the RDT state of the cluster as recorded in RmdNodeStates and RmdWorkloads
+type inventory struct {
+	nodes      map[string]*corev1.Node
+	nodeStates map[string]*intelv1alpha1.RmdNodeState
+	workloads  []intelv1alpha1.RmdWorkload
+}
+
+// getInventory reads all nodes and RmdNodeStates and the RmdWorkloads of namespace. All namespaces are
+// read if namespace is empty.
+func getInventory(c client.Client, namespace string) (*inventory, error) {
+	inv := &inventory{
+		nodes:      make(map[string]*corev1.Node),
+		nodeStates: make(map[string]*intelv1alpha1.RmdNodeState),
+	}
+	nodes := &corev1.NodeList{}
+	err := c.List(context.TODO(), nodes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		inv.nodes[nodes.Items[i].GetObjectMeta().GetName()] = &nodes.Items[i]
+	}
+
+	rmdNodeStates := &intelv1alpha1.RmdNodeStateList{}
+	err = c.List(context.TODO(), rmdNodeStates)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rmdNodeStates.Items {
+		inv.nodeStates[rmdNodeStates.Items[i].Spec.Node] = &rmdNodeStates.Items[i]
+	}
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err = c.List(context.TODO(), rmdWorkloads, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+	inv.workloads = rmdWorkloads.Items
+	return inv, nil
+}
+
+// rmdNodeNames returns the names of all nodes with an RmdNodeState, sorted
+func (inv *inventory) rmdNodeNames() []string {
+	nodeNames := make([]string, 0)
+	for nodeName := range inv.nodeStates {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// nodeCPUs returns the number of CPUs of nodeName, or 0 if unknown
+func (inv *inventory) nodeCPUs(nodeName string) int {
+	node, ok := inv.nodes[nodeName]
+	if !ok {
+		return 0
+	}
+	cpus := node.Status.Capacity[corev1.ResourceCPU]
+	return int(cpus.Value())
+}
+
+// usedCores returns the cores of all workloads configured on RMD as recorded in rmdNodeState
+func usedCores(rmdNodeState *intelv1alpha1.RmdNodeState) cpuset.CPUSet {
+	cores := cpuset.NewCPUSet()
+	for _, workloadMap := range rmdNodeState.Status.Workloads {
+		workloadCores, err := cpuset.Parse(workloadMap[coreIDsKey])
+		if err != nil {
+			continue
+		}
+		cores = cores.Union(workloadCores)
+	}
+	return cores
+}
+
+// cacheIDs returns the IDs of caches sorted numerically
+func cacheIDs(caches map[string]intelv1alpha1.CacheMap) []string {
+	ids := make([]string, 0)
+	for id := range caches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+	return ids
+}
+
+// cacheWays returns the number of ways of a cache and its free ways per pool
+func cacheWays(cacheMap intelv1alpha1.CacheMap) (int, map[string]cpuset.CPUSet) {
+	numWays, _ := strconv.Atoi(cacheMap[numWaysKey])
+	free := make(map[string]cpuset.CPUSet)
+	for key, value := range cacheMap {
+		if !strings.HasPrefix(key, wayPrefixKey) || !strings.HasSuffix(key, waySuffixKey) {
+			continue
+		}
+		ways, err := cpuset.Parse(value)
+		if err != nil {
+			continue
+		}
+		pool := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(key, wayPrefixKey), waySuffixKey))
+		free[pool] = ways
+	}
+	return numWays, free
+}
+
+// usedWays returns the ways of a cache not available in any pool
+func usedWays(numWays int, free map[string]cpuset.CPUSet) cpuset.CPUSet {
+	freeWays := cpuset.NewCPUSet()
+	for _, ways := range free {
+		freeWays = freeWays.Union(ways)
+	}
+	builder := cpuset.NewBuilder()
+	for way := 0; way < numWays; way++ {
+		if !freeWays.Contains(way) {
+			builder.Add(way)
+		}
+	}
+	return builder.Result()
+}
+
+// bitmap draws the members of set in [0, size) as '#' and all others as '.'
+func bitmap(set cpuset.CPUSet, size int) string {
+	var sb strings.Builder
+	for i := 0; i < size; i++ {
+		if set.Contains(i) {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+// setString returns set in cpuset format, or "-" if empty
+func setString(set cpuset.CPUSet) string {
+	if set.IsEmpty() {
+		return "-"
+	}
+	return set.String()
+}
+
+// valueOrDash returns value, or "-" if empty
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
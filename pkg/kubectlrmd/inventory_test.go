@@ -0,0 +1,134 @@
+package kubectlrmd
+
+import (
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// createInventoryClient returns a fake client with two nodes running RMD and two RmdWorkloads
+func createInventoryClient(t *testing.T) client.Client {
+	s := scheme.Scheme
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatalf("error adding scheme: (%v)", err)
+	}
+	objects := []runtime.Object{
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-node-1.com", Labels: map[string]string{"rdt": "true"}},
+			Status: corev1.NodeStatus{
+				Capacity: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-node-2.com"},
+		},
+		&intelv1alpha1.RmdNodeState{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-1.com", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-1.com"},
+			Status: intelv1alpha1.RmdNodeStateStatus{
+				Workloads: map[string]intelv1alpha1.WorkloadMap{
+					"rmd-workload-1": {"Core IDs": "0-1", "Cos Name": "0-1-guarantee", "Status": "Successful", "Cache Max": "2", "Cache Min": "2"},
+				},
+				L3Caches: map[string]intelv1alpha1.CacheMap{
+					"0": {"Num Ways": "11", "Available Guaranteed Ways": "2-9", "Available Shared Ways": "10"},
+				},
+				CdpSupported: true,
+			},
+		},
+		&intelv1alpha1.RmdNodeState{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-2.com", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-2.com"},
+		},
+		&intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-1", Namespace: "default"},
+			Spec: intelv1alpha1.RmdWorkloadSpec{
+				Nodes: []string{"example-node-1.com"},
+			},
+			Status: intelv1alpha1.RmdWorkloadStatus{
+				WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+					"example-node-1.com": {
+						CosName: "0-1-guarantee",
+						Status:  "Successful",
+						CoreIds: []string{"0-1"},
+						Rdt:     intelv1alpha1.Rdt{Cache: intelv1alpha1.Cache{Max: 2, Min: 2}},
+					},
+				},
+			},
+		},
+		&intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-workload-2", Namespace: "default"},
+			Spec: intelv1alpha1.RmdWorkloadSpec{
+				Nodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			},
+			Status: intelv1alpha1.RmdWorkloadStatus{
+				WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+					"example-node-1.com": {Status: "Pending"},
+				},
+				Conditions: []intelv1alpha1.RmdWorkloadCondition{
+					{
+						Type:    intelv1alpha1.InsufficientCapacity,
+						Status:  corev1.ConditionTrue,
+						Reason:  "NodesCannotFitWorkload",
+						Message: "example-node-1.com: requested 10 cache ways, 8 available on cache 0",
+					},
+				},
+			},
+		},
+	}
+	return fake.NewFakeClient(objects...)
+}
+
+func TestCacheWays(t *testing.T) {
+	tcases := []struct {
+		name            string
+		cacheMap        intelv1alpha1.CacheMap
+		expectedNumWays int
+		expectedUsed    string
+		expectedBitmap  string
+	}{
+		{
+			name:            "test case 1 - guaranteed and shared pools",
+			cacheMap:        intelv1alpha1.CacheMap{"Num Ways": "11", "Available Guaranteed Ways": "2-9", "Available Shared Ways": "10"},
+			expectedNumWays: 11,
+			expectedUsed:    "0-1",
+			expectedBitmap:  "##.........",
+		},
+		{
+			name:            "test case 2 - all ways used",
+			cacheMap:        intelv1alpha1.CacheMap{"Num Ways": "4", "Available Guaranteed Ways": ""},
+			expectedNumWays: 4,
+			expectedUsed:    "0-3",
+			expectedBitmap:  "####",
+		},
+		{
+			name:            "test case 3 - no inventory",
+			cacheMap:        intelv1alpha1.CacheMap{},
+			expectedNumWays: 0,
+			expectedUsed:    "",
+			expectedBitmap:  "",
+		},
+	}
+	for _, tc := range tcases {
+		numWays, free := cacheWays(tc.cacheMap)
+		used := usedWays(numWays, free)
+		if numWays != tc.expectedNumWays || used.String() != tc.expectedUsed || bitmap(used, numWays) != tc.expectedBitmap {
+			t.Errorf("%v failed: Expected %v, %v and %v, got %v, %v and %v", tc.name, tc.expectedNumWays, tc.expectedUsed,
+				tc.expectedBitmap, numWays, used.String(), bitmap(used, numWays))
+		}
+	}
+}
+
+func TestShiftSet(t *testing.T) {
+	shifted := shiftSet(cpuset.NewCPUSet(1, 32, 40), 32)
+	if !shifted.Equals(cpuset.NewCPUSet(0, 8)) {
+		t.Errorf("Expected 0,8, got %v", shifted)
+	}
+}
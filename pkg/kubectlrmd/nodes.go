@@ -0,0 +1,38 @@
+package kubectlrmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Nodes writes the l3 cache capacity and usage of each node running RMD as a table. One row is written
+// per l3 cache.
+func Nodes(c client.Client, out io.Writer) error {
+	inv, err := getInventory(c, "")
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCACHE\tWAYS\tUSED\tFREE GUARANTEED\tFREE BESTEFFORT\tFREE SHARED\tCORES USED\tWORKLOADS")
+	for _, nodeName := range inv.rmdNodeNames() {
+		rmdNodeState := inv.nodeStates[nodeName]
+		cores := fmt.Sprintf("%d", usedCores(rmdNodeState).Size())
+		if cpus := inv.nodeCPUs(nodeName); cpus > 0 {
+			cores = fmt.Sprintf("%s/%d", cores, cpus)
+		}
+		workloads := len(rmdNodeState.Status.Workloads)
+		if len(rmdNodeState.Status.L3Caches) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\t-\t%s\t%d\n", nodeName, cores, workloads)
+			continue
+		}
+		for _, id := range cacheIDs(rmdNodeState.Status.L3Caches) {
+			numWays, free := cacheWays(rmdNodeState.Status.L3Caches[id])
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%d\n", nodeName, id, numWays, usedWays(numWays, free).Size(),
+				free[guaranteedPool].Size(), free[besteffortPool].Size(), free[sharedPool].Size(), cores, workloads)
+		}
+	}
+	return w.Flush()
+}
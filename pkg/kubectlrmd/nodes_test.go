@@ -0,0 +1,21 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNodes(t *testing.T) {
+	expected := `NODE                CACHE  WAYS  USED  FREE GUARANTEED  FREE BESTEFFORT  FREE SHARED  CORES USED  WORKLOADS
+example-node-1.com  0      11    2     8                0                1            2/8         1
+example-node-2.com  -      -     -     -                -                -            0           0
+`
+	var out bytes.Buffer
+	err := Nodes(createInventoryClient(t), &out)
+	if err != nil {
+		t.Fatalf("Nodes returned error: (%v)", err)
+	}
+	if out.String() != expected {
+		t.Errorf("Expected:\n%v\ngot:\n%v", expected, out.String())
+	}
+}
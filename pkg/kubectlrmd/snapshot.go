@@ -0,0 +1,203 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/rmd"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	nodeKind         = "Node"
+	rmdConfigKind    = "RmdConfig"
+	rmdNodeStateKind = "RmdNodeState"
+	rmdWorkloadKind  = "RmdWorkload"
+	listKindSuffix   = "List"
+)
+
+// nodeSnapshot is the RDT inventory of a node read from an inventory snapshot
+type nodeSnapshot struct {
+	// rmd is true if the snapshot holds an RmdNodeState or RMD cache info of the node
+	rmd       bool
+	labels    map[string]string
+	cacheInfo rmdCache.Infos
+	// workloads are the workloads configured on RMD as recorded in the RmdNodeState
+	workloads map[string]intelv1alpha1.WorkloadMap
+}
+
+// Snapshot is the RDT inventory of a set of nodes, saved from a cluster to validate RmdWorkloads offline
+type Snapshot struct {
+	nodes map[string]*nodeSnapshot
+}
+
+// snapshotObject holds the fields used to identify the objects of a snapshot file
+type snapshotObject struct {
+	Kind   string            `json:"kind"`
+	Items  []json.RawMessage `json:"items"`
+	Caches json.RawMessage   `json:"Caches"`
+}
+
+// LoadSnapshot reads an inventory snapshot from files. Each file is YAML or JSON and contains Nodes
+// and RmdNodeStates (eg `kubectl get nodes,rmdnodestates -o yaml`), or the response of RMD's
+// /v1/cache/l3 endpoint. The node of an RMD response is given as "<node>=<file>".
+func LoadSnapshot(files []string) (*Snapshot, error) {
+	snapshot := &Snapshot{nodes: make(map[string]*nodeSnapshot)}
+	for _, file := range files {
+		nodeName := ""
+		if i := strings.Index(file, "="); i >= 0 {
+			nodeName, file = file[:i], file[i+1:]
+		}
+		objects, err := readObjects(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			err = snapshot.add(nodeName, object)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// node returns the snapshot of nodeName, adding it if not present
+func (s *Snapshot) node(nodeName string) *nodeSnapshot {
+	node, ok := s.nodes[nodeName]
+	if !ok {
+		node = &nodeSnapshot{}
+		s.nodes[nodeName] = node
+	}
+	return node
+}
+
+func (s *Snapshot) add(nodeName string, data []byte) error {
+	object := snapshotObject{}
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.HasSuffix(object.Kind, listKindSuffix):
+		for _, item := range object.Items {
+			err = s.add(nodeName, item)
+			if err != nil {
+				return err
+			}
+		}
+	case object.Kind == nodeKind:
+		node := &corev1.Node{}
+		err = json.Unmarshal(data, node)
+		if err != nil {
+			return err
+		}
+		s.node(node.GetObjectMeta().GetName()).labels = node.GetObjectMeta().GetLabels()
+	case object.Kind == rmdNodeStateKind:
+		rmdNodeState := &intelv1alpha1.RmdNodeState{}
+		err = json.Unmarshal(data, rmdNodeState)
+		if err != nil {
+			return err
+		}
+		node := s.node(rmdNodeState.Spec.Node)
+		node.rmd = true
+		node.cacheInfo = rmd.CacheInfoFromNodeStatus(rmdNodeState.Status.L3Caches)
+		node.workloads = rmdNodeState.Status.Workloads
+	case object.Kind == "" && len(object.Caches) != 0:
+		if nodeName == "" {
+			return fmt.Errorf("node of RMD cache info not set, use <node>=<file>")
+		}
+		allCacheInfo := rmdCache.Infos{}
+		err = json.Unmarshal(data, &allCacheInfo)
+		if err != nil {
+			return err
+		}
+		s.node(nodeName).rmd = true
+		s.node(nodeName).cacheInfo = allCacheInfo
+	default:
+		return fmt.Errorf("unsupported object kind %q", object.Kind)
+	}
+	return nil
+}
+
+// LoadRmdWorkloads reads the RmdWorkloads of files. Each file is YAML or JSON and may contain several
+// documents and lists of RmdWorkloads. RmdWorkloads without namespace are set to defaultNamespace.
+func LoadRmdWorkloads(files []string, defaultNamespace string) ([]*intelv1alpha1.RmdWorkload, error) {
+	rmdWorkloads := make([]*intelv1alpha1.RmdWorkload, 0)
+	var add func(file string, data []byte) error
+	add = func(file string, data []byte) error {
+		object := snapshotObject{}
+		err := json.Unmarshal(data, &object)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasSuffix(object.Kind, listKindSuffix):
+			for _, item := range object.Items {
+				err = add(file, item)
+				if err != nil {
+					return err
+				}
+			}
+		case object.Kind == rmdWorkloadKind:
+			rmdWorkload := &intelv1alpha1.RmdWorkload{}
+			err = json.Unmarshal(data, rmdWorkload)
+			if err != nil {
+				return err
+			}
+			if rmdWorkload.GetObjectMeta().GetNamespace() == "" {
+				rmdWorkload.SetNamespace(defaultNamespace)
+			}
+			rmdWorkloads = append(rmdWorkloads, rmdWorkload)
+		default:
+			return fmt.Errorf("%s: unsupported object kind %q", file, object.Kind)
+		}
+		return nil
+	}
+	for _, file := range files {
+		objects, err := readObjects(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			err = add(file, object)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rmdWorkloads, nil
+}
+
+// readObjects returns the JSON of each YAML or JSON document in file
+func readObjects(file string) ([]json.RawMessage, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]json.RawMessage, 0)
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		object := json.RawMessage{}
+		err = decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if len(object) == 0 || string(object) == "null" {
+			// Empty YAML document
+			continue
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
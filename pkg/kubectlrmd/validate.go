@@ -0,0 +1,321 @@
+package kubectlrmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/rmd"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/yaml"
+)
+
+// Results of validating an RmdWorkload on a node. Pending and Conflict match the states the
+// RmdWorkload controller records for the node.
+const (
+	ValidResult        = "Valid"
+	PendingResult      = "Pending"
+	ConflictResult     = "Conflict"
+	NodeNotFoundResult = "NodeNotFound"
+)
+
+// ValidationResult is the result of validating an RmdWorkload against a Snapshot
+type ValidationResult struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Valid is true if the RmdWorkload can be applied on all targeted nodes
+	Valid bool                   `json:"valid"`
+	Error string                 `json:"error,omitempty"`
+	Nodes []NodeValidationResult `json:"nodes,omitempty"`
+}
+
+// NodeValidationResult is the result of validating an RmdWorkload on a single node. The cores and
+// cache ways are those the RmdWorkload would be applied with.
+type NodeValidationResult struct {
+	Node     string   `json:"node"`
+	Result   string   `json:"result"`
+	Reason   string   `json:"reason,omitempty"`
+	CoreIds  []string `json:"coreIds,omitempty"`
+	CacheMax int      `json:"cacheMax,omitempty"`
+	CacheMin int      `json:"cacheMin,omitempty"`
+}
+
+// Validate runs the cache size resolution, core selection, core conflict and capacity checks of the
+// RmdWorkload controller for each of rmdWorkloads on each of its targeted nodes in snapshot. The
+// workloads are validated in order and snapshot is updated with the cores and cache ways of each valid
+// workload, as if it was applied, before the next one is validated. MBA is not checked as the snapshot
+// does not hold MBA info.
+func Validate(rmdWorkloads []*intelv1alpha1.RmdWorkload, snapshot *Snapshot) []ValidationResult {
+	results := make([]ValidationResult, 0)
+	for _, rmdWorkload := range rmdWorkloads {
+		result := ValidationResult{
+			Namespace: rmdWorkload.GetObjectMeta().GetNamespace(),
+			Name:      rmdWorkload.GetObjectMeta().GetName(),
+			Valid:     true,
+		}
+		nodeNames := snapshot.targetedNodes(rmdWorkload)
+		if len(nodeNames) == 0 {
+			result.Valid = false
+			result.Error = "no node in snapshot targeted by spec.nodes or spec.nodeSelector"
+			results = append(results, result)
+			continue
+		}
+		for _, nodeName := range nodeNames {
+			nodeResult := snapshot.validateNode(rmdWorkload, nodeName)
+			if nodeResult.Result != ValidResult {
+				result.Valid = false
+			}
+			result.Nodes = append(result.Nodes, nodeResult)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// targetedNodes returns the sorted names of the nodes targeted by rmdWorkload. spec.nodeSelector
+// takes precedence over spec.nodes and is matched against the labels of the Nodes in the snapshot.
+// As in the controller, spec.maxNodes limits the matching nodes to those with the most free
+// guaranteed l3 cache ways.
+func (s *Snapshot) targetedNodes(rmdWorkload *intelv1alpha1.RmdWorkload) []string {
+	nodeNames := make([]string, 0)
+	if len(rmdWorkload.Spec.NodeSelector) == 0 {
+		nodeNames = append(nodeNames, rmdWorkload.Spec.Nodes...)
+		sort.Strings(nodeNames)
+		return nodeNames
+	}
+	selector := labels.SelectorFromSet(labels.Set(rmdWorkload.Spec.NodeSelector))
+	for nodeName, node := range s.nodes {
+		if node.rmd && selector.Matches(labels.Set(node.labels)) {
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+	maxNodes := rmdWorkload.Spec.MaxNodes
+	if maxNodes != nil && len(nodeNames) > *maxNodes {
+		sort.Slice(nodeNames, func(i, j int) bool {
+			iWays, jWays := rmd.FreeGuaranteedWays(s.nodes[nodeNames[i]].cacheInfo), rmd.FreeGuaranteedWays(s.nodes[nodeNames[j]].cacheInfo)
+			if iWays != jWays {
+				return iWays > jWays
+			}
+			return nodeNames[i] < nodeNames[j]
+		})
+		nodeNames = nodeNames[:*maxNodes]
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// validateNode validates rmdWorkload on nodeName and, if valid, reserves its cores and cache ways on the node
+func (s *Snapshot) validateNode(rmdWorkload *intelv1alpha1.RmdWorkload, nodeName string) NodeValidationResult {
+	result := NodeValidationResult{Node: nodeName}
+	node, ok := s.nodes[nodeName]
+	if !ok || !node.rmd {
+		result.Result = NodeNotFoundResult
+		result.Reason = "no RmdNodeState or RMD cache info of node in snapshot"
+		return result
+	}
+	pending := func(err error) NodeValidationResult {
+		result.Result = PendingResult
+		result.Reason = err.Error()
+		return result
+	}
+	cacheInfoErr := fmt.Errorf("cache info of node not in snapshot")
+	hasCacheInfo := len(node.cacheInfo.Caches) != 0
+
+	nodeWorkload := rmd.ApplyOverrides(rmdWorkload, nodeName, node.labels).DeepCopy()
+	name := nodeWorkload.GetObjectMeta().GetName()
+	cache := &nodeWorkload.Spec.Rdt.Cache
+	if rmd.CacheRequestedInBytes(*cache) {
+		if !hasCacheInfo {
+			return pending(cacheInfoErr)
+		}
+		maxWays, minWays, err := rmd.ResolveCacheWays(*cache, node.cacheInfo)
+		if err != nil {
+			return pending(err)
+		}
+		cache.Max, cache.Min, cache.MaxBytes, cache.MinBytes = maxWays, minWays, nil, nil
+	}
+
+	// Cores of workloads on RMD other than rmdWorkload itself are owned by those workloads.
+	coreOwnership := make(map[string][]string)
+	for workloadName, workloadMap := range node.workloads {
+		if workloadName != name && workloadMap[coreIDsKey] != "" {
+			coreOwnership[workloadName] = strings.Split(workloadMap[coreIDsKey], ",")
+		}
+	}
+
+	if nodeWorkload.Spec.CoreCount != 0 {
+		if !hasCacheInfo {
+			return pending(cacheInfoErr)
+		}
+		unavailableCores := cpuset.NewCPUSet()
+		for _, coreIDs := range coreOwnership {
+			ownedCPUSet, err := cpuset.Parse(strings.Join(coreIDs, ","))
+			if err != nil {
+				continue
+			}
+			unavailableCores = unavailableCores.Union(ownedCPUSet)
+		}
+		coreIDs, err := rmd.SelectCores(nodeWorkload, node.cacheInfo, unavailableCores)
+		if err != nil {
+			return pending(err)
+		}
+		nodeWorkload.Spec.CoreIds = coreIDs
+	}
+	if nodeWorkload.Spec.AllCores && !hasCacheInfo {
+		return pending(cacheInfoErr)
+	}
+	coreIDs, err := rmd.ResolveCoreIDs(nodeWorkload, node.cacheInfo)
+	if err != nil {
+		return pending(err)
+	}
+	result.CoreIds = coreIDs
+	result.CacheMax = cache.Max
+	result.CacheMin = cache.Min
+
+	conflicts, err := rmd.FindCoreConflicts(coreIDs, coreOwnership)
+	if err != nil {
+		return pending(err)
+	}
+	if len(conflicts) != 0 {
+		owners := make([]string, 0)
+		for owner := range conflicts {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+		reports := make([]string, 0)
+		for _, owner := range owners {
+			reports = append(reports, fmt.Sprintf("cores %s owned by %s", conflicts[owner], owner))
+		}
+		result.Result = ConflictResult
+		result.Reason = strings.Join(reports, ", ")
+		return result
+	}
+
+	if !hasCacheInfo {
+		node.reserve(nodeWorkload, coreIDs)
+		result.Result = ValidResult
+		result.Reason = "cache info of node not in snapshot, capacity not checked"
+		return result
+	}
+	err = rmd.CheckCacheCapacity(nodeWorkload, coreIDs, node.cacheInfo, existingWorkload(node.workloads[name]))
+	if err != nil {
+		return pending(err)
+	}
+	node.reserve(nodeWorkload, coreIDs)
+	result.Result = ValidResult
+	return result
+}
+
+// reserve records workloadCR on coreIDs as a workload on RMD and removes the l3 cache ways it is
+// allocated from the free ways of the node. Ways of a workload of the same name on RMD are reused.
+func (node *nodeSnapshot) reserve(workloadCR *intelv1alpha1.RmdWorkload, coreIDs []string) {
+	name := workloadCR.GetObjectMeta().GetName()
+	cache := workloadCR.Spec.Rdt.Cache
+	pool, ways := "", 0
+	if workloadCR.Spec.Policy == "" {
+		pool, ways = wayPool(cache.Max, cache.Min)
+	}
+	if existing := node.workloads[name]; existing != nil {
+		existingMax, _ := strconv.Atoi(existing[cacheMaxKey])
+		existingMin, _ := strconv.Atoi(existing[cacheMinKey])
+		if existingPool, existingWays := wayPool(existingMax, existingMin); existingPool == pool {
+			ways -= existingWays
+		}
+	}
+
+	if node.workloads == nil {
+		node.workloads = make(map[string]intelv1alpha1.WorkloadMap)
+	}
+	node.workloads[name] = intelv1alpha1.WorkloadMap{
+		coreIDsKey:  strings.Join(coreIDs, ","),
+		cacheMaxKey: strconv.Itoa(cache.Max),
+		cacheMinKey: strconv.Itoa(cache.Min),
+	}
+	if pool == "" || ways <= 0 {
+		return
+	}
+
+	coreCPUSet, err := cpuset.Parse(strings.Join(coreIDs, ","))
+	if err != nil {
+		return
+	}
+	for cacheID, cacheInfo := range node.cacheInfo.Caches {
+		shareCPUSet, err := cpuset.Parse(cacheInfo.ShareCPUList)
+		if err != nil || shareCPUSet.Intersection(coreCPUSet).IsEmpty() {
+			continue
+		}
+		freeWays, err := cpuset.Parse(cacheInfo.AvailableWaysPool[pool])
+		if err != nil {
+			continue
+		}
+		free := freeWays.ToSlice()
+		if len(free) > ways {
+			free = free[ways:]
+		} else {
+			free = []int{}
+		}
+		cacheInfo.AvailableWaysPool[pool] = cpuset.NewCPUSet(free...).String()
+		node.cacheInfo.Caches[cacheID] = cacheInfo
+	}
+}
+
+// wayPool returns the RMD pool l3 cache ways of a max/min cache request are taken from and their
+// number, or "" if ways are not reserved for the request.
+func wayPool(maxWays, minWays int) (string, int) {
+	switch {
+	case maxWays == 0:
+		return "", 0
+	case maxWays == minWays:
+		return guaranteedPool, maxWays
+	case maxWays > minWays && minWays != 0:
+		return besteffortPool, minWays
+	}
+	return "", 0
+}
+
+// existingWorkload returns the cores and cache ways of a workload configured on RMD as recorded in
+// the RmdNodeState, or nil if not configured.
+func existingWorkload(workloadMap intelv1alpha1.WorkloadMap) *rmdtypes.RDTWorkLoad {
+	if workloadMap == nil {
+		return nil
+	}
+	workload := &rmdtypes.RDTWorkLoad{}
+	if workloadMap[coreIDsKey] != "" {
+		workload.CoreIDs = strings.Split(workloadMap[coreIDsKey], ",")
+	}
+	if maxWays, err := strconv.Atoi(workloadMap[cacheMaxKey]); err == nil {
+		max := uint32(maxWays)
+		workload.Rdt.Cache.Max = &max
+	}
+	if minWays, err := strconv.Atoi(workloadMap[cacheMinKey]); err == nil {
+		min := uint32(minWays)
+		workload.Rdt.Cache.Min = &min
+	}
+	return workload
+}
+
+// WriteValidationResults writes results to out in format "json" or "yaml"
+func WriteValidationResults(results []ValidationResult, format string, out io.Writer) error {
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(results, "", "  ")
+		data = append(data, '\n')
+	case "yaml":
+		data, err = yaml.Marshal(results)
+	default:
+		return fmt.Errorf("unsupported output format %q, use json or yaml", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
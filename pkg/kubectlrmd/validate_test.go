@@ -0,0 +1,373 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const snapshotYAML = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: example-node-1.com
+    labels:
+      rdt: "true"
+      tier: gold
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: example-node-2.com
+    labels:
+      tier: gold
+- apiVersion: v1
+  kind: Node
+  metadata:
+    name: example-node-3.com
+    labels:
+      rdt: "true"
+- apiVersion: intel.com/v1alpha1
+  kind: RmdNodeState
+  metadata:
+    name: rmd-node-state-example-node-1.com
+    namespace: default
+  spec:
+    node: example-node-1.com
+  status:
+    workloads:
+      rmd-workload-existing:
+        Core IDs: "0-1"
+        Cache Max: "2"
+        Cache Min: "2"
+        Status: Successful
+    l3Caches:
+      "0":
+        Num Ways: "11"
+        Way Size: "1048576"
+        Share CPU List: "0-7"
+        Available Guaranteed Ways: "2-9"
+        Available Shared Ways: "10"
+`
+
+const l3CacheJSON = `{
+  "number": 1,
+  "Caches": {
+    "0": {
+      "cache_id": 0,
+      "NumWays": 11,
+      "WaySize": 1048576,
+      "share_cpu_list": "0-3",
+      "available_ways_pool": {"guaranteed": "1-9", "shared": "10"}
+    }
+  }
+}
+`
+
+const rmdWorkloadsYAML = `apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-valid
+spec:
+  nodes: ["example-node-1.com"]
+  coreIds: ["2-3"]
+  rdt:
+    cache:
+      max: 4
+      min: 4
+---
+apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-conflict
+spec:
+  nodes: ["example-node-1.com"]
+  coreIds: ["1", "3"]
+  rdt:
+    cache:
+      max: 1
+      min: 1
+---
+apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-capacity
+spec:
+  nodes: ["example-node-1.com"]
+  coreIds: ["4-5"]
+  rdt:
+    cache:
+      max: 5
+      min: 5
+---
+apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-core-count
+  namespace: rmd
+spec:
+  nodeSelector:
+    rdt: "true"
+  coreCount: 2
+  rdt:
+    cache:
+      maxBytes: 2097152
+      minBytes: 2097152
+---
+apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-missing-node
+spec:
+  nodes: ["example-node-2.com", "example-node-4.com"]
+  coreIds: ["0"]
+---
+apiVersion: intel.com/v1alpha1
+kind: RmdWorkload
+metadata:
+  name: rmd-workload-max-nodes
+spec:
+  nodeSelector:
+    tier: gold
+  maxNodes: 1
+  coreIds: ["2"]
+  rdt:
+    cache:
+      max: 2
+      min: 2
+`
+
+// writeValidateFiles writes the snapshot and RmdWorkload fixtures to a temporary directory and
+// returns the RmdWorkload files, the inventory files and the directory
+func writeValidateFiles(t *testing.T) ([]string, []string, string) {
+	dir, err := ioutil.TempDir("", "kubectl-rmd-validate")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: (%v)", err)
+	}
+	files := map[string]string{
+		"snapshot.yaml":     snapshotYAML,
+		"l3.json":           l3CacheJSON,
+		"rmdworkloads.yaml": rmdWorkloadsYAML,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %v: (%v)", name, err)
+		}
+	}
+	return []string{filepath.Join(dir, "rmdworkloads.yaml")},
+		[]string{filepath.Join(dir, "snapshot.yaml"), "example-node-2.com=" + filepath.Join(dir, "l3.json")},
+		dir
+}
+
+func TestValidate(t *testing.T) {
+	rmdWorkloadFiles, inventoryFiles, dir := writeValidateFiles(t)
+	defer os.RemoveAll(dir)
+
+	rmdWorkloads, err := LoadRmdWorkloads(rmdWorkloadFiles, "default")
+	if err != nil {
+		t.Fatalf("error loading RmdWorkloads: (%v)", err)
+	}
+	snapshot, err := LoadSnapshot(inventoryFiles)
+	if err != nil {
+		t.Fatalf("error loading snapshot: (%v)", err)
+	}
+	results := Validate(rmdWorkloads, snapshot)
+
+	expected := []ValidationResult{
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-valid",
+			Valid:     true,
+			Nodes: []NodeValidationResult{
+				{Node: "example-node-1.com", Result: ValidResult, CoreIds: []string{"2-3"}, CacheMax: 4, CacheMin: 4},
+			},
+		},
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-conflict",
+			Nodes: []NodeValidationResult{
+				{
+					Node:     "example-node-1.com",
+					Result:   ConflictResult,
+					Reason:   "cores 1 owned by rmd-workload-existing, cores 3 owned by rmd-workload-valid",
+					CoreIds:  []string{"1", "3"},
+					CacheMax: 1,
+					CacheMin: 1,
+				},
+			},
+		},
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-capacity",
+			Nodes: []NodeValidationResult{
+				{
+					Node:     "example-node-1.com",
+					Result:   PendingResult,
+					Reason:   "insufficient cache capacity: cache 0: requested 5 guaranteed ways, 4 available",
+					CoreIds:  []string{"4-5"},
+					CacheMax: 5,
+					CacheMin: 5,
+				},
+			},
+		},
+		{
+			Namespace: "rmd",
+			Name:      "rmd-workload-core-count",
+			Valid:     true,
+			Nodes: []NodeValidationResult{
+				{Node: "example-node-1.com", Result: ValidResult, CoreIds: []string{"4-5"}, CacheMax: 2, CacheMin: 2},
+			},
+		},
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-missing-node",
+			Nodes: []NodeValidationResult{
+				{Node: "example-node-2.com", Result: ValidResult, CoreIds: []string{"0"}},
+				{Node: "example-node-4.com", Result: NodeNotFoundResult, Reason: "no RmdNodeState or RMD cache info of node in snapshot"},
+			},
+		},
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-max-nodes",
+			Valid:     true,
+			Nodes: []NodeValidationResult{
+				{Node: "example-node-2.com", Result: ValidResult, CoreIds: []string{"2"}, CacheMax: 2, CacheMin: 2},
+			},
+		},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %v results, got %v", len(expected), results)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(results[i], expected[i]) {
+			t.Errorf("%v failed: Expected %+v, got %+v", expected[i].Name, expected[i], results[i])
+		}
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	_, inventoryFiles, dir := writeValidateFiles(t)
+	defer os.RemoveAll(dir)
+
+	tcases := []struct {
+		name          string
+		files         []string
+		expectedNodes map[string]bool
+		expectedError bool
+	}{
+		{
+			name:          "test case 1 - nodes and rmdnodestates",
+			files:         inventoryFiles[:1],
+			expectedNodes: map[string]bool{"example-node-1.com": true, "example-node-2.com": false, "example-node-3.com": false},
+		},
+		{
+			name:          "test case 2 - rmd cache info",
+			files:         inventoryFiles[1:],
+			expectedNodes: map[string]bool{"example-node-2.com": true},
+		},
+		{
+			name:          "test case 3 - rmd cache info without node",
+			files:         []string{filepath.Join(dir, "l3.json")},
+			expectedError: true,
+		},
+		{
+			name:          "test case 4 - unsupported kind",
+			files:         []string{filepath.Join(dir, "rmdworkloads.yaml")},
+			expectedError: true,
+		},
+		{
+			name:          "test case 5 - missing file",
+			files:         []string{filepath.Join(dir, "missing.yaml")},
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		snapshot, err := LoadSnapshot(tc.files)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		nodes := make(map[string]bool)
+		for nodeName, node := range snapshot.nodes {
+			nodes[nodeName] = node.rmd
+		}
+		if !reflect.DeepEqual(nodes, tc.expectedNodes) {
+			t.Errorf("%v failed: Expected nodes %v, got %v", tc.name, tc.expectedNodes, nodes)
+		}
+	}
+}
+
+func TestWriteValidationResults(t *testing.T) {
+	results := []ValidationResult{
+		{
+			Namespace: "default",
+			Name:      "rmd-workload-1",
+			Valid:     true,
+			Nodes:     []NodeValidationResult{{Node: "example-node-1.com", Result: ValidResult, CoreIds: []string{"0-1"}}},
+		},
+	}
+	tcases := []struct {
+		name          string
+		format        string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:   "test case 1 - json",
+			format: "json",
+			expected: `[
+  {
+    "namespace": "default",
+    "name": "rmd-workload-1",
+    "valid": true,
+    "nodes": [
+      {
+        "node": "example-node-1.com",
+        "result": "Valid",
+        "coreIds": [
+          "0-1"
+        ]
+      }
+    ]
+  }
+]
+`,
+		},
+		{
+			name:   "test case 2 - yaml",
+			format: "yaml",
+			expected: `- name: rmd-workload-1
+  namespace: default
+  nodes:
+  - coreIds:
+    - 0-1
+    node: example-node-1.com
+    result: Valid
+  valid: true
+`,
+		},
+		{
+			name:          "test case 3 - unsupported format",
+			format:        "wide",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		var out bytes.Buffer
+		err := WriteValidationResults(results, tc.format, &out)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+			continue
+		}
+		if err == nil && out.String() != tc.expected {
+			t.Errorf("%v failed: Expected:\n%v\ngot:\n%v", tc.name, tc.expected, out.String())
+		}
+	}
+}
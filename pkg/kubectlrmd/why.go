@@ -0,0 +1,117 @@
+package kubectlrmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Why explains why the RmdWorkload name of namespace is not applied on its targeted nodes. It reports
+// the nodes targeted by the workload, the state of the workload on each node and the workload's
+// failure conditions.
+func Why(c client.Client, namespace, name string, out io.Writer) error {
+	rmdWorkload := &intelv1alpha1.RmdWorkload{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, rmdWorkload)
+	if err != nil {
+		return err
+	}
+	inv, err := getInventory(c, namespace)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "RmdWorkload %s/%s\n", namespace, name)
+	nodeNames := targetedNodes(rmdWorkload, inv)
+	if len(nodeNames) == 0 {
+		if len(rmdWorkload.Spec.NodeSelector) == 0 {
+			fmt.Fprintln(out, "Not applied: spec.nodes and spec.nodeSelector are empty, no node is targeted.")
+		} else {
+			fmt.Fprintf(out, "Not applied: no node matches spec.nodeSelector %v.\n", labels.Set(rmdWorkload.Spec.NodeSelector))
+		}
+		return nil
+	}
+
+	applied := 0
+	for _, nodeName := range nodeNames {
+		reason := nodeReason(rmdWorkload, inv, nodeName)
+		if reason == "" {
+			applied++
+			workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+			fmt.Fprintf(out, "  %s: applied (cos %s, cores %s)\n", nodeName, workloadState.CosName, strings.Join(workloadState.CoreIds, ","))
+			continue
+		}
+		fmt.Fprintf(out, "  %s: not applied: %s\n", nodeName, reason)
+	}
+	if applied == len(nodeNames) {
+		fmt.Fprintln(out, "Applied on all targeted nodes.")
+	}
+
+	for _, condition := range rmdWorkload.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		fmt.Fprintf(out, "Condition %s (%s): %s\n", condition.Type, condition.Reason, condition.Message)
+	}
+	return nil
+}
+
+// targetedNodes returns the sorted names of the nodes targeted by rmdWorkload. As in the RmdWorkload
+// controller, spec.nodeSelector takes precedence over spec.nodes.
+func targetedNodes(rmdWorkload *intelv1alpha1.RmdWorkload, inv *inventory) []string {
+	nodeNames := make([]string, 0)
+	if len(rmdWorkload.Spec.NodeSelector) == 0 {
+		nodeNames = append(nodeNames, rmdWorkload.Spec.Nodes...)
+	} else {
+		selector := labels.SelectorFromSet(labels.Set(rmdWorkload.Spec.NodeSelector))
+		for nodeName, node := range inv.nodes {
+			if selector.Matches(labels.Set(node.GetObjectMeta().GetLabels())) {
+				nodeNames = append(nodeNames, nodeName)
+			}
+		}
+	}
+	sort.Strings(nodeNames)
+	return nodeNames
+}
+
+// nodeReason returns why rmdWorkload is not applied on nodeName, or an empty string if it is applied
+func nodeReason(rmdWorkload *intelv1alpha1.RmdWorkload, inv *inventory, nodeName string) string {
+	if _, ok := inv.nodes[nodeName]; !ok {
+		return "node does not exist"
+	}
+	rmdNodeState, ok := inv.nodeStates[nodeName]
+	if !ok {
+		return "no RmdNodeState for node, RMD is not running on the node"
+	}
+	workloadState, ok := rmdWorkload.Status.WorkloadStates[nodeName]
+	if !ok {
+		return "not yet reconciled by the operator"
+	}
+	if workloadState.Status == successfulConst {
+		return ""
+	}
+
+	reasons := make([]string, 0)
+	if workloadState.Status != "" {
+		reasons = append(reasons, fmt.Sprintf("status %s", workloadState.Status))
+	}
+	if workloadState.Response != "" {
+		reasons = append(reasons, fmt.Sprintf("response %q", workloadState.Response))
+	}
+	// Report the free guaranteed ways to explain capacity failures.
+	for _, id := range cacheIDs(rmdNodeState.Status.L3Caches) {
+		_, free := cacheWays(rmdNodeState.Status.L3Caches[id])
+		reasons = append(reasons, fmt.Sprintf("l3 cache %s has %d free guaranteed ways", id, free[guaranteedPool].Size()))
+	}
+	if len(reasons) == 0 {
+		return "no status reported"
+	}
+	return strings.Join(reasons, "; ")
+}
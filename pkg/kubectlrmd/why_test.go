@@ -0,0 +1,50 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWhy(t *testing.T) {
+	tcases := []struct {
+		name          string
+		workloadName  string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name:         "test case 1 - workload applied",
+			workloadName: "rmd-workload-1",
+			expected: `RmdWorkload default/rmd-workload-1
+  example-node-1.com: applied (cos 0-1-guarantee, cores 0-1)
+Applied on all targeted nodes.
+`,
+		},
+		{
+			name:         "test case 2 - workload pending, not reconciled and targeting missing node",
+			workloadName: "rmd-workload-2",
+			expected: `RmdWorkload default/rmd-workload-2
+  example-node-1.com: not applied: status Pending; l3 cache 0 has 8 free guaranteed ways
+  example-node-2.com: not applied: not yet reconciled by the operator
+  example-node-3.com: not applied: node does not exist
+Condition InsufficientCapacity (NodesCannotFitWorkload): example-node-1.com: requested 10 cache ways, 8 available on cache 0
+`,
+		},
+		{
+			name:          "test case 3 - workload does not exist",
+			workloadName:  "rmd-workload-3",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		var out bytes.Buffer
+		err := Why(createInventoryClient(t), "default", tc.workloadName, &out)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+			continue
+		}
+		if err == nil && out.String() != tc.expected {
+			t.Errorf("%v failed: Expected:\n%v\ngot:\n%v", tc.name, tc.expected, out.String())
+		}
+	}
+}
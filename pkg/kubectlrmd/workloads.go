@@ -0,0 +1,45 @@
+package kubectlrmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Workloads writes the state of each RmdWorkload of namespace on each of its nodes as a table. All
+// namespaces are read if namespace is empty.
+func Workloads(c client.Client, namespace string, out io.Writer) error {
+	inv, err := getInventory(c, namespace)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tNODE\tSTATUS\tCOS\tCORES\tCACHE MAX/MIN")
+	for _, rmdWorkload := range inv.workloads {
+		namespace := rmdWorkload.GetObjectMeta().GetNamespace()
+		name := rmdWorkload.GetObjectMeta().GetName()
+		if len(rmdWorkload.Status.WorkloadStates) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t<none>\t-\t-\t-\t-\n", namespace, name)
+			continue
+		}
+		nodeNames := make([]string, 0)
+		for nodeName := range rmdWorkload.Status.WorkloadStates {
+			nodeNames = append(nodeNames, nodeName)
+		}
+		sort.Strings(nodeNames)
+		for _, nodeName := range nodeNames {
+			workloadState := rmdWorkload.Status.WorkloadStates[nodeName]
+			status := workloadState.Status
+			if status == "" {
+				status = "Unknown"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\n", namespace, name, nodeName, status, valueOrDash(workloadState.CosName),
+				valueOrDash(strings.Join(workloadState.CoreIds, ",")), workloadState.Rdt.Cache.Max, workloadState.Rdt.Cache.Min)
+		}
+	}
+	return w.Flush()
+}
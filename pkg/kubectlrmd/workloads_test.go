@@ -0,0 +1,39 @@
+package kubectlrmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWorkloads(t *testing.T) {
+	tcases := []struct {
+		name      string
+		namespace string
+		expected  string
+	}{
+		{
+			name:      "test case 1 - all namespaces",
+			namespace: "",
+			expected: `NAMESPACE  NAME            NODE                STATUS      COS            CORES  CACHE MAX/MIN
+default    rmd-workload-1  example-node-1.com  Successful  0-1-guarantee  0-1    2/2
+default    rmd-workload-2  example-node-1.com  Pending     -              -      0/0
+`,
+		},
+		{
+			name:      "test case 2 - namespace without workloads",
+			namespace: "other",
+			expected: `NAMESPACE  NAME  NODE  STATUS  COS  CORES  CACHE MAX/MIN
+`,
+		},
+	}
+	for _, tc := range tcases {
+		var out bytes.Buffer
+		err := Workloads(createInventoryClient(t), tc.namespace, &out)
+		if err != nil {
+			t.Fatalf("%v failed: Workloads returned error: (%v)", tc.name, err)
+		}
+		if out.String() != tc.expected {
+			t.Errorf("%v failed: Expected:\n%v\ngot:\n%v", tc.name, tc.expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package metrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	namespace = "rmd_operator"
+
+	// Workload states reported by rmd_operator_workloads
+	stateApplied  = "applied"
+	statePending  = "pending"
+	stateConflict = "conflict"
+	stateFailed   = "failed"
+)
+
+var (
+	rmdRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rmd_requests_total",
+			Help:      "Total number of requests sent to RMD per node, verb and response code.",
+		},
+		[]string{"node", "verb", "code"},
+	)
+	rmdRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rmd_request_errors_total",
+			Help:      "Total number of requests to RMD that failed or returned an error status per node and verb.",
+		},
+		[]string{"node", "verb"},
+	)
+	rmdRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rmd_request_duration_seconds",
+			Help:      "Latency of requests sent to RMD per node and verb.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"node", "verb"},
+	)
+	nodeRmdUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_rmd_up",
+			Help:      "Whether the RMD instance of a node responded to the last request of the RmdNodeState controller.",
+		},
+		[]string{"node"},
+	)
+	nodeFreeCacheWays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_free_cache_ways",
+			Help:      "Number of free l3 cache ways per node, cache ID and pool as reported by RMD.",
+		},
+		[]string{"node", "cache_id", "pool"},
+	)
+	nodeCosUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_cos_used",
+			Help:      "Number of classes of service used by RMD workloads per node.",
+		},
+		[]string{"node"},
+	)
+	nodeWorkloads = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "node_workloads",
+			Help:      "Number of workloads configured on RMD per node.",
+		},
+		[]string{"node"},
+	)
+	workloadsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "workloads"),
+		"Number of RmdWorkloads by state, counted once per targeted node.",
+		[]string{"state"}, nil,
+	)
+)
+
+// rmdNodes maps the host of each RMD instance to the name of its node
+var rmdNodes sync.Map
+
+// workloadStore records the state of each RmdWorkload per node for rmd_operator_workloads
+var workloadStore = &workloadCollector{states: make(map[string]map[string]string)}
+
+// cacheWaySeries records the cache ID and pool labels of rmd_operator_node_free_cache_ways per node,
+// so that series of caches or pools no longer reported are removed
+var cacheWaySeries = struct {
+	sync.Mutex
+	labels map[string]map[[2]string]bool
+}{labels: make(map[string]map[[2]string]bool)}
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		rmdRequests,
+		rmdRequestErrors,
+		rmdRequestDuration,
+		nodeRmdUp,
+		nodeFreeCacheWays,
+		nodeCosUsed,
+		nodeWorkloads,
+		workloadStore,
+	)
+}
+
+// SetRmdNodeAddress records nodeName as the node of the RMD instance served at address, so that
+// requests to the instance are labelled with its node.
+func SetRmdNodeAddress(nodeName, address string) {
+	rmdURL, err := url.Parse(address)
+	if err != nil || rmdURL.Host == "" {
+		return
+	}
+	rmdNodes.Store(rmdURL.Host, nodeName)
+}
+
+func rmdNode(host string) string {
+	if nodeName, ok := rmdNodes.Load(host); ok {
+		return nodeName.(string)
+	}
+	return host
+}
+
+// InstrumentedTransport records the count, errors and latency of requests sent to RMD
+type InstrumentedTransport struct {
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *InstrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	node := rmdNode(req.URL.Host)
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	rmdRequestDuration.WithLabelValues(node, req.Method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		rmdRequests.WithLabelValues(node, req.Method, "error").Inc()
+		rmdRequestErrors.WithLabelValues(node, req.Method).Inc()
+		return resp, err
+	}
+	rmdRequests.WithLabelValues(node, req.Method, strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode >= http.StatusBadRequest {
+		rmdRequestErrors.WithLabelValues(node, req.Method).Inc()
+	}
+	return resp, nil
+}
+
+// SetNodeInventory records the free cache ways, COS usage and workload count reported by the RMD
+// instance of nodeName.
+func SetNodeInventory(nodeName string, allCacheInfo rmdCache.Infos, workloads []*rmdtypes.RDTWorkLoad) {
+	nodeRmdUp.WithLabelValues(nodeName).Set(1)
+	cosNames := make(map[string]bool)
+	for _, workload := range workloads {
+		if workload.CosName != "" {
+			cosNames[workload.CosName] = true
+		}
+	}
+	nodeCosUsed.WithLabelValues(nodeName).Set(float64(len(cosNames)))
+	nodeWorkloads.WithLabelValues(nodeName).Set(float64(len(workloads)))
+
+	cacheWaySeries.Lock()
+	defer cacheWaySeries.Unlock()
+	series := make(map[[2]string]bool)
+	for cacheID, cache := range allCacheInfo.Caches {
+		for pool, ways := range cache.AvailableWaysPool {
+			waySet, err := cpuset.Parse(ways)
+			if err != nil {
+				continue
+			}
+			labels := [2]string{strconv.Itoa(int(cacheID)), pool}
+			nodeFreeCacheWays.WithLabelValues(nodeName, labels[0], labels[1]).Set(float64(waySet.Size()))
+			series[labels] = true
+		}
+	}
+	for labels := range cacheWaySeries.labels[nodeName] {
+		if !series[labels] {
+			nodeFreeCacheWays.DeleteLabelValues(nodeName, labels[0], labels[1])
+		}
+	}
+	cacheWaySeries.labels[nodeName] = series
+}
+
+// SetNodeDown records that the RMD instance of nodeName did not respond
+func SetNodeDown(nodeName string) {
+	nodeRmdUp.WithLabelValues(nodeName).Set(0)
+}
+
+// DeleteNode removes all node metrics of nodeName
+func DeleteNode(nodeName string) {
+	nodeRmdUp.DeleteLabelValues(nodeName)
+	nodeCosUsed.DeleteLabelValues(nodeName)
+	nodeWorkloads.DeleteLabelValues(nodeName)
+
+	cacheWaySeries.Lock()
+	defer cacheWaySeries.Unlock()
+	for labels := range cacheWaySeries.labels[nodeName] {
+		nodeFreeCacheWays.DeleteLabelValues(nodeName, labels[0], labels[1])
+	}
+	delete(cacheWaySeries.labels, nodeName)
+}
+
+// SetWorkloadStates records the state of rmdWorkload on each of its targeted nodes
+func SetWorkloadStates(rmdWorkload *intelv1alpha1.RmdWorkload) {
+	states := make(map[string]string)
+	for nodeName, workloadState := range rmdWorkload.Status.WorkloadStates {
+		if state := workloadStateLabel(workloadState.Status); state != "" {
+			states[nodeName] = state
+		}
+	}
+	workloadStore.set(workloadKey(rmdWorkload.GetObjectMeta().GetNamespace(), rmdWorkload.GetObjectMeta().GetName()), states)
+}
+
+// DeleteWorkload removes the states of a deleted RmdWorkload
+func DeleteWorkload(namespace, name string) {
+	workloadStore.set(workloadKey(namespace, name), nil)
+}
+
+func workloadKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// workloadStateLabel maps the WorkloadState status reported by RMD or set by the operator to a state label
+func workloadStateLabel(status string) string {
+	switch status {
+	case "":
+		return ""
+	case "Successful":
+		return stateApplied
+	case "Pending":
+		return statePending
+	case "Conflict":
+		return stateConflict
+	default:
+		return stateFailed
+	}
+}
+
+// workloadCollector reports the number of RmdWorkloads per state. States are counted on collection so
+// that deleted RmdWorkloads never leave stale series behind.
+type workloadCollector struct {
+	mutex  sync.Mutex
+	states map[string]map[string]string
+}
+
+func (c *workloadCollector) set(key string, states map[string]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(states) == 0 {
+		delete(c.states, key)
+		return
+	}
+	c.states[key] = states
+}
+
+// Describe implements prometheus.Collector
+func (c *workloadCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workloadsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *workloadCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	counts := map[string]int{stateApplied: 0, statePending: 0, stateConflict: 0, stateFailed: 0}
+	for _, states := range c.states {
+		for _, state := range states {
+			counts[state]++
+		}
+	}
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(workloadsDesc, prometheus.GaugeValue, float64(count), state)
+	}
+}
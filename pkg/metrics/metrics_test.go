@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInstrumentedTransport(t *testing.T) {
+	tcases := []struct {
+		name           string
+		nodeName       string
+		method         string
+		statusCode     int
+		expectedCode   string
+		expectedErrors float64
+	}{
+		{
+			name:         "test case 1 - successful GET",
+			nodeName:     "example-node-1.com",
+			method:       http.MethodGet,
+			statusCode:   http.StatusOK,
+			expectedCode: "200",
+		},
+		{
+			name:           "test case 2 - rejected POST",
+			nodeName:       "example-node-2.com",
+			method:         http.MethodPost,
+			statusCode:     http.StatusBadRequest,
+			expectedCode:   "400",
+			expectedErrors: 1,
+		},
+	}
+	for _, tc := range tcases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.statusCode)
+		}))
+		SetRmdNodeAddress(tc.nodeName, ts.URL)
+
+		client := &http.Client{Transport: &InstrumentedTransport{}}
+		req, err := http.NewRequest(tc.method, ts.URL+"/v1/workloads", nil)
+		if err != nil {
+			t.Fatalf("%v failed: error creating request: (%v)", tc.name, err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%v failed: request returned error: (%v)", tc.name, err)
+		}
+		resp.Body.Close()
+		ts.Close()
+
+		requests := testutil.ToFloat64(rmdRequests.WithLabelValues(tc.nodeName, tc.method, tc.expectedCode))
+		if requests != 1 {
+			t.Errorf("%v failed: Expected 1 request, got %v", tc.name, requests)
+		}
+		errors := testutil.ToFloat64(rmdRequestErrors.WithLabelValues(tc.nodeName, tc.method))
+		if errors != tc.expectedErrors {
+			t.Errorf("%v failed: Expected %v errors, got %v", tc.name, tc.expectedErrors, errors)
+		}
+	}
+}
+
+func TestSetNodeInventory(t *testing.T) {
+	nodeName := "example-node-1.com"
+	workloads := []*rmdtypes.RDTWorkLoad{
+		{ID: "1", CosName: "0-3-guarantee"},
+		{ID: "2", CosName: "0-3-guarantee"},
+		{ID: "3", CosName: "4-7-besteffort"},
+	}
+	SetNodeInventory(nodeName, rmdCache.Infos{
+		Caches: map[uint32]rmdCache.Info{
+			0: {AvailableWaysPool: map[string]string{"guaranteed": "0-3", "shared": "10"}},
+			1: {AvailableWaysPool: map[string]string{"guaranteed": "0-5"}},
+		},
+	}, workloads)
+
+	if up := testutil.ToFloat64(nodeRmdUp.WithLabelValues(nodeName)); up != 1 {
+		t.Errorf("Expected node up, got %v", up)
+	}
+	if cos := testutil.ToFloat64(nodeCosUsed.WithLabelValues(nodeName)); cos != 2 {
+		t.Errorf("Expected 2 COS used, got %v", cos)
+	}
+	if count := testutil.ToFloat64(nodeWorkloads.WithLabelValues(nodeName)); count != 3 {
+		t.Errorf("Expected 3 workloads, got %v", count)
+	}
+	if ways := testutil.ToFloat64(nodeFreeCacheWays.WithLabelValues(nodeName, "1", "guaranteed")); ways != 6 {
+		t.Errorf("Expected 6 free ways, got %v", ways)
+	}
+
+	// Cache 1 no longer reported, its series must be removed.
+	SetNodeInventory(nodeName, rmdCache.Infos{
+		Caches: map[uint32]rmdCache.Info{
+			0: {AvailableWaysPool: map[string]string{"guaranteed": "0-3", "shared": "10"}},
+		},
+	}, workloads)
+	expected := `
+# HELP rmd_operator_node_free_cache_ways Number of free l3 cache ways per node, cache ID and pool as reported by RMD.
+# TYPE rmd_operator_node_free_cache_ways gauge
+rmd_operator_node_free_cache_ways{cache_id="0",node="example-node-1.com",pool="guaranteed"} 4
+rmd_operator_node_free_cache_ways{cache_id="0",node="example-node-1.com",pool="shared"} 1
+`
+	if err := testutil.CollectAndCompare(nodeFreeCacheWays, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected free cache ways: %v", err)
+	}
+
+	SetNodeDown(nodeName)
+	if up := testutil.ToFloat64(nodeRmdUp.WithLabelValues(nodeName)); up != 0 {
+		t.Errorf("Expected node down, got %v", up)
+	}
+
+	DeleteNode(nodeName)
+	if err := testutil.CollectAndCompare(nodeFreeCacheWays, strings.NewReader("")); err != nil {
+		t.Errorf("Expected no free cache ways after node deletion: %v", err)
+	}
+}
+
+func TestSetWorkloadStates(t *testing.T) {
+	rmdWorkload1 := &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rmd-workload-1",
+			Namespace: "default",
+		},
+		Status: intelv1alpha1.RmdWorkloadStatus{
+			WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+				"example-node-1.com": {Status: "Successful"},
+				"example-node-2.com": {Status: "Pending"},
+			},
+		},
+	}
+	rmdWorkload2 := &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rmd-workload-2",
+			Namespace: "default",
+		},
+		Status: intelv1alpha1.RmdWorkloadStatus{
+			WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+				"example-node-1.com": {Status: "Conflict"},
+				"example-node-2.com": {Status: "Failed: cache ways not available"},
+				"example-node-3.com": {Status: "Successful"},
+			},
+		},
+	}
+	SetWorkloadStates(rmdWorkload1)
+	SetWorkloadStates(rmdWorkload2)
+	expected := `
+# HELP rmd_operator_workloads Number of RmdWorkloads by state, counted once per targeted node.
+# TYPE rmd_operator_workloads gauge
+rmd_operator_workloads{state="applied"} 2
+rmd_operator_workloads{state="conflict"} 1
+rmd_operator_workloads{state="failed"} 1
+rmd_operator_workloads{state="pending"} 1
+`
+	if err := testutil.CollectAndCompare(workloadStore, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected workload states: %v", err)
+	}
+
+	DeleteWorkload("default", "rmd-workload-2")
+	expected = `
+# HELP rmd_operator_workloads Number of RmdWorkloads by state, counted once per targeted node.
+# TYPE rmd_operator_workloads gauge
+rmd_operator_workloads{state="applied"} 1
+rmd_operator_workloads{state="conflict"} 0
+rmd_operator_workloads{state="failed"} 0
+rmd_operator_workloads{state="pending"} 1
+`
+	if err := testutil.CollectAndCompare(workloadStore, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected workload states after deletion: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultNodeSysfsPath is the sysfs directory listing the NUMA nodes of the host
+	DefaultNodeSysfsPath     = "/sys/devices/system/node"
+	cacheWaysMisalignedEvent = "CacheWaysMisaligned"
+)
+
+// readNumaNodeCPUs returns the CPUs of each NUMA node listed under nodeSysfsPath
+func readNumaNodeCPUs(nodeSysfsPath string) (map[int64]cpuset.CPUSet, error) {
+	nodeDirs, err := filepath.Glob(filepath.Join(nodeSysfsPath, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeDirs) == 0 {
+		return nil, fmt.Errorf("no NUMA nodes found in %s", nodeSysfsPath)
+	}
+	nodeCPUs := make(map[int64]cpuset.CPUSet)
+	for _, nodeDir := range nodeDirs {
+		nodeID, err := strconv.ParseInt(strings.TrimPrefix(filepath.Base(nodeDir), "node"), 10, 64)
+		if err != nil {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(nodeDir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := cpuset.Parse(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil, err
+		}
+		nodeCPUs[nodeID] = cpus
+	}
+	return nodeCPUs, nil
+}
+
+// cacheWayAllocations groups the cache way devices and the cores of a container by NUMA node. Devices
+// without topology cannot be aligned and are left out. No allocations are returned if none of the
+// devices report topology.
+func cacheWayAllocations(coreIDs []string, cacheWays []podresourcesclient.Device, nodeCPUs map[int64]cpuset.CPUSet) []intelv1alpha1.CacheWayAllocation {
+	nodeDevices := make(map[int64][]string)
+	for _, cacheWay := range cacheWays {
+		for _, node := range cacheWay.NUMANodes {
+			nodeDevices[node] = append(nodeDevices[node], cacheWay.ID)
+		}
+	}
+	if len(nodeDevices) == 0 {
+		return nil
+	}
+
+	cores := cpuset.NewCPUSet()
+	if parsed, err := cpuset.Parse(strings.Join(coreIDs, ",")); err == nil {
+		cores = parsed
+	}
+	nodeCores := make(map[int64]cpuset.CPUSet)
+	for node, cpus := range nodeCPUs {
+		if intersection := cores.Intersection(cpus); !intersection.IsEmpty() {
+			nodeCores[node] = intersection
+		}
+	}
+
+	nodes := make([]int64, 0)
+	for node := range nodeDevices {
+		nodes = append(nodes, node)
+	}
+	for node := range nodeCores {
+		if _, ok := nodeDevices[node]; !ok {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+	allocations := make([]intelv1alpha1.CacheWayAllocation, 0)
+	for _, node := range nodes {
+		allocation := intelv1alpha1.CacheWayAllocation{NumaNode: node}
+		if deviceIDs, ok := nodeDevices[node]; ok {
+			sort.Strings(deviceIDs)
+			allocation.DeviceIds = deviceIDs
+		}
+		if nodeCoreSet, ok := nodeCores[node]; ok {
+			for _, core := range nodeCoreSet.ToSlice() {
+				allocation.CoreIds = append(allocation.CoreIds, strconv.Itoa(core))
+			}
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations
+}
+
+// cacheWayMisalignments reports the NUMA nodes on which cache ways were allocated without any of the
+// container's cores, and those on which the container's cores have no cache ways allocated.
+func cacheWayMisalignments(allocations []intelv1alpha1.CacheWayAllocation) []string {
+	misalignments := make([]string, 0)
+	for _, allocation := range allocations {
+		switch {
+		case len(allocation.CoreIds) == 0:
+			misalignments = append(misalignments, fmt.Sprintf("cache ways %s allocated on NUMA node %d without any of the container's cores",
+				strings.Join(allocation.DeviceIds, ","), allocation.NumaNode))
+		case len(allocation.DeviceIds) == 0:
+			misalignments = append(misalignments, fmt.Sprintf("cores %s on NUMA node %d have no cache ways allocated",
+				strings.Join(allocation.CoreIds, ","), allocation.NumaNode))
+		}
+	}
+	return misalignments
+}
+
+// setCacheWayAllocations records allocations in the status of rmdWorkload. The status is only patched
+// if the allocations changed, misalignments are then reported as Warning events on pod and rmdWorkload.
+func (r *ReconcilePod) setCacheWayAllocations(pod *corev1.Pod, rmdWorkload *intelv1alpha1.RmdWorkload, allocations []intelv1alpha1.CacheWayAllocation) error {
+	logger := log.WithName("setCacheWayAllocations")
+	if reflect.DeepEqual(rmdWorkload.Status.CacheWayAllocations, allocations) {
+		return nil
+	}
+	patch := client.MergeFrom(rmdWorkload.DeepCopy())
+	rmdWorkload.Status.CacheWayAllocations = allocations
+	err := r.client.Status().Patch(context.TODO(), rmdWorkload, patch)
+	if err != nil {
+		logger.Error(err, "Failed to patch rmdWorkload cache way allocations")
+		return err
+	}
+
+	podName, containerName := workloadContainer(rmdWorkload)
+	subject := fmt.Sprintf("container %s", containerName)
+	if containerName == "" {
+		subject = fmt.Sprintf("pod %s", podName)
+	}
+	for _, misalignment := range cacheWayMisalignments(allocations) {
+		logger.Info("Cache ways not aligned with container cores", "workload name", rmdWorkload.GetObjectMeta().GetName(), "misalignment", misalignment)
+		message := fmt.Sprintf("%s: %s", subject, misalignment)
+		r.recorder.Event(pod, corev1.EventTypeWarning, cacheWaysMisalignedEvent, message)
+		r.recorder.Event(rmdWorkload, corev1.EventTypeWarning, cacheWaysMisalignedEvent, message)
+	}
+	return nil
+}
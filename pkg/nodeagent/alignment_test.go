@@ -0,0 +1,286 @@
+package nodeagent
+
+import (
+	"context"
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
+	"google.golang.org/grpc"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"testing"
+)
+
+// fakeListerClient serves a fixed podresources List response
+type fakeListerClient struct {
+	listResponse *podresourcesclient.ListPodResourcesResponse
+}
+
+func (f *fakeListerClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*podresourcesclient.ListPodResourcesResponse, error) {
+	return f.listResponse, nil
+}
+
+// createNodeSysfs writes the cpulist of each NUMA node under a temporary sysfs node directory and
+// returns its path.
+func createNodeSysfs(t *testing.T, nodeCPUs map[string]string) string {
+	nodeSysfsPath, err := ioutil.TempDir("", "node")
+	if err != nil {
+		t.Fatalf("error creating sysfs tree: (%v)", err)
+	}
+	for node, cpus := range nodeCPUs {
+		nodeDir := filepath.Join(nodeSysfsPath, node)
+		err = os.MkdirAll(nodeDir, 0755)
+		if err != nil {
+			t.Fatalf("error creating sysfs tree: (%v)", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(nodeDir, "cpulist"), []byte(cpus+"\n"), 0644)
+		if err != nil {
+			t.Fatalf("error creating sysfs tree: (%v)", err)
+		}
+	}
+	return nodeSysfsPath
+}
+
+func TestReadNumaNodeCPUs(t *testing.T) {
+	tcases := []struct {
+		name             string
+		nodeCPUs         map[string]string
+		expectedNodeCPUs map[int64]cpuset.CPUSet
+		expectedError    bool
+	}{
+		{
+			name:     "test case 1 - two NUMA nodes",
+			nodeCPUs: map[string]string{"node0": "0-3,8-11", "node1": "4-7,12-15"},
+			expectedNodeCPUs: map[int64]cpuset.CPUSet{
+				0: cpuset.MustParse("0-3,8-11"),
+				1: cpuset.MustParse("4-7,12-15"),
+			},
+		},
+		{
+			name:          "test case 2 - no NUMA nodes",
+			nodeCPUs:      map[string]string{},
+			expectedError: true,
+		},
+		{
+			name:          "test case 3 - invalid cpulist",
+			nodeCPUs:      map[string]string{"node0": "a-b"},
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		nodeSysfsPath := createNodeSysfs(t, tc.nodeCPUs)
+		nodeCPUs, err := readNumaNodeCPUs(nodeSysfsPath)
+		os.RemoveAll(nodeSysfsPath)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+		}
+		if tc.expectedError {
+			continue
+		}
+		if len(nodeCPUs) != len(tc.expectedNodeCPUs) {
+			t.Errorf("%v failed: Expected %v, got %v", tc.name, tc.expectedNodeCPUs, nodeCPUs)
+		}
+		for node, cpus := range tc.expectedNodeCPUs {
+			if !nodeCPUs[node].Equals(cpus) {
+				t.Errorf("%v failed: Expected node %v cpus %v, got %v", tc.name, node, cpus, nodeCPUs[node])
+			}
+		}
+	}
+}
+
+func TestCacheWayAllocations(t *testing.T) {
+	nodeCPUs := map[int64]cpuset.CPUSet{
+		0: cpuset.MustParse("0-3"),
+		1: cpuset.MustParse("4-7"),
+	}
+	tcases := []struct {
+		name                  string
+		coreIDs               []string
+		cacheWays             []podresourcesclient.Device
+		expectedAllocations   []intelv1alpha1.CacheWayAllocation
+		expectedMisalignments int
+	}{
+		{
+			name:    "test case 1 - aligned",
+			coreIDs: []string{"0", "1"},
+			cacheWays: []podresourcesclient.Device{
+				{ID: "01", NUMANodes: []int64{0}},
+				{ID: "00", NUMANodes: []int64{0}},
+			},
+			expectedAllocations: []intelv1alpha1.CacheWayAllocation{
+				{NumaNode: 0, DeviceIds: []string{"00", "01"}, CoreIds: []string{"0", "1"}},
+			},
+		},
+		{
+			name:    "test case 2 - cache ways on other NUMA node",
+			coreIDs: []string{"0", "1"},
+			cacheWays: []podresourcesclient.Device{
+				{ID: "10", NUMANodes: []int64{1}},
+			},
+			expectedAllocations: []intelv1alpha1.CacheWayAllocation{
+				{NumaNode: 0, CoreIds: []string{"0", "1"}},
+				{NumaNode: 1, DeviceIds: []string{"10"}},
+			},
+			expectedMisalignments: 2,
+		},
+		{
+			name:    "test case 3 - cores across NUMA nodes",
+			coreIDs: []string{"3", "4"},
+			cacheWays: []podresourcesclient.Device{
+				{ID: "00", NUMANodes: []int64{0}},
+				{ID: "10", NUMANodes: []int64{1}},
+			},
+			expectedAllocations: []intelv1alpha1.CacheWayAllocation{
+				{NumaNode: 0, DeviceIds: []string{"00"}, CoreIds: []string{"3"}},
+				{NumaNode: 1, DeviceIds: []string{"10"}, CoreIds: []string{"4"}},
+			},
+		},
+		{
+			name:    "test case 4 - no device topology",
+			coreIDs: []string{"0", "1"},
+			cacheWays: []podresourcesclient.Device{
+				{ID: "00"},
+			},
+		},
+	}
+	for _, tc := range tcases {
+		allocations := cacheWayAllocations(tc.coreIDs, tc.cacheWays, nodeCPUs)
+		if !reflect.DeepEqual(allocations, tc.expectedAllocations) {
+			t.Errorf("%v failed: Expected %+v, got %+v", tc.name, tc.expectedAllocations, allocations)
+		}
+		misalignments := cacheWayMisalignments(allocations)
+		if len(misalignments) != tc.expectedMisalignments {
+			t.Errorf("%v failed: Expected %v misalignments, got %v", tc.name, tc.expectedMisalignments, misalignments)
+		}
+	}
+}
+
+func alignmentTestPod() *corev1.Pod {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+		l3Cache:               resource.MustParse("1"),
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			UID:       "f906a249-ab9d-4180-9afa-4075e2058ac7",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "example-node-1.com",
+			Containers: []corev1.Container{
+				{
+					Name:      "container-1",
+					Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources},
+				},
+			},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestReconcileCacheWayAlignment(t *testing.T) {
+	tcases := []struct {
+		name                string
+		cacheWayNode        int64
+		expectedAllocations []intelv1alpha1.CacheWayAllocation
+		expectedEvents      int
+	}{
+		{
+			name:         "test case 1 - cache ways aligned with cores",
+			cacheWayNode: 0,
+			expectedAllocations: []intelv1alpha1.CacheWayAllocation{
+				{NumaNode: 0, DeviceIds: []string{"02"}, CoreIds: []string{"0", "1"}},
+			},
+		},
+		{
+			name:         "test case 2 - cache ways misaligned",
+			cacheWayNode: 1,
+			expectedAllocations: []intelv1alpha1.CacheWayAllocation{
+				{NumaNode: 0, CoreIds: []string{"0", "1"}},
+				{NumaNode: 1, DeviceIds: []string{"02"}},
+			},
+			// Two misalignments, each reported on the pod and the RmdWorkload
+			expectedEvents: 4,
+		},
+	}
+	for _, tc := range tcases {
+		pod := alignmentTestPod()
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding to scheme: (%v)", err)
+		}
+		nodeSysfsPath := createNodeSysfs(t, map[string]string{"node0": "0-3", "node1": "4-7"})
+		defer os.RemoveAll(nodeSysfsPath)
+		recorder := record.NewFakeRecorder(10)
+		r := &ReconcilePod{
+			client:   fake.NewFakeClientWithScheme(s, []runtime.Object{pod}...),
+			scheme:   s,
+			recorder: recorder,
+			cpuDiscoverer: &podresourcesclient.PodResourcesClient{
+				Client: &fakeListerClient{
+					listResponse: &podresourcesclient.ListPodResourcesResponse{
+						PodResources: []*podresourcesclient.PodResources{
+							{
+								Name:      "pod-1",
+								Namespace: "default",
+								Containers: []*podresourcesclient.ContainerResources{
+									{
+										Name:   "container-1",
+										CpuIds: []int64{0, 1},
+										Devices: []*podresourcesapi.ContainerDevices{
+											{
+												ResourceName: l3Cache,
+												DeviceIds:    []string{"02"},
+												Topology:     &podresourcesapi.TopologyInfo{Nodes: []*podresourcesapi.NUMANode{{ID: tc.cacheWayNode}}},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			nodeName:      "example-node-1.com",
+			nodeSysfsPath: nodeSysfsPath,
+		}
+
+		_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-1", Namespace: "default"}})
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "pod-1-rmd-workload-container-1", Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Fatalf("%v failed: error getting RmdWorkload: (%v)", tc.name, err)
+		}
+		if !reflect.DeepEqual(rmdWorkload.Status.CacheWayAllocations, tc.expectedAllocations) {
+			t.Errorf("%v failed: Expected allocations %+v, got %+v", tc.name, tc.expectedAllocations, rmdWorkload.Status.CacheWayAllocations)
+		}
+		if len(recorder.Events) != tc.expectedEvents {
+			t.Errorf("%v failed: Expected %v events, got %v", tc.name, tc.expectedEvents, len(recorder.Events))
+		}
+		for len(recorder.Events) > 0 {
+			event := <-recorder.Events
+			if !strings.HasPrefix(event, "Warning CacheWaysMisaligned container container-1: ") {
+				t.Errorf("%v failed: Expected CacheWaysMisaligned warning, got %v", tc.name, event)
+			}
+		}
+	}
+}
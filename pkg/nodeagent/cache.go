@@ -0,0 +1,162 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const nodeNameField = "spec.nodeName"
+
+var podGVK = corev1.SchemeGroupVersion.WithKind("Pod")
+
+// nodeCache is a cache.Cache whose Pods are restricted to the Pods scheduled on a single node.
+// All other kinds are served by the default informer cache.
+type nodeCache struct {
+	cache.Cache
+	podInformer toolscache.SharedIndexInformer
+}
+
+// blank assignment to verify that nodeCache implements cache.Cache
+var _ cache.Cache = &nodeCache{}
+
+// NewNodeCacheFunc returns a cache.NewCacheFunc creating caches whose Pod informer only lists and
+// watches Pods with spec.nodeName set to nodeName. It is passed to the Manager as NewCache so that
+// the node agent does not cache the Pods of the whole cluster.
+func NewNodeCacheFunc(nodeName string) cache.NewCacheFunc {
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		defaultCache, err := cache.New(config, opts)
+		if err != nil {
+			return nil, err
+		}
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		var resync time.Duration
+		if opts.Resync != nil {
+			resync = *opts.Resync
+		}
+		podInformer := coreinformers.NewFilteredPodInformer(clientset, opts.Namespace, resync,
+			toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc},
+			func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector(nodeNameField, nodeName).String()
+			})
+		return newNodeCache(defaultCache, podInformer), nil
+	}
+}
+
+func newNodeCache(defaultCache cache.Cache, podInformer toolscache.SharedIndexInformer) *nodeCache {
+	return &nodeCache{Cache: defaultCache, podInformer: podInformer}
+}
+
+// Get reads Pods from the node's Pod informer and other objects from the default cache
+func (c *nodeCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return c.Cache.Get(ctx, key, obj)
+	}
+	storeKey := key.Name
+	if key.Namespace != "" {
+		storeKey = key.Namespace + "/" + key.Name
+	}
+	item, exists, err := c.podInformer.GetIndexer().GetByKey(storeKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewNotFound(corev1.Resource("pods"), key.Name)
+	}
+	item.(*corev1.Pod).DeepCopyInto(pod)
+	pod.GetObjectKind().SetGroupVersionKind(podGVK)
+	return nil
+}
+
+// List lists Pods from the node's Pod informer and other objects from the default cache. Pods can
+// only be filtered by namespace and labels.
+func (c *nodeCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	podList, ok := list.(*corev1.PodList)
+	if !ok {
+		return c.Cache.List(ctx, list, opts...)
+	}
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil {
+		return fmt.Errorf("field selectors are not supported for Pods cached by the node agent")
+	}
+
+	var items []interface{}
+	var err error
+	if listOpts.Namespace != "" {
+		items, err = c.podInformer.GetIndexer().ByIndex(toolscache.NamespaceIndex, listOpts.Namespace)
+		if err != nil {
+			return err
+		}
+	} else {
+		items = c.podInformer.GetIndexer().List()
+	}
+	pods := make([]runtime.Object, 0)
+	for _, item := range items {
+		pod := item.(*corev1.Pod)
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(pod.GetObjectMeta().GetLabels())) {
+			continue
+		}
+		podCopy := pod.DeepCopy()
+		podCopy.GetObjectKind().SetGroupVersionKind(podGVK)
+		pods = append(pods, podCopy)
+	}
+	return meta.SetList(podList, pods)
+}
+
+// GetInformer returns the node's Pod informer for Pods and the default cache's informer otherwise
+func (c *nodeCache) GetInformer(obj runtime.Object) (cache.Informer, error) {
+	if _, ok := obj.(*corev1.Pod); ok {
+		return c.podInformer, nil
+	}
+	return c.Cache.GetInformer(obj)
+}
+
+// GetInformerForKind returns the node's Pod informer for Pods and the default cache's informer otherwise
+func (c *nodeCache) GetInformerForKind(gvk schema.GroupVersionKind) (cache.Informer, error) {
+	if gvk == podGVK {
+		return c.podInformer, nil
+	}
+	return c.Cache.GetInformerForKind(gvk)
+}
+
+// Start runs the node's Pod informer and the informers of the default cache until stopCh is closed
+func (c *nodeCache) Start(stopCh <-chan struct{}) error {
+	go c.podInformer.Run(stopCh)
+	return c.Cache.Start(stopCh)
+}
+
+// WaitForCacheSync waits for the node's Pod informer and the default cache to sync
+func (c *nodeCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	if !toolscache.WaitForCacheSync(stop, c.podInformer.HasSynced) {
+		return false
+	}
+	return c.Cache.WaitForCacheSync(stop)
+}
+
+// IndexField adds field indices to objects of the default cache. Pods of the node are not indexed.
+func (c *nodeCache) IndexField(obj runtime.Object, field string, extractValue client.IndexerFunc) error {
+	if _, ok := obj.(*corev1.Pod); ok {
+		return fmt.Errorf("field indices are not supported for Pods cached by the node agent")
+	}
+	return c.Cache.IndexField(obj, field, extractValue)
+}
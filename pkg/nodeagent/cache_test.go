@@ -0,0 +1,135 @@
+package nodeagent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func createNodeCache(t *testing.T, pods ...*corev1.Pod) *nodeCache {
+	podInformer := coreinformers.NewPodInformer(k8sfake.NewSimpleClientset(), metav1.NamespaceAll, 0,
+		toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc})
+	for _, pod := range pods {
+		if err := podInformer.GetIndexer().Add(pod); err != nil {
+			t.Fatalf("error adding pod to informer: (%v)", err)
+		}
+	}
+	return newNodeCache(&informertest.FakeInformers{}, podInformer)
+}
+
+func cachedPod(name, namespace string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: "example-node-1.com"},
+	}
+}
+
+func TestNodeCacheGet(t *testing.T) {
+	c := createNodeCache(t, cachedPod("pod-1", "default", nil))
+	tcases := []struct {
+		name             string
+		key              types.NamespacedName
+		expectedNotFound bool
+	}{
+		{
+			name: "test case 1 - pod on node",
+			key:  types.NamespacedName{Name: "pod-1", Namespace: "default"},
+		},
+		{
+			name:             "test case 2 - pod not on node",
+			key:              types.NamespacedName{Name: "pod-2", Namespace: "default"},
+			expectedNotFound: true,
+		},
+	}
+	for _, tc := range tcases {
+		pod := &corev1.Pod{}
+		err := c.Get(context.TODO(), tc.key, pod)
+		if errors.IsNotFound(err) != tc.expectedNotFound {
+			t.Errorf("%v failed: Expected not found %v, got %v", tc.name, tc.expectedNotFound, err)
+		}
+		if err == nil && (pod.GetObjectMeta().GetName() != tc.key.Name || pod.Kind != "Pod") {
+			t.Errorf("%v failed: Expected pod %v, got %+v", tc.name, tc.key, pod)
+		}
+	}
+}
+
+func TestNodeCacheList(t *testing.T) {
+	c := createNodeCache(t,
+		cachedPod("pod-1", "default", map[string]string{"app": "db"}),
+		cachedPod("pod-2", "default", nil),
+		cachedPod("pod-3", "test", map[string]string{"app": "db"}),
+	)
+	tcases := []struct {
+		name          string
+		opts          []client.ListOption
+		expectedPods  []string
+		expectedError bool
+	}{
+		{
+			name:         "test case 1 - all pods on node",
+			expectedPods: []string{"pod-1", "pod-2", "pod-3"},
+		},
+		{
+			name:         "test case 2 - pods in namespace",
+			opts:         []client.ListOption{client.InNamespace("default")},
+			expectedPods: []string{"pod-1", "pod-2"},
+		},
+		{
+			name:         "test case 3 - pods with labels",
+			opts:         []client.ListOption{client.MatchingLabels{"app": "db"}},
+			expectedPods: []string{"pod-1", "pod-3"},
+		},
+		{
+			name:          "test case 4 - field selector not supported",
+			opts:          []client.ListOption{client.MatchingFields{"spec.nodeName": "example-node-1.com"}},
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		podList := &corev1.PodList{}
+		err := c.List(context.TODO(), podList, tc.opts...)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+		}
+		if tc.expectedError {
+			continue
+		}
+		podNames := make(map[string]bool)
+		for _, pod := range podList.Items {
+			podNames[pod.GetObjectMeta().GetName()] = true
+		}
+		expectedNames := make(map[string]bool)
+		for _, podName := range tc.expectedPods {
+			expectedNames[podName] = true
+		}
+		if !reflect.DeepEqual(podNames, expectedNames) {
+			t.Errorf("%v failed: Expected pods %v, got %v", tc.name, tc.expectedPods, podNames)
+		}
+	}
+}
+
+func TestNodeCacheGetInformer(t *testing.T) {
+	c := createNodeCache(t)
+	informer, err := c.GetInformer(&corev1.Pod{})
+	if err != nil || informer != c.podInformer {
+		t.Errorf("Expected node pod informer for Pods, got %v (%v)", informer, err)
+	}
+	informer, err = c.GetInformerForKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+	if err != nil || informer != c.podInformer {
+		t.Errorf("Expected node pod informer for Pod kind, got %v (%v)", informer, err)
+	}
+	informer, err = c.GetInformer(&corev1.Node{})
+	if err != nil || informer == c.podInformer {
+		t.Errorf("Expected default informer for Nodes, got %v (%v)", informer, err)
+	}
+}
@@ -1,16 +1,17 @@
 package nodeagent
 
 import (
+	"github.com/intel/rmd-operator/pkg/cpudiscovery"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 // AddToManagerFuncs is a list of functions to add all Controllers to the Manager
-var AddToManagerFuncs []func(manager.Manager) error
+var AddToManagerFuncs []func(manager.Manager, cpudiscovery.Discoverer) error
 
-// AddToManager adds all Controllers to the Manager
-func AddToManager(m manager.Manager) error {
+// AddToManager adds all Controllers to the Manager. Container CPUs are discovered with discoverer.
+func AddToManager(m manager.Manager, discoverer cpudiscovery.Discoverer) error {
 	for _, f := range AddToManagerFuncs {
-		if err := f(m); err != nil {
+		if err := f(m, discoverer); err != nil {
 			return err
 		}
 	}
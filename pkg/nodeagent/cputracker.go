@@ -0,0 +1,185 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/cpudiscovery"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const cpuManagerReassignmentReason = "CPUManagerReassignment"
+
+// containerCPUGetter returns the CPUs assigned to a container by the kubelet CPU manager
+type containerCPUGetter interface {
+	GetContainerCPUs(namespace, podName, containerName string) ([]string, error)
+}
+
+// CPUTracker periodically re-discovers the CPUs of containers with an RmdWorkload on its node. The CPU
+// manager may reassign CPUs after the RmdWorkload was created, e.g. on kubelet restart with a changed
+// state file, in which case the RmdWorkload's coreIds are patched with the container's current CPUs.
+type CPUTracker struct {
+	client        client.Client
+	cpuDiscoverer containerCPUGetter
+	nodeName      string
+	interval      time.Duration
+}
+
+// blank assignment to verify that CPUTracker implements manager.Runnable
+var _ manager.Runnable = &CPUTracker{}
+
+// NewCPUTracker returns a CPUTracker for nodeName checking container CPUs discovered with discoverer
+// every interval
+func NewCPUTracker(c client.Client, discoverer cpudiscovery.Discoverer, nodeName string, interval time.Duration) *CPUTracker {
+	return &CPUTracker{
+		client:        c,
+		cpuDiscoverer: discoverer,
+		nodeName:      nodeName,
+		interval:      interval,
+	}
+}
+
+// Start runs the tracker until stop is closed
+func (t *CPUTracker) Start(stop <-chan struct{}) error {
+	logger := log.WithName("CPUTracker")
+	logger.Info("Starting CPU tracker", "node", t.nodeName, "interval", t.interval)
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			err := t.track()
+			if err != nil {
+				logger.Error(err, "Failed to track container CPUs")
+			}
+		}
+	}
+}
+
+// track patches the coreIds of the RmdWorkloads whose container CPUs were reassigned
+func (t *CPUTracker) track() error {
+	logger := log.WithName("track")
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err := t.client.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		return err
+	}
+
+	for i := range rmdWorkloads.Items {
+		rmdWorkload := &rmdWorkloads.Items[i]
+		// Workloads of containers without exclusive CPUs have no coreIds to track.
+		if len(rmdWorkload.Spec.CoreIds) == 0 {
+			continue
+		}
+		podName, containerName := workloadContainer(rmdWorkload)
+		if podName == "" {
+			continue
+		}
+		pod := &corev1.Pod{}
+		err = t.client.Get(context.TODO(), types.NamespacedName{Name: podName, Namespace: rmdWorkload.GetObjectMeta().GetNamespace()}, pod)
+		if err != nil || pod.Spec.NodeName != t.nodeName || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		name := types.NamespacedName{Name: rmdWorkload.GetObjectMeta().GetName(), Namespace: rmdWorkload.GetObjectMeta().GetNamespace()}
+		coreIDs, err := t.workloadCPUs(pod, containerName)
+		if err != nil || len(coreIDs) == 0 {
+			logger.Info("Could not discover container CPUs.", "workload", name, "Error:", err)
+			continue
+		}
+		previous, current, changed := coresChanged(rmdWorkload.Spec.CoreIds, coreIDs)
+		if !changed {
+			continue
+		}
+
+		subject := fmt.Sprintf("container %s", containerName)
+		if containerName == "" {
+			subject = fmt.Sprintf("pod %s", podName)
+		}
+		logger.Info("Container CPUs reassigned, patching RmdWorkload.", "workload", name, "from", previous, "to", current)
+		err = t.patchCoreIDs(rmdWorkload, coreIDs, fmt.Sprintf("cores of %s reassigned from %s to %s", subject, previous, current))
+		if err != nil {
+			// Patch again on next interval.
+			logger.Info("Failed to patch RmdWorkload cores.", "workload", name, "Error:", err)
+		}
+	}
+	return nil
+}
+
+// workloadCPUs returns the CPUs of container containerName of pod, or the CPUs of all running containers
+// of pod with exclusive CPUs sharing the pod's RmdWorkload if containerName is empty
+func (t *CPUTracker) workloadCPUs(pod *corev1.Pod, containerName string) ([]string, error) {
+	namespace := pod.GetObjectMeta().GetNamespace()
+	if containerName != "" {
+		return t.cpuDiscoverer.GetContainerCPUs(namespace, pod.GetObjectMeta().GetName(), containerName)
+	}
+	coreIDs := make([]string, 0)
+	for _, container := range getContainersRequestingCache(pod) {
+		if containerTerminated(pod, container.Name) || !exclusiveCPUs(pod, &container) {
+			continue
+		}
+		containerCoreIDs, err := t.cpuDiscoverer.GetContainerCPUs(namespace, pod.GetObjectMeta().GetName(), container.Name)
+		if err != nil {
+			return nil, err
+		}
+		coreIDs = append(coreIDs, containerCoreIDs...)
+	}
+	return coreIDs, nil
+}
+
+// patchCoreIDs patches the coreIds of rmdWorkload and records the reassignment in its CoresReassigned condition
+func (t *CPUTracker) patchCoreIDs(rmdWorkload *intelv1alpha1.RmdWorkload, coreIDs []string, message string) error {
+	patch := client.MergeFrom(rmdWorkload.DeepCopy())
+	rmdWorkload.Spec.CoreIds = coreIDs
+	err := t.client.Patch(context.TODO(), rmdWorkload, patch)
+	if err != nil {
+		return err
+	}
+
+	statusPatch := client.MergeFrom(rmdWorkload.DeepCopy())
+	setCoresReassignedCondition(&rmdWorkload.Status, message)
+	return t.client.Status().Patch(context.TODO(), rmdWorkload, statusPatch)
+}
+
+// setCoresReassignedCondition replaces the CoresReassigned condition of status with the latest reassignment
+func setCoresReassignedCondition(status *intelv1alpha1.RmdWorkloadStatus, message string) {
+	condition := intelv1alpha1.RmdWorkloadCondition{
+		Type:               intelv1alpha1.CoresReassigned,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             cpuManagerReassignmentReason,
+		Message:            message,
+	}
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == intelv1alpha1.CoresReassigned {
+			status.Conditions[i] = condition
+			return
+		}
+	}
+	status.Conditions = append(status.Conditions, condition)
+}
+
+// coresChanged compares the cores of an RmdWorkload with the CPUs currently assigned to its container
+// and returns both in cpuset format. Cores that cannot be parsed are treated as changed.
+func coresChanged(workloadCores, containerCPUs []string) (string, string, bool) {
+	previous, err := cpuset.Parse(strings.Join(workloadCores, ","))
+	if err != nil {
+		return strings.Join(workloadCores, ","), strings.Join(containerCPUs, ","), true
+	}
+	current, err := cpuset.Parse(strings.Join(containerCPUs, ","))
+	if err != nil {
+		return previous.String(), strings.Join(containerCPUs, ","), true
+	}
+	return previous.String(), current.String(), !previous.Equals(current)
+}
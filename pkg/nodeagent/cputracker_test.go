@@ -0,0 +1,235 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+// fakeCPUGetter returns the CPUs of containers keyed by namespace, pod and container name
+type fakeCPUGetter map[string][]string
+
+func (f fakeCPUGetter) GetContainerCPUs(namespace, podName, containerName string) ([]string, error) {
+	cpus, ok := f[namespace+"/"+podName+"/"+containerName]
+	if !ok {
+		return nil, fmt.Errorf("cpus for Pod:%v/%v Container:%v not found", namespace, podName, containerName)
+	}
+	return cpus, nil
+}
+
+func trackedRmdWorkload(coreIDs []string) *intelv1alpha1.RmdWorkload {
+	return &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod-1-rmd-workload-container-1",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Pod", Name: "pod-1"}},
+		},
+		Spec: intelv1alpha1.RmdWorkloadSpec{
+			CoreIds: coreIDs,
+			Nodes:   []string{"example-node-1.com"},
+		},
+	}
+}
+
+func trackedPod(nodeName string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+// trackedSharedPod returns a running pod with two exclusive-CPU containers sharing the pod's RmdWorkload
+func trackedSharedPod() *corev1.Pod {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+		l3Cache:               resource.MustParse("1"),
+	}
+	pod := trackedPod("example-node-1.com", corev1.PodRunning)
+	for _, name := range []string{"container-1", "container-2"} {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:      name,
+			Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources},
+		})
+	}
+	return pod
+}
+
+func trackedPodRmdWorkload(coreIDs []string) *intelv1alpha1.RmdWorkload {
+	rmdWorkload := trackedRmdWorkload(coreIDs)
+	rmdWorkload.SetName("pod-1-rmd-workload")
+	return rmdWorkload
+}
+
+func TestCoresChanged(t *testing.T) {
+	tcases := []struct {
+		name             string
+		workloadCores    []string
+		containerCPUs    []string
+		expectedPrevious string
+		expectedCurrent  string
+		expectedChanged  bool
+	}{
+		{
+			name:             "test case 1 - same cores",
+			workloadCores:    []string{"0", "1"},
+			containerCPUs:    []string{"0", "1"},
+			expectedPrevious: "0-1",
+			expectedCurrent:  "0-1",
+		},
+		{
+			name:             "test case 2 - same cores in range format",
+			workloadCores:    []string{"0-3"},
+			containerCPUs:    []string{"0", "1", "2", "3"},
+			expectedPrevious: "0-3",
+			expectedCurrent:  "0-3",
+		},
+		{
+			name:             "test case 3 - cores reassigned",
+			workloadCores:    []string{"0", "1"},
+			containerCPUs:    []string{"4", "5"},
+			expectedPrevious: "0-1",
+			expectedCurrent:  "4-5",
+			expectedChanged:  true,
+		},
+		{
+			name:             "test case 4 - invalid workload cores",
+			workloadCores:    []string{"a"},
+			containerCPUs:    []string{"4"},
+			expectedPrevious: "a",
+			expectedCurrent:  "4",
+			expectedChanged:  true,
+		},
+	}
+	for _, tc := range tcases {
+		previous, current, changed := coresChanged(tc.workloadCores, tc.containerCPUs)
+		if previous != tc.expectedPrevious || current != tc.expectedCurrent || changed != tc.expectedChanged {
+			t.Errorf("%v failed: Expected %v %v %v, got %v %v %v", tc.name, tc.expectedPrevious, tc.expectedCurrent, tc.expectedChanged, previous, current, changed)
+		}
+	}
+}
+
+func TestTrack(t *testing.T) {
+	tcases := []struct {
+		name              string
+		pod               *corev1.Pod
+		rmdWorkload       *intelv1alpha1.RmdWorkload
+		containerCPUs     fakeCPUGetter
+		expectedCoreIDs   []string
+		expectedCondition string
+	}{
+		{
+			name:            "test case 1 - cores unchanged",
+			pod:             trackedPod("example-node-1.com", corev1.PodRunning),
+			rmdWorkload:     trackedRmdWorkload([]string{"0", "1"}),
+			containerCPUs:   fakeCPUGetter{"default/pod-1/container-1": {"0", "1"}},
+			expectedCoreIDs: []string{"0", "1"},
+		},
+		{
+			name:              "test case 2 - cores reassigned",
+			pod:               trackedPod("example-node-1.com", corev1.PodRunning),
+			rmdWorkload:       trackedRmdWorkload([]string{"0", "1"}),
+			containerCPUs:     fakeCPUGetter{"default/pod-1/container-1": {"4", "5"}},
+			expectedCoreIDs:   []string{"4", "5"},
+			expectedCondition: "cores of container container-1 reassigned from 0-1 to 4-5",
+		},
+		{
+			name:            "test case 3 - pod on other node",
+			pod:             trackedPod("example-node-2.com", corev1.PodRunning),
+			rmdWorkload:     trackedRmdWorkload([]string{"0", "1"}),
+			containerCPUs:   fakeCPUGetter{"default/pod-1/container-1": {"4", "5"}},
+			expectedCoreIDs: []string{"0", "1"},
+		},
+		{
+			name:            "test case 4 - pod not running",
+			pod:             trackedPod("example-node-1.com", corev1.PodPending),
+			rmdWorkload:     trackedRmdWorkload([]string{"0", "1"}),
+			containerCPUs:   fakeCPUGetter{"default/pod-1/container-1": {"4", "5"}},
+			expectedCoreIDs: []string{"0", "1"},
+		},
+		{
+			name:            "test case 5 - container cpus not found",
+			pod:             trackedPod("example-node-1.com", corev1.PodRunning),
+			rmdWorkload:     trackedRmdWorkload([]string{"0", "1"}),
+			containerCPUs:   fakeCPUGetter{},
+			expectedCoreIDs: []string{"0", "1"},
+		},
+		{
+			name:          "test case 6 - workload without exclusive cpus",
+			pod:           trackedPod("example-node-1.com", corev1.PodRunning),
+			rmdWorkload:   trackedRmdWorkload(nil),
+			containerCPUs: fakeCPUGetter{"default/pod-1/container-1": {"4", "5"}},
+		},
+		{
+			name:        "test case 7 - cores of pod workload reassigned",
+			pod:         trackedSharedPod(),
+			rmdWorkload: trackedPodRmdWorkload([]string{"0", "1", "2", "3"}),
+			containerCPUs: fakeCPUGetter{
+				"default/pod-1/container-1": {"0", "1"},
+				"default/pod-1/container-2": {"6", "7"},
+			},
+			expectedCoreIDs:   []string{"0", "1", "6", "7"},
+			expectedCondition: "cores of pod pod-1 reassigned from 0-3 to 0-1,6-7",
+		},
+	}
+	for _, tc := range tcases {
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding to scheme: (%v)", err)
+		}
+		tracker := &CPUTracker{
+			client:        fake.NewFakeClientWithScheme(s, []runtime.Object{tc.pod, tc.rmdWorkload}...),
+			cpuDiscoverer: tc.containerCPUs,
+			nodeName:      "example-node-1.com",
+		}
+		err := tracker.track()
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = tracker.client.Get(context.TODO(), types.NamespacedName{Name: tc.rmdWorkload.GetObjectMeta().GetName(), Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Fatalf("error getting RmdWorkload: (%v)", err)
+		}
+		if !reflect.DeepEqual(rmdWorkload.Spec.CoreIds, tc.expectedCoreIDs) {
+			t.Errorf("%v failed: Expected cores %v, got %v", tc.name, tc.expectedCoreIDs, rmdWorkload.Spec.CoreIds)
+		}
+		message := ""
+		for _, condition := range rmdWorkload.Status.Conditions {
+			if condition.Type == intelv1alpha1.CoresReassigned && condition.Reason == cpuManagerReassignmentReason {
+				message = condition.Message
+			}
+		}
+		if message != tc.expectedCondition {
+			t.Errorf("%v failed: Expected condition message %q, got %q", tc.name, tc.expectedCondition, message)
+		}
+	}
+}
+
+func TestSetCoresReassignedCondition(t *testing.T) {
+	status := &intelv1alpha1.RmdWorkloadStatus{
+		Conditions: []intelv1alpha1.RmdWorkloadCondition{
+			{Type: intelv1alpha1.Conflict, Status: corev1.ConditionFalse},
+			{Type: intelv1alpha1.CoresReassigned, Status: corev1.ConditionTrue, Message: "cores of container container-1 reassigned from 0-1 to 2-3"},
+		},
+	}
+	setCoresReassignedCondition(status, "cores of container container-1 reassigned from 2-3 to 4-5")
+	if len(status.Conditions) != 2 || status.Conditions[0].Type != intelv1alpha1.Conflict {
+		t.Errorf("Expected other conditions kept, got %+v", status.Conditions)
+	}
+	if status.Conditions[1].Message != "cores of container container-1 reassigned from 2-3 to 4-5" {
+		t.Errorf("Expected latest reassignment, got %+v", status.Conditions[1])
+	}
+}
@@ -0,0 +1,155 @@
+package nodeagent
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	exporterNamespace = "rmd_container"
+	podKind           = "Pod"
+)
+
+// exporterLabels are the labels of all metrics reported by RdtExporter. Pod and container are empty for
+// RmdWorkloads not created from a pod spec.
+var exporterLabels = []string{"node", "namespace", "pod", "container", "rmdworkload"}
+
+var (
+	cacheWaysDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "cache_ways"),
+		"Cache ways allocated to the container per cache level and bound.",
+		append(exporterLabels, "level", "bound"), nil,
+	)
+	mbaPercentageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "mba_percentage"),
+		"Memory bandwidth allocation of the container in percent.",
+		exporterLabels, nil,
+	)
+	mbaMbpsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "mba_mbps"),
+		"Memory bandwidth allocation of the container in MBps.",
+		exporterLabels, nil,
+	)
+	pstateRatioDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "pstate_ratio"),
+		"P-State ratio configured for the container.",
+		exporterLabels, nil,
+	)
+	llcOccupancyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "llc_occupancy_bytes"),
+		"L3 cache occupancy of the container's resource group.",
+		exporterLabels, nil,
+	)
+	mbmLocalBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "mbm_local_bytes_total"),
+		"Local memory bandwidth counter of the container's resource group.",
+		exporterLabels, nil,
+	)
+	mbmTotalBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(exporterNamespace, "", "mbm_total_bytes_total"),
+		"Total memory bandwidth counter of the container's resource group.",
+		exporterLabels, nil,
+	)
+)
+
+// RdtExporter is a Prometheus collector reporting the RDT configuration and, where supported by the
+// platform, the cache and memory bandwidth usage of each RmdWorkload applied on its node. Metrics are
+// labelled by pod and container so they can be joined with other container metrics.
+type RdtExporter struct {
+	client      client.Client
+	nodeName    string
+	resctrlPath string
+}
+
+// blank assignment to verify that RdtExporter implements prometheus.Collector
+var _ prometheus.Collector = &RdtExporter{}
+
+// NewRdtExporter returns an RdtExporter for nodeName
+func NewRdtExporter(c client.Client, nodeName, resctrlPath string) *RdtExporter {
+	return &RdtExporter{
+		client:      c,
+		nodeName:    nodeName,
+		resctrlPath: resctrlPath,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (e *RdtExporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{cacheWaysDesc, mbaPercentageDesc, mbaMbpsDesc, pstateRatioDesc, llcOccupancyDesc, mbmLocalBytesDesc, mbmTotalBytesDesc} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (e *RdtExporter) Collect(ch chan<- prometheus.Metric) {
+	logger := log.WithName("RdtExporter")
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err := e.client.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		logger.Error(err, "Failed to list RmdWorkloads")
+		return
+	}
+	for i := range rmdWorkloads.Items {
+		rmdWorkload := &rmdWorkloads.Items[i]
+		workloadState, ok := rmdWorkload.Status.WorkloadStates[e.nodeName]
+		if !ok || workloadState.CosName == "" {
+			continue
+		}
+		podName, containerName := workloadContainer(rmdWorkload)
+		labels := []string{e.nodeName, rmdWorkload.GetObjectMeta().GetNamespace(), podName, containerName, rmdWorkload.GetObjectMeta().GetName()}
+
+		cache := workloadState.Rdt.Cache
+		ch <- prometheus.MustNewConstMetric(cacheWaysDesc, prometheus.GaugeValue, float64(cache.Max), append(labels, "l3", "max")...)
+		ch <- prometheus.MustNewConstMetric(cacheWaysDesc, prometheus.GaugeValue, float64(cache.Min), append(labels, "l3", "min")...)
+		if workloadState.Rdt.Mba.Percentage > 0 {
+			ch <- prometheus.MustNewConstMetric(mbaPercentageDesc, prometheus.GaugeValue, float64(workloadState.Rdt.Mba.Percentage), labels...)
+		}
+		if workloadState.Rdt.Mba.Mbps > 0 {
+			ch <- prometheus.MustNewConstMetric(mbaMbpsDesc, prometheus.GaugeValue, float64(workloadState.Rdt.Mba.Mbps), labels...)
+		}
+		if ratio, err := strconv.ParseFloat(workloadState.Plugins.Pstate.Ratio, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(pstateRatioDesc, prometheus.GaugeValue, ratio, labels...)
+		}
+
+		// Monitoring counters are only reported if resctrl monitoring is supported on the node.
+		counters, err := readMonCounters(e.resctrlPath, workloadState.CosName)
+		if err != nil {
+			continue
+		}
+		if value, ok := counters[llcOccupancyFile]; ok {
+			ch <- prometheus.MustNewConstMetric(llcOccupancyDesc, prometheus.GaugeValue, float64(value), labels...)
+		}
+		if value, ok := counters[mbmLocalBytesFile]; ok {
+			ch <- prometheus.MustNewConstMetric(mbmLocalBytesDesc, prometheus.CounterValue, float64(value), labels...)
+		}
+		if value, ok := counters[mbmTotalBytesFile]; ok {
+			ch <- prometheus.MustNewConstMetric(mbmTotalBytesDesc, prometheus.CounterValue, float64(value), labels...)
+		}
+	}
+}
+
+// workloadContainer returns the pod and container an RmdWorkload was created for by the pod controller.
+// Convention: "<pod-name>-rmd-workload-<container-name>", owned by the pod. The container is empty for
+// the RmdWorkload "<pod-name>-rmd-workload" shared by all containers of the pod.
+func workloadContainer(rmdWorkload *intelv1alpha1.RmdWorkload) (string, string) {
+	name := rmdWorkload.GetObjectMeta().GetName()
+	for _, owner := range rmdWorkload.GetObjectMeta().GetOwnerReferences() {
+		if owner.Kind != podKind {
+			continue
+		}
+		if name == owner.Name+podRmdWorkloadNameConst {
+			return owner.Name, ""
+		}
+		prefix := owner.Name + rmdWorkloadNameConst
+		if strings.HasPrefix(name, prefix) {
+			return owner.Name, strings.TrimPrefix(name, prefix)
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,187 @@
+package nodeagent
+
+import (
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"strings"
+	"testing"
+)
+
+const exporterHeader = `
+# HELP rmd_container_cache_ways Cache ways allocated to the container per cache level and bound.
+# TYPE rmd_container_cache_ways gauge
+`
+
+func TestRdtExporter(t *testing.T) {
+	tcases := []struct {
+		name        string
+		rmdWorkload *intelv1alpha1.RmdWorkload
+		groups      map[string]map[string]map[string]string
+		expected    string
+	}{
+		{
+			name: "test case 1 - pod workload with mba, pstate and monitoring",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1-rmd-workload-container-1",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{
+						{Kind: "Pod", Name: "pod-1"},
+					},
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node-1.com": {
+							CosName: "0-3-guarantee",
+							Rdt: intelv1alpha1.Rdt{
+								Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+								Mba:   intelv1alpha1.Mba{Percentage: 50},
+							},
+							Plugins: intelv1alpha1.Plugins{
+								Pstate: intelv1alpha1.Pstate{Ratio: "3.0"},
+							},
+						},
+					},
+				},
+			},
+			groups: map[string]map[string]map[string]string{
+				"0-3-guarantee": {
+					"mon_L3_00": {"llc_occupancy": "1000", "mbm_total_bytes": "3000"},
+				},
+			},
+			expected: exporterHeader + `
+rmd_container_cache_ways{bound="max",container="container-1",level="l3",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 2
+rmd_container_cache_ways{bound="min",container="container-1",level="l3",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 2
+# HELP rmd_container_llc_occupancy_bytes L3 cache occupancy of the container's resource group.
+# TYPE rmd_container_llc_occupancy_bytes gauge
+rmd_container_llc_occupancy_bytes{container="container-1",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 1000
+# HELP rmd_container_mba_percentage Memory bandwidth allocation of the container in percent.
+# TYPE rmd_container_mba_percentage gauge
+rmd_container_mba_percentage{container="container-1",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 50
+# HELP rmd_container_mbm_total_bytes_total Total memory bandwidth counter of the container's resource group.
+# TYPE rmd_container_mbm_total_bytes_total counter
+rmd_container_mbm_total_bytes_total{container="container-1",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 3000
+# HELP rmd_container_pstate_ratio P-State ratio configured for the container.
+# TYPE rmd_container_pstate_ratio gauge
+rmd_container_pstate_ratio{container="container-1",namespace="default",node="example-node-1.com",pod="pod-1",rmdworkload="pod-1-rmd-workload-container-1"} 3
+`,
+		},
+		{
+			name: "test case 2 - workload without pod, monitoring not supported",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node-1.com": {
+							CosName: "0-3-guarantee",
+							Rdt: intelv1alpha1.Rdt{
+								Cache: intelv1alpha1.Cache{Max: 2, Min: 1},
+							},
+						},
+					},
+				},
+			},
+			expected: exporterHeader + `
+rmd_container_cache_ways{bound="max",container="",level="l3",namespace="default",node="example-node-1.com",pod="",rmdworkload="rmd-workload-1"} 2
+rmd_container_cache_ways{bound="min",container="",level="l3",namespace="default",node="example-node-1.com",pod="",rmdworkload="rmd-workload-1"} 1
+`,
+		},
+		{
+			name: "test case 3 - workload applied on other node",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node-2.com": {
+							CosName: "0-3-guarantee",
+							Rdt: intelv1alpha1.Rdt{
+								Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+							},
+						},
+					},
+				},
+			},
+			expected: "",
+		},
+	}
+	for _, tc := range tcases {
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding scheme: (%v)", err)
+		}
+		cl := fake.NewFakeClient([]runtime.Object{tc.rmdWorkload}...)
+		resctrlPath := createResctrlTree(t, tc.groups)
+		exporter := NewRdtExporter(cl, "example-node-1.com", resctrlPath)
+		err := testutil.CollectAndCompare(exporter, strings.NewReader(tc.expected))
+		os.RemoveAll(resctrlPath)
+		if err != nil {
+			t.Errorf("%v failed: %v", tc.name, err)
+		}
+	}
+}
+
+func TestWorkloadContainer(t *testing.T) {
+	tcases := []struct {
+		name              string
+		rmdWorkload       *intelv1alpha1.RmdWorkload
+		expectedPod       string
+		expectedContainer string
+	}{
+		{
+			name: "test case 1 - workload owned by pod",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "pod-1-rmd-workload-container-1",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "Pod", Name: "pod-1"}},
+				},
+			},
+			expectedPod:       "pod-1",
+			expectedContainer: "container-1",
+		},
+		{
+			name: "test case 2 - pod workload owned by pod",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "pod-1-rmd-workload",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "Pod", Name: "pod-1"}},
+				},
+			},
+			expectedPod: "pod-1",
+		},
+		{
+			name: "test case 3 - workload not owned by pod",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-1-rmd-workload-container-1",
+				},
+			},
+		},
+		{
+			name: "test case 4 - workload name not following convention",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "rmd-workload-1",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "Pod", Name: "pod-1"}},
+				},
+			},
+		},
+	}
+	for _, tc := range tcases {
+		podName, containerName := workloadContainer(tc.rmdWorkload)
+		if podName != tc.expectedPod || containerName != tc.expectedContainer {
+			t.Errorf("%v failed: Expected %v and %v, got %v and %v", tc.name, tc.expectedPod, tc.expectedContainer, podName, containerName)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// DefaultResctrlPath is the resctrl mount point read by the workload monitor
+	DefaultResctrlPath    = "/sys/fs/resctrl"
+	rmdNodeStateNameConst = "rmd-node-state-"
+	pstateMonitoringKey   = "P-State Monitoring"
+	llcOccupancyFile      = "llc_occupancy"
+	mbmLocalBytesFile     = "mbm_local_bytes"
+	mbmTotalBytesFile     = "mbm_total_bytes"
+)
+
+// monData holds the resctrl monitoring counters of a resource group summed over all l3 cache domains
+type monData struct {
+	llcOccupancy  int64
+	mbmLocalBytes int64
+	mbmTotalBytes int64
+	timestamp     time.Time
+}
+
+// WorkloadMonitor periodically reports the cache and memory bandwidth usage of the RmdWorkloads applied
+// on its node. Usage is read from the resctrl mon_data of each workload's COS, P-State monitoring output
+// from the node's RmdNodeState.
+type WorkloadMonitor struct {
+	client      client.Client
+	nodeName    string
+	resctrlPath string
+	interval    time.Duration
+	// samples stores the last counters read per RmdWorkload to calculate bandwidth
+	samples map[types.NamespacedName]monData
+}
+
+// blank assignment to verify that WorkloadMonitor implements manager.Runnable
+var _ manager.Runnable = &WorkloadMonitor{}
+
+// NewWorkloadMonitor returns a WorkloadMonitor for nodeName reporting every interval
+func NewWorkloadMonitor(c client.Client, nodeName, resctrlPath string, interval time.Duration) *WorkloadMonitor {
+	return &WorkloadMonitor{
+		client:      c,
+		nodeName:    nodeName,
+		resctrlPath: resctrlPath,
+		interval:    interval,
+		samples:     make(map[types.NamespacedName]monData),
+	}
+}
+
+// Start runs the monitor until stop is closed
+func (m *WorkloadMonitor) Start(stop <-chan struct{}) error {
+	logger := log.WithName("WorkloadMonitor")
+	logger.Info("Starting workload monitor", "node", m.nodeName, "interval", m.interval)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			err := m.monitor()
+			if err != nil {
+				logger.Error(err, "Failed to monitor workloads")
+			}
+		}
+	}
+}
+
+// monitor updates the monitoring data of all RmdWorkloads applied on the monitor's node
+func (m *WorkloadMonitor) monitor() error {
+	logger := log.WithName("monitor")
+
+	rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+	err := m.client.List(context.TODO(), rmdWorkloads)
+	if err != nil {
+		return err
+	}
+
+	// P-State monitoring output is reported by RMD and recorded in the RmdNodeState.
+	rmdNodeState := &intelv1alpha1.RmdNodeState{}
+	err = m.client.Get(context.TODO(), types.NamespacedName{Name: fmt.Sprintf("%s%s", rmdNodeStateNameConst, m.nodeName), Namespace: defaultNamespace}, rmdNodeState)
+	if err != nil {
+		logger.Info("Could not get RmdNodeState, P-State monitoring not reported.", "Error:", err)
+	}
+
+	monitored := make(map[types.NamespacedName]bool)
+	for i := range rmdWorkloads.Items {
+		rmdWorkload := &rmdWorkloads.Items[i]
+		workloadState, ok := rmdWorkload.Status.WorkloadStates[m.nodeName]
+		if !ok || workloadState.CosName == "" {
+			continue
+		}
+		name := types.NamespacedName{Name: rmdWorkload.GetObjectMeta().GetName(), Namespace: rmdWorkload.GetObjectMeta().GetNamespace()}
+		current, err := readMonData(m.resctrlPath, workloadState.CosName)
+		if err != nil {
+			logger.Info("Could not read resctrl monitoring data.", "workload", name, "Error:", err)
+			continue
+		}
+		monitored[name] = true
+		previous, ok := m.samples[name]
+		m.samples[name] = current
+
+		monitoring := workloadMonitoring(current, previous, ok)
+		monitoring.Pstate = rmdNodeState.Status.Workloads[name.Name][pstateMonitoringKey]
+		if monitoringUnchanged(workloadState.Monitoring, monitoring) {
+			continue
+		}
+		// Only the monitoring data of the node is patched, leaving the rest of the status to the operator.
+		patch := client.MergeFrom(rmdWorkload.DeepCopy())
+		workloadState.Monitoring = monitoring
+		rmdWorkload.Status.WorkloadStates[m.nodeName] = workloadState
+		err = m.client.Status().Patch(context.TODO(), rmdWorkload, patch)
+		if err != nil {
+			// Report again on next interval.
+			logger.Info("Failed to update RmdWorkload monitoring data.", "workload", name, "Error:", err)
+		}
+	}
+
+	// Forget samples of workloads no longer applied on the node.
+	for name := range m.samples {
+		if !monitored[name] {
+			delete(m.samples, name)
+		}
+	}
+	return nil
+}
+
+// monitoringUnchanged returns true if current reports the same values as previous, regardless of the
+// time of the update
+func monitoringUnchanged(previous, current *intelv1alpha1.WorkloadMonitoring) bool {
+	if previous == nil {
+		return false
+	}
+	unchanged := *previous
+	unchanged.LastUpdate = current.LastUpdate
+	return reflect.DeepEqual(&unchanged, current)
+}
+
+// workloadMonitoring summarises the current counters. Bandwidth is calculated from the previous counters
+// and reported as 0 on the first sample or when a counter was reset.
+func workloadMonitoring(current, previous monData, hasPrevious bool) *intelv1alpha1.WorkloadMonitoring {
+	monitoring := &intelv1alpha1.WorkloadMonitoring{
+		LlcOccupancy: current.llcOccupancy,
+		LastUpdate:   metav1.NewTime(current.timestamp),
+	}
+	elapsed := current.timestamp.Sub(previous.timestamp).Seconds()
+	if !hasPrevious || elapsed <= 0 {
+		return monitoring
+	}
+	if current.mbmLocalBytes >= previous.mbmLocalBytes {
+		monitoring.LocalBandwidth = int64(float64(current.mbmLocalBytes-previous.mbmLocalBytes) / elapsed)
+	}
+	if current.mbmTotalBytes >= previous.mbmTotalBytes {
+		monitoring.TotalBandwidth = int64(float64(current.mbmTotalBytes-previous.mbmTotalBytes) / elapsed)
+	}
+	return monitoring
+}
+
+// readMonData reads the monitoring counters of resource group cosName from all mon_L3_* domains of
+// resctrlPath. Counters not supported by the platform are reported as 0.
+func readMonData(resctrlPath, cosName string) (monData, error) {
+	data := monData{timestamp: time.Now()}
+	counters, err := readMonCounters(resctrlPath, cosName)
+	if err != nil {
+		return data, err
+	}
+	data.llcOccupancy = counters[llcOccupancyFile]
+	data.mbmLocalBytes = counters[mbmLocalBytesFile]
+	data.mbmTotalBytes = counters[mbmTotalBytesFile]
+	return data, nil
+}
+
+// readMonCounters returns the monitoring counters of resource group cosName summed over all mon_L3_*
+// domains of resctrlPath, keyed by counter file. Counters not supported by the platform are omitted.
+func readMonCounters(resctrlPath, cosName string) (map[string]int64, error) {
+	domains, err := filepath.Glob(filepath.Join(resctrlPath, cosName, "mon_data", "mon_L3_*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no monitoring data found for resource group %s", cosName)
+	}
+	counters := make(map[string]int64)
+	for _, domain := range domains {
+		for _, file := range []string{llcOccupancyFile, mbmLocalBytesFile, mbmTotalBytesFile} {
+			value, err := readCounter(filepath.Join(domain, file))
+			if err != nil {
+				continue
+			}
+			counters[file] += value
+		}
+	}
+	return counters, nil
+}
+
+func readCounter(path string) (int64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	// resctrl reports "Unavailable" if the counter could not be read
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
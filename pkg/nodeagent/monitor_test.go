@@ -0,0 +1,286 @@
+package nodeagent
+
+import (
+	"context"
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+// createResctrlTree writes the mon_data counters of each resource group and l3 domain under a temporary
+// resctrl root and returns its path.
+func createResctrlTree(t *testing.T, groups map[string]map[string]map[string]string) string {
+	resctrlPath, err := ioutil.TempDir("", "resctrl")
+	if err != nil {
+		t.Fatalf("error creating resctrl tree: (%v)", err)
+	}
+	for cosName, domains := range groups {
+		for domain, counters := range domains {
+			domainPath := filepath.Join(resctrlPath, cosName, "mon_data", domain)
+			err = os.MkdirAll(domainPath, 0755)
+			if err != nil {
+				t.Fatalf("error creating resctrl tree: (%v)", err)
+			}
+			for file, value := range counters {
+				err = ioutil.WriteFile(filepath.Join(domainPath, file), []byte(value+"\n"), 0644)
+				if err != nil {
+					t.Fatalf("error creating resctrl tree: (%v)", err)
+				}
+			}
+		}
+	}
+	return resctrlPath
+}
+
+func TestReadMonData(t *testing.T) {
+	tcases := []struct {
+		name          string
+		groups        map[string]map[string]map[string]string
+		cosName       string
+		expectedData  monData
+		expectedError bool
+	}{
+		{
+			name: "test case 1 - single l3 domain",
+			groups: map[string]map[string]map[string]string{
+				"0-3-guarantee": {
+					"mon_L3_00": {"llc_occupancy": "1048576", "mbm_local_bytes": "2000", "mbm_total_bytes": "3000"},
+				},
+			},
+			cosName:      "0-3-guarantee",
+			expectedData: monData{llcOccupancy: 1048576, mbmLocalBytes: 2000, mbmTotalBytes: 3000},
+		},
+		{
+			name: "test case 2 - counters summed over l3 domains",
+			groups: map[string]map[string]map[string]string{
+				"0-3-guarantee": {
+					"mon_L3_00": {"llc_occupancy": "1000", "mbm_local_bytes": "2000", "mbm_total_bytes": "3000"},
+					"mon_L3_01": {"llc_occupancy": "500", "mbm_local_bytes": "100", "mbm_total_bytes": "200"},
+				},
+			},
+			cosName:      "0-3-guarantee",
+			expectedData: monData{llcOccupancy: 1500, mbmLocalBytes: 2100, mbmTotalBytes: 3200},
+		},
+		{
+			name: "test case 3 - MBM not supported",
+			groups: map[string]map[string]map[string]string{
+				"0-3-guarantee": {
+					"mon_L3_00": {"llc_occupancy": "1000", "mbm_total_bytes": "Unavailable"},
+				},
+			},
+			cosName:      "0-3-guarantee",
+			expectedData: monData{llcOccupancy: 1000},
+		},
+		{
+			name: "test case 4 - resource group not monitored",
+			groups: map[string]map[string]map[string]string{
+				"0-3-guarantee": {
+					"mon_L3_00": {"llc_occupancy": "1000"},
+				},
+			},
+			cosName:       "4-7-guarantee",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		resctrlPath := createResctrlTree(t, tc.groups)
+		data, err := readMonData(resctrlPath, tc.cosName)
+		os.RemoveAll(resctrlPath)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+			continue
+		}
+		data.timestamp = time.Time{}
+		if data != tc.expectedData {
+			t.Errorf("%v failed: Expected %+v, got %+v", tc.name, tc.expectedData, data)
+		}
+	}
+}
+
+func TestWorkloadMonitoring(t *testing.T) {
+	now := time.Now()
+	tcases := []struct {
+		name               string
+		current            monData
+		previous           monData
+		hasPrevious        bool
+		expectedMonitoring intelv1alpha1.WorkloadMonitoring
+	}{
+		{
+			name:               "test case 1 - first sample, no bandwidth",
+			current:            monData{llcOccupancy: 1000, mbmLocalBytes: 2000, mbmTotalBytes: 4000, timestamp: now},
+			expectedMonitoring: intelv1alpha1.WorkloadMonitoring{LlcOccupancy: 1000},
+		},
+		{
+			name:               "test case 2 - bandwidth over interval",
+			current:            monData{llcOccupancy: 1000, mbmLocalBytes: 22000, mbmTotalBytes: 44000, timestamp: now},
+			previous:           monData{llcOccupancy: 800, mbmLocalBytes: 2000, mbmTotalBytes: 4000, timestamp: now.Add(-10 * time.Second)},
+			hasPrevious:        true,
+			expectedMonitoring: intelv1alpha1.WorkloadMonitoring{LlcOccupancy: 1000, LocalBandwidth: 2000, TotalBandwidth: 4000},
+		},
+		{
+			name:               "test case 3 - counter reset",
+			current:            monData{llcOccupancy: 1000, mbmLocalBytes: 100, mbmTotalBytes: 44000, timestamp: now},
+			previous:           monData{llcOccupancy: 800, mbmLocalBytes: 2000, mbmTotalBytes: 4000, timestamp: now.Add(-10 * time.Second)},
+			hasPrevious:        true,
+			expectedMonitoring: intelv1alpha1.WorkloadMonitoring{LlcOccupancy: 1000, TotalBandwidth: 4000},
+		},
+	}
+	for _, tc := range tcases {
+		monitoring := workloadMonitoring(tc.current, tc.previous, tc.hasPrevious)
+		tc.expectedMonitoring.LastUpdate = metav1.NewTime(tc.current.timestamp)
+		if *monitoring != tc.expectedMonitoring {
+			t.Errorf("%v failed: Expected %+v, got %+v", tc.name, tc.expectedMonitoring, *monitoring)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	tcases := []struct {
+		name               string
+		rmdWorkload        *intelv1alpha1.RmdWorkload
+		rmdNodeState       *intelv1alpha1.RmdNodeState
+		expectedMonitoring bool
+		expectedPstate     string
+	}{
+		{
+			name: "test case 1 - workload applied on node",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node-1.com": {CosName: "0-3-guarantee", Status: "Successful"},
+					},
+				},
+			},
+			rmdNodeState: &intelv1alpha1.RmdNodeState{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-node-state-example-node-1.com",
+					Namespace: "default",
+				},
+				Status: intelv1alpha1.RmdNodeStateStatus{
+					Workloads: map[string]intelv1alpha1.WorkloadMap{
+						"rmd-workload-1": {"P-State Monitoring": "on"},
+					},
+				},
+			},
+			expectedMonitoring: true,
+			expectedPstate:     "on",
+		},
+		{
+			name: "test case 2 - workload applied on other node",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-workload-1",
+					Namespace: "default",
+				},
+				Status: intelv1alpha1.RmdWorkloadStatus{
+					WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+						"example-node-2.com": {CosName: "0-3-guarantee", Status: "Successful"},
+					},
+				},
+			},
+			rmdNodeState: &intelv1alpha1.RmdNodeState{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rmd-node-state-example-node-1.com",
+					Namespace: "default",
+				},
+			},
+			expectedMonitoring: false,
+		},
+	}
+	for _, tc := range tcases {
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding scheme: (%v)", err)
+		}
+		cl := fake.NewFakeClient([]runtime.Object{tc.rmdWorkload, tc.rmdNodeState}...)
+		resctrlPath := createResctrlTree(t, map[string]map[string]map[string]string{
+			"0-3-guarantee": {
+				"mon_L3_00": {"llc_occupancy": "1000", "mbm_local_bytes": "2000", "mbm_total_bytes": "3000"},
+			},
+		})
+		m := NewWorkloadMonitor(cl, "example-node-1.com", resctrlPath, time.Minute)
+		err := m.monitor()
+		os.RemoveAll(resctrlPath)
+		if err != nil {
+			t.Fatalf("%v failed: monitor returned error: (%v)", tc.name, err)
+		}
+
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = cl.Get(context.TODO(), types.NamespacedName{Name: "rmd-workload-1", Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Fatalf("%v failed: could not get RmdWorkload: (%v)", tc.name, err)
+		}
+		monitoring := rmdWorkload.Status.WorkloadStates["example-node-1.com"].Monitoring
+		if (monitoring != nil) != tc.expectedMonitoring {
+			t.Errorf("%v failed: Expected monitoring %v, got %v", tc.name, tc.expectedMonitoring, monitoring)
+			continue
+		}
+		if monitoring == nil {
+			continue
+		}
+		if monitoring.LlcOccupancy != 1000 || monitoring.Pstate != tc.expectedPstate {
+			t.Errorf("%v failed: Expected occupancy 1000 and pstate %v, got %+v", tc.name, tc.expectedPstate, monitoring)
+		}
+		if rmdWorkload.Status.WorkloadStates["example-node-1.com"].Status != "Successful" {
+			t.Errorf("%v failed: Expected workload state to be kept, got %+v", tc.name, rmdWorkload.Status.WorkloadStates["example-node-1.com"])
+		}
+	}
+}
+
+func TestMonitorUnchanged(t *testing.T) {
+	lastUpdate := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	rmdWorkload := &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rmd-workload-1",
+			Namespace: "default",
+		},
+		Status: intelv1alpha1.RmdWorkloadStatus{
+			WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+				"example-node-1.com": {
+					CosName:    "0-3-guarantee",
+					Status:     "Successful",
+					Monitoring: &intelv1alpha1.WorkloadMonitoring{LlcOccupancy: 1000, LastUpdate: lastUpdate},
+				},
+			},
+		},
+	}
+	s := scheme.Scheme
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatalf("error adding scheme: (%v)", err)
+	}
+	cl := fake.NewFakeClient(rmdWorkload)
+	resctrlPath := createResctrlTree(t, map[string]map[string]map[string]string{
+		"0-3-guarantee": {
+			"mon_L3_00": {"llc_occupancy": "1000", "mbm_local_bytes": "2000", "mbm_total_bytes": "3000"},
+		},
+	})
+	defer os.RemoveAll(resctrlPath)
+	m := NewWorkloadMonitor(cl, "example-node-1.com", resctrlPath, time.Minute)
+	err := m.monitor()
+	if err != nil {
+		t.Fatalf("monitor returned error: (%v)", err)
+	}
+
+	updated := &intelv1alpha1.RmdWorkload{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Name: "rmd-workload-1", Namespace: "default"}, updated)
+	if err != nil {
+		t.Fatalf("could not get RmdWorkload: (%v)", err)
+	}
+	if !updated.Status.WorkloadStates["example-node-1.com"].Monitoring.LastUpdate.Equal(&lastUpdate) {
+		t.Errorf("Expected unchanged monitoring data not to be written, got last update %v", updated.Status.WorkloadStates["example-node-1.com"].Monitoring.LastUpdate)
+	}
+}
@@ -3,17 +3,21 @@ package nodeagent
 import (
 	"context"
 	"fmt"
+	"os"
+	"reflect"
 	"strconv"
 	"strings"
 
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/cpudiscovery"
+	"github.com/intel/rmd-operator/pkg/podannotations"
 	"github.com/intel/rmd-operator/pkg/podresourcesclient"
-	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/client-go/tools/record"
 	v1qos "k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -25,15 +29,22 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+// NodeNameEnv is the environment variable holding the name of the node agent's node, set from
+// spec.nodeName via the downward API
+const NodeNameEnv = "NODE_NAME"
+
 const (
-	defaultNamespace      = "default"
-	rmdWorkloadNameConst  = "-rmd-workload-"
-	policyConst           = "policy"
-	pstateMonitoringConst = "pstate_monitoring"
-	pstateRatioConst      = "pstate_ratio"
-	mbaPercentageConst    = "mba_percentage"
-	mbaMbpsConst          = "mba_mbps"
-	l3Cache               = "intel.com/l3_cache_ways"
+	defaultNamespace        = "default"
+	rmdWorkloadNameConst    = "-rmd-workload-"
+	podRmdWorkloadNameConst = "-rmd-workload"
+	l3Cache                 = "intel.com/l3_cache_ways"
+	l3CacheBesteffort       = "intel.com/l3_cache_ways_besteffort"
+	invalidAnnotationEvent  = "InvalidRmdAnnotation"
+	invalidCacheEvent       = "InvalidCacheRequest"
+	invalidPodWorkloadEvent = "InvalidPodRmdWorkload"
+	guaranteedPool          = "guaranteed"
+	besteffortPool          = "besteffort"
+	sharedPool              = "shared"
 )
 
 var log = logf.Log.WithName("controller_pod")
@@ -41,6 +52,10 @@ var log = logf.Log.WithName("controller_pod")
 type containerInformation struct {
 	coreIDs  []string
 	maxCache int
+	// besteffortCache is the number of best-effort pool cache ways requested by the container
+	besteffortCache int
+	// cacheWays are the cache way devices allocated to the container by the device plugin
+	cacheWays []podresourcesclient.Device
 }
 
 /**
@@ -50,19 +65,20 @@ type containerInformation struct {
 
 // Add creates a new Pod Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+func Add(mgr manager.Manager, discoverer cpudiscovery.Discoverer) error {
+	return add(mgr, newReconciler(mgr, discoverer))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	logger := log.WithName("newReconciler")
-	podResourcesClient, err := podresourcesclient.NewPodResourcesClient()
-	if err != nil {
-		logger.Error(err, "unable to create podresources client")
-		return nil
+func newReconciler(mgr manager.Manager, discoverer cpudiscovery.Discoverer) reconcile.Reconciler {
+	return &ReconcilePod{
+		client:        mgr.GetClient(),
+		scheme:        mgr.GetScheme(),
+		recorder:      mgr.GetEventRecorderFor("rmd-node-agent"),
+		cpuDiscoverer: discoverer,
+		nodeName:      os.Getenv(NodeNameEnv),
+		nodeSysfsPath: DefaultNodeSysfsPath,
 	}
-	return &ReconcilePod{client: mgr.GetClient(), scheme: mgr.GetScheme(), podResourcesClient: podResourcesClient}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -99,9 +115,15 @@ var _ reconcile.Reconciler = &ReconcilePod{}
 type ReconcilePod struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client             client.Client
-	scheme             *runtime.Scheme
-	podResourcesClient *podresourcesclient.PodResourcesClient
+	client   client.Client
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	// cpuDiscoverer returns the CPUs and devices assigned to containers by the kubelet
+	cpuDiscoverer cpudiscovery.Discoverer
+	// nodeName is the node the node agent runs on
+	nodeName string
+	// nodeSysfsPath lists the NUMA nodes of the node and their CPUs
+	nodeSysfsPath string
 }
 
 // Reconcile reads that state of the cluster for a Pod object and makes changes based on the state read
@@ -127,33 +149,55 @@ func (r *ReconcilePod) Reconcile(request reconcile.Request) (reconcile.Result, e
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	// The Pod cache only holds Pods scheduled on the node agent's node, Pods passed to
+	// Reconcile from elsewhere are ignored.
+	if cachePod.Spec.NodeName != r.nodeName {
+		reqLogger.Info("Pod does not belong to same node as node agent")
+		return reconcile.Result{}, nil
+	}
+
+	// Pods that ran to completion release the cache of all of their containers.
+	if cachePod.Status.Phase == corev1.PodSucceeded || cachePod.Status.Phase == corev1.PodFailed {
+		reqLogger.Info("Pod terminated, delete its RmdWorkloads", "pod status:", cachePod.Status.Phase)
+		return reconcile.Result{}, r.deleteRmdWorkloadsExcept(cachePod, nil)
+	}
 	podNotRunningErr := errors.NewServiceUnavailable("pod not in running phase")
 	if cachePod.Status.Phase != corev1.PodRunning {
 		reqLogger.Info("Pod not running", "pod status:", cachePod.Status.Phase)
 		return reconcile.Result{}, podNotRunningErr
 	}
 
-	// Get node-agent pod to compare host node with cachePod
-	nodeAgentPod, err := r.getNodeAgentPod()
+	// Terminated containers, such as finished init containers, release their cache. The
+	// RmdWorkload of a container is created again once the container is restarted.
+	err = r.deleteRmdWorkloads(cachePod, getTerminatedContainers(cachePod))
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-	if cachePod.Status.HostIP != nodeAgentPod.Status.HostIP {
-		reqLogger.Info("Pod does not belong to same node as node agent")
-		return reconcile.Result{}, nil
-	}
 
 	rmdWorkloads, err := r.buildRmdWorkload(cachePod)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	// RmdWorkloads no longer built for the pod, e.g. after switching to or from a pod RmdWorkload,
+	// release their cache.
+	err = r.deleteRmdWorkloadsExcept(cachePod, rmdWorkloads)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
 	if len(rmdWorkloads) == 0 {
+		if hasRmdAppliedGate(cachePod) {
+			return reconcile.Result{}, r.setRmdAppliedCondition(cachePod, rmdWorkloads)
+		}
 		return reconcile.Result{}, nil
 	}
-	for _, rmdWorkload := range rmdWorkloads {
+	for i, rmdWorkload := range rmdWorkloads {
 		rmdWorkloadName := rmdWorkload.GetObjectMeta().GetName()
+		// Status is not written on create, the allocations are patched once the RmdWorkload exists.
+		cacheWayAllocations := rmdWorkload.Status.CacheWayAllocations
+		rmdWorkload.Status.CacheWayAllocations = nil
+		existingWorkload := &intelv1alpha1.RmdWorkload{}
 		err = r.client.Get(context.TODO(), types.NamespacedName{
-			Name: rmdWorkloadName, Namespace: request.Namespace}, rmdWorkload)
+			Name: rmdWorkloadName, Namespace: request.Namespace}, existingWorkload)
 
 		if err != nil {
 			if errors.IsNotFound(err) {
@@ -168,53 +212,41 @@ func (r *ReconcilePod) Reconcile(request reconcile.Request) (reconcile.Result, e
 					reqLogger.Error(err, "Failed to create rmdWorkload")
 					return reconcile.Result{}, err
 				}
+				err = r.setCacheWayAllocations(cachePod, rmdWorkload, cacheWayAllocations)
+				if err != nil {
+					return reconcile.Result{}, err
+				}
 				// Continue to next workload
 				continue
 			}
 			return reconcile.Result{}, err
 		}
-		// RmdWorkload found, update it.
-		err = r.client.Update(context.TODO(), rmdWorkload)
+		// RmdWorkload found, update it with the spec built from the pod. The existing RmdWorkload
+		// keeps its status, which is used for the readiness condition of the pod.
+		rmdWorkloads[i] = existingWorkload
+		if !reflect.DeepEqual(existingWorkload.Spec, rmdWorkload.Spec) {
+			reqLogger.Info("Update workload for pod container requesting cache", "workload name", rmdWorkloadName)
+			existingWorkload.Spec = rmdWorkload.Spec
+			err = r.client.Update(context.TODO(), existingWorkload)
+			if err != nil {
+				reqLogger.Error(err, "Failed to update rmdWorkload")
+				return reconcile.Result{}, err
+			}
+		}
+		err = r.setCacheWayAllocations(cachePod, existingWorkload, cacheWayAllocations)
 		if err != nil {
-			reqLogger.Error(err, "Failed to update rmdWorkload")
 			return reconcile.Result{}, err
 		}
 	}
-	return reconcile.Result{}, nil
-}
 
-func (r *ReconcilePod) getNodeAgentPod() (*corev1.Pod, error) {
-	logger := log.WithName("getNodeAgentPod")
-	namespaceList := &corev1.NamespaceList{}
-	err := r.client.List(context.TODO(), namespaceList)
-	if err != nil {
-		logger.Error(err, "Failed to list namespaces")
-		return nil, err
-	}
-	nodeAgentPod := &corev1.Pod{}
-	for _, namespace := range namespaceList.Items {
-		namespaceName := namespace.GetObjectMeta().GetName()
-		if namespaceName == core.NamespaceSystem {
-			continue
-		}
-		if namespaceName == core.NamespacePublic {
-			continue
-		}
-		if namespaceName == core.NamespaceNodeLease {
-			continue
-		}
-		nodeAgentPod, err = k8sutil.GetPod(context.TODO(), r.client, namespaceName)
+	// The pod is reconciled again whenever the operator updates the status of its RmdWorkloads.
+	if hasRmdAppliedGate(cachePod) {
+		err = r.setRmdAppliedCondition(cachePod, rmdWorkloads)
 		if err != nil {
-			if errors.IsNotFound(err) {
-				logger.Info("attempt discovery in another namespace")
-				continue
-			}
-			return nil, err
+			return reconcile.Result{}, err
 		}
-		break
 	}
-	logger.Info("returning with node agent pod", "nodeAgentPod", nodeAgentPod)
-	return nodeAgentPod, nil
+	return reconcile.Result{}, nil
 }
 
 func (r *ReconcilePod) buildRmdWorkload(pod *corev1.Pod) ([]*intelv1alpha1.RmdWorkload, error) {
@@ -226,13 +258,23 @@ func (r *ReconcilePod) buildRmdWorkload(pod *corev1.Pod) ([]*intelv1alpha1.RmdWo
 		logger.Info("No container requesting cache found in pod")
 		return nil, nil
 	}
+	mode, err := podannotations.WorkloadMode(pod)
+	if err != nil {
+		logger.Info("Invalid RMD annotation ignored", "pod", pod.GetObjectMeta().GetName(), "error", err.Error())
+		r.recorder.Event(pod, corev1.EventTypeWarning, invalidAnnotationEvent, err.Error())
+	}
 	rmdWorkloads := make([]*intelv1alpha1.RmdWorkload, 0)
+	containerNames := make([]string, 0)
+	containerInfos := make([]containerInformation, 0)
 	for _, container := range containersRequestingCache {
 		// Container name should NOT contain "-rmd-workload-" substring.
 		if strings.Contains(container.Name, rmdWorkloadNameConst) {
 			logger.Info("Container name must NOT contain '-rmd-workload-' substring.", "Workload will not be created for pod", pod.GetObjectMeta().GetName(), "container", container.Name)
 			continue
 		}
+		if containerTerminated(pod, container.Name) {
+			continue
+		}
 
 		containerInfo, err := r.getContainerInfo(pod, container)
 		if err != nil {
@@ -240,67 +282,226 @@ func (r *ReconcilePod) buildRmdWorkload(pod *corev1.Pod) ([]*intelv1alpha1.RmdWo
 		}
 
 		rmdWorkload := &intelv1alpha1.RmdWorkload{}
-		//Create workload name. Convention: "<pod-name>rmd-workload-<container-name>"
-		podName := string(pod.GetObjectMeta().GetName())
-		rmdWorkloadName := fmt.Sprintf("%s%s%s", podName, rmdWorkloadNameConst, container.Name)
-		podNamespace := pod.GetObjectMeta().GetNamespace()
-		if podNamespace == "" {
-			podNamespace = defaultNamespace
-		}
-		rmdWorkloadNamespacedName := types.NamespacedName{
-			Name:      rmdWorkloadName,
-			Namespace: podNamespace,
-		}
+		rmdWorkloadNamespacedName := getRmdWorkloadNamespacedName(pod, container.Name)
 
 		rmdWorkload.SetName(rmdWorkloadNamespacedName.Name)
 		rmdWorkload.SetNamespace(rmdWorkloadNamespacedName.Namespace)
 		rmdWorkload.Spec.Rdt.Cache.Max = containerInfo.maxCache
 		rmdWorkload.Spec.Rdt.Cache.Min = containerInfo.maxCache
+		if containerInfo.maxCache == 0 && containerInfo.besteffortCache != 0 {
+			// The max of best-effort cache is set with annotations.
+			rmdWorkload.Spec.Rdt.Cache.Min = containerInfo.besteffortCache
+		}
 		rmdWorkload.Spec.CoreIds = containerInfo.coreIDs
 		rmdWorkload.Spec.Nodes = make([]string, 0)
 		rmdWorkload.Spec.Nodes = append(rmdWorkload.Spec.Nodes, pod.Spec.NodeName)
 		rmdWorkload.Spec.NodeSelector = make(map[string]string)
 
-		getAnnotationInfo(rmdWorkload, pod, container.Name) //Changes workload in getAnnotationInfo()
+		for _, err := range getAnnotationInfo(rmdWorkload, pod, container.Name) {
+			logger.Info("Invalid RMD annotation ignored", "pod", pod.GetObjectMeta().GetName(), "container", container.Name, "error", err.Error())
+			r.recorder.Event(pod, corev1.EventTypeWarning, invalidAnnotationEvent, err.Error())
+		}
+		if err := checkCacheWays(rmdWorkload, containerInfo); err != nil {
+			logger.Info("Invalid cache request, workload will not be created", "pod", pod.GetObjectMeta().GetName(), "container", container.Name, "error", err.Error())
+			r.recorder.Event(pod, corev1.EventTypeWarning, invalidCacheEvent, fmt.Sprintf("container %s: %v", container.Name, err))
+			continue
+		}
+		rmdWorkload.Status.CacheWayAllocations = r.getCacheWayAllocations(containerInfo)
 
 		rmdWorkloads = append(rmdWorkloads, rmdWorkload)
+		containerNames = append(containerNames, container.Name)
+		containerInfos = append(containerInfos, containerInfo)
+	}
+	if mode == podannotations.PodWorkloadMode && len(rmdWorkloads) != 0 {
+		rmdWorkload, err := r.mergeRmdWorkloads(pod, rmdWorkloads, containerNames, containerInfos)
+		if err != nil {
+			logger.Info("Invalid pod workload request, workload will not be created", "pod", pod.GetObjectMeta().GetName(), "error", err.Error())
+			r.recorder.Event(pod, corev1.EventTypeWarning, invalidPodWorkloadEvent, err.Error())
+			return []*intelv1alpha1.RmdWorkload{}, nil
+		}
+		return []*intelv1alpha1.RmdWorkload{rmdWorkload}, nil
 	}
 	return rmdWorkloads, nil
 }
 
-func getAnnotationInfo(rmdWorkload *intelv1alpha1.RmdWorkload, pod *corev1.Pod, containerName string) {
-	workloadData := pod.GetObjectMeta().GetAnnotations()
-	for field, data := range workloadData {
-		if !strings.HasPrefix(field, containerName) {
+// mergeRmdWorkloads combines the RmdWorkloads of the containers of pod into a single RmdWorkload with
+// the cores of all containers and their summed cache. Containers without exclusive CPUs are left out.
+// The containers must request cache from the same RMD pools and the same policy, MBA and P-State
+// settings as these apply to all cores of the RmdWorkload.
+func (r *ReconcilePod) mergeRmdWorkloads(pod *corev1.Pod, rmdWorkloads []*intelv1alpha1.RmdWorkload, containerNames []string, containerInfos []containerInformation) (*intelv1alpha1.RmdWorkload, error) {
+	podWorkload := &intelv1alpha1.RmdWorkload{}
+	podWorkloadNamespacedName := getRmdWorkloadNamespacedName(pod, "")
+	podWorkload.SetName(podWorkloadNamespacedName.Name)
+	podWorkload.SetNamespace(podWorkloadNamespacedName.Namespace)
+	podWorkload.Spec.Nodes = []string{pod.Spec.NodeName}
+	podWorkload.Spec.NodeSelector = make(map[string]string)
+
+	podInfo := containerInformation{}
+	var first *intelv1alpha1.RmdWorkload
+	firstContainer := ""
+	for i, rmdWorkload := range rmdWorkloads {
+		if len(containerInfos[i].coreIDs) == 0 {
 			continue
 		}
-		switch {
-		case strings.HasSuffix(field, policyConst):
-			if data != "" {
-				rmdWorkload.Spec.Policy = data
-			}
-		case strings.HasSuffix(field, mbaPercentageConst):
-			mbaPercentage, err := strconv.Atoi(data)
-			if err != nil {
-				continue
-			}
-			rmdWorkload.Spec.Rdt.Mba.Percentage = mbaPercentage
-		case strings.HasSuffix(field, mbaMbpsConst):
-			mbaMbps, err := strconv.Atoi(data)
-			if err != nil {
-				continue
-			}
-			rmdWorkload.Spec.Rdt.Mba.Mbps = mbaMbps
-		case strings.HasSuffix(field, pstateRatioConst):
-			if data != "" {
-				rmdWorkload.Spec.Plugins.Pstate.Ratio = data
-			}
-		case strings.HasSuffix(field, pstateMonitoringConst):
-			if data != "" {
-				rmdWorkload.Spec.Plugins.Pstate.Monitoring = data
-			}
+		if first == nil {
+			first = rmdWorkload
+			firstContainer = containerNames[i]
+			podWorkload.Spec.Policy = rmdWorkload.Spec.Policy
+			podWorkload.Spec.Rdt.Mba = rmdWorkload.Spec.Rdt.Mba
+			podWorkload.Spec.Plugins = rmdWorkload.Spec.Plugins
+		}
+		if pool, firstPool := cachePool(rmdWorkload.Spec.Rdt.Cache.Max, rmdWorkload.Spec.Rdt.Cache.Min), cachePool(first.Spec.Rdt.Cache.Max, first.Spec.Rdt.Cache.Min); pool != firstPool {
+			return nil, fmt.Errorf("container %s requests l3 cache from the %s pool, container %s from the %s pool", containerNames[i], pool, firstContainer, firstPool)
+		}
+		if rmdWorkload.Spec.Policy != first.Spec.Policy ||
+			!reflect.DeepEqual(rmdWorkload.Spec.Rdt.Mba, first.Spec.Rdt.Mba) ||
+			!reflect.DeepEqual(rmdWorkload.Spec.Plugins, first.Spec.Plugins) {
+			return nil, fmt.Errorf("container %s requests policy, MBA or P-State settings differing from container %s", containerNames[i], firstContainer)
+		}
+
+		podWorkload.Spec.CoreIds = append(podWorkload.Spec.CoreIds, rmdWorkload.Spec.CoreIds...)
+		podWorkload.Spec.Rdt.Cache.Max += rmdWorkload.Spec.Rdt.Cache.Max
+		podWorkload.Spec.Rdt.Cache.Min += rmdWorkload.Spec.Rdt.Cache.Min
+		podInfo.coreIDs = append(podInfo.coreIDs, containerInfos[i].coreIDs...)
+		podInfo.cacheWays = append(podInfo.cacheWays, containerInfos[i].cacheWays...)
+	}
+	if first == nil {
+		return nil, fmt.Errorf("no container requesting cache has exclusive CPUs")
+	}
+	podWorkload.Status.CacheWayAllocations = r.getCacheWayAllocations(podInfo)
+	return podWorkload, nil
+}
+
+// getRmdWorkloadNamespacedName returns the name of the RmdWorkload of a container of pod, or of the
+// RmdWorkload shared by all containers of pod if containerName is empty.
+// Convention: "<pod-name>-rmd-workload-<container-name>", or "<pod-name>-rmd-workload", in the
+// namespace of the pod.
+func getRmdWorkloadNamespacedName(pod *corev1.Pod, containerName string) types.NamespacedName {
+	podName := string(pod.GetObjectMeta().GetName())
+	podNamespace := pod.GetObjectMeta().GetNamespace()
+	if podNamespace == "" {
+		podNamespace = defaultNamespace
+	}
+	name := fmt.Sprintf("%s%s%s", podName, rmdWorkloadNameConst, containerName)
+	if containerName == "" {
+		name = podName + podRmdWorkloadNameConst
+	}
+	return types.NamespacedName{
+		Name:      name,
+		Namespace: podNamespace,
+	}
+}
+
+// deleteRmdWorkloads deletes the RmdWorkloads of containers created for pod.
+func (r *ReconcilePod) deleteRmdWorkloads(pod *corev1.Pod, containers []corev1.Container) error {
+	for _, container := range containers {
+		err := r.deleteRmdWorkload(pod, getRmdWorkloadNamespacedName(pod, container.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRmdWorkloadsExcept deletes the RmdWorkloads created for pod, its containers' and its shared
+// RmdWorkload, other than rmdWorkloads.
+func (r *ReconcilePod) deleteRmdWorkloadsExcept(pod *corev1.Pod, rmdWorkloads []*intelv1alpha1.RmdWorkload) error {
+	keep := make(map[string]bool)
+	for _, rmdWorkload := range rmdWorkloads {
+		keep[rmdWorkload.GetObjectMeta().GetName()] = true
+	}
+	names := []types.NamespacedName{getRmdWorkloadNamespacedName(pod, "")}
+	for _, container := range getContainersRequestingCache(pod) {
+		names = append(names, getRmdWorkloadNamespacedName(pod, container.Name))
+	}
+	for _, name := range names {
+		if keep[name.Name] {
+			continue
+		}
+		err := r.deleteRmdWorkload(pod, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRmdWorkload deletes the RmdWorkload name created for pod. RmdWorkloads not controlled by pod
+// are left untouched.
+func (r *ReconcilePod) deleteRmdWorkload(pod *corev1.Pod, name types.NamespacedName) error {
+	logger := log.WithName("deleteRmdWorkload")
+	rmdWorkload := &intelv1alpha1.RmdWorkload{}
+	err := r.client.Get(context.TODO(), name, rmdWorkload)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !metav1.IsControlledBy(rmdWorkload, pod) {
+		return nil
+	}
+	logger.Info("Delete workload released by pod", "workload name", rmdWorkload.GetObjectMeta().GetName())
+	err = r.client.Delete(context.TODO(), rmdWorkload)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Failed to delete rmdWorkload")
+		return err
+	}
+	return nil
+}
+
+// getTerminatedContainers returns the containers of pod requesting cache that have terminated
+func getTerminatedContainers(pod *corev1.Pod) []corev1.Container {
+	terminatedContainers := make([]corev1.Container, 0)
+	for _, container := range getContainersRequestingCache(pod) {
+		if containerTerminated(pod, container.Name) {
+			terminatedContainers = append(terminatedContainers, container)
+		}
+	}
+	return terminatedContainers
+}
+
+// containerTerminated returns true if the container has terminated and has not been restarted yet.
+// A container waiting to be restarted after terminating is not running either.
+func containerTerminated(pod *corev1.Pod, containerName string) bool {
+	for _, containerStatus := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if containerStatus.Name != containerName {
+			continue
+		}
+		if containerStatus.State.Terminated != nil {
+			return true
 		}
+		return containerStatus.State.Waiting != nil && containerStatus.LastTerminationState.Terminated != nil
+	}
+	return false
+}
+
+// getAnnotationInfo sets the RMD settings requested for containerName in the pod annotations on
+// rmdWorkload. Invalid annotations are skipped and returned as errors.
+func getAnnotationInfo(rmdWorkload *intelv1alpha1.RmdWorkload, pod *corev1.Pod, containerName string) []error {
+	settings, errs := podannotations.ForContainer(pod, containerName)
+	if settings.Policy != nil {
+		rmdWorkload.Spec.Policy = *settings.Policy
+	}
+	if settings.MbaPercentage != nil {
+		rmdWorkload.Spec.Rdt.Mba.Percentage = *settings.MbaPercentage
+	}
+	if settings.MbaMbps != nil {
+		rmdWorkload.Spec.Rdt.Mba.Mbps = *settings.MbaMbps
+	}
+	if settings.PstateRatio != nil {
+		rmdWorkload.Spec.Plugins.Pstate.Ratio = *settings.PstateRatio
 	}
+	if settings.PstateMonitoring != nil {
+		rmdWorkload.Spec.Plugins.Pstate.Monitoring = *settings.PstateMonitoring
+	}
+	if settings.L3CacheMax != nil {
+		rmdWorkload.Spec.Rdt.Cache.Max = *settings.L3CacheMax
+	}
+	if settings.L3CacheMin != nil {
+		rmdWorkload.Spec.Rdt.Cache.Min = *settings.L3CacheMin
+	}
+	return errs
 }
 
 func (r *ReconcilePod) getContainerInfo(pod *corev1.Pod, container corev1.Container) (containerInformation, error) {
@@ -317,31 +518,54 @@ func (r *ReconcilePod) getContainerInfo(pod *corev1.Pod, container corev1.Contai
 		return containerInformation{}, errors.NewServiceUnavailable("pod UID not found")
 	}
 
-	coreIDs, err := r.podResourcesClient.GetContainerCPUs(pod.GetObjectMeta().GetName(), container.Name)
+	resources, err := r.cpuDiscoverer.GetContainerResources(pod.GetObjectMeta().GetNamespace(), pod.GetObjectMeta().GetName(), container.Name)
 	if err != nil {
-		logger.Error(err, "failed to access coreIDs from kubelet podresources endpoint")
+		logger.Error(err, "failed to discover coreIDs of container")
 		return containerInformation{}, err
 	}
+	coreIDs := resources.CPUs
 	if len(coreIDs) == 0 {
 		logger.Info("coreIDs list for container is empty")
 		return containerInformation{}, errors.NewServiceUnavailable("coreIDs list for container is empty")
 	}
 
 	containerInfo.coreIDs = coreIDs
+	containerInfo.cacheWays = append(containerInfo.cacheWays, resources.Devices[l3Cache]...)
+	containerInfo.cacheWays = append(containerInfo.cacheWays, resources.Devices[l3CacheBesteffort]...)
 
 	containerInfo.maxCache, err = getMaxCache(&container)
 	if err != nil {
 		return containerInformation{}, err
 	}
+	containerInfo.besteffortCache, err = getCacheLimit(&container, l3CacheBesteffort)
+	if err != nil {
+		return containerInformation{}, err
+	}
 	return containerInfo, nil
 }
 
+// getCacheWayAllocations aligns the cache ways allocated to a container with its cores. Allocations
+// are only returned for containers with exclusive CPUs on nodes whose NUMA topology can be read.
+func (r *ReconcilePod) getCacheWayAllocations(containerInfo containerInformation) []intelv1alpha1.CacheWayAllocation {
+	logger := log.WithName("getCacheWayAllocations")
+	if len(containerInfo.coreIDs) == 0 || len(containerInfo.cacheWays) == 0 {
+		return nil
+	}
+	nodeCPUs, err := readNumaNodeCPUs(r.nodeSysfsPath)
+	if err != nil {
+		logger.Info("Could not read NUMA nodes, cache way alignment not checked.", "Error:", err)
+		return nil
+	}
+	return cacheWayAllocations(containerInfo.coreIDs, containerInfo.cacheWays, nodeCPUs)
+}
+
 func getContainersRequestingCache(pod *corev1.Pod) []corev1.Container {
 	containersRequestingCache := make([]corev1.Container, 0)
 	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
 		for resourceName := range container.Resources.Limits {
-			if resourceName.String() == l3Cache {
+			if resourceName.String() == l3Cache || resourceName.String() == l3CacheBesteffort {
 				containersRequestingCache = append(containersRequestingCache, container)
+				break
 			}
 		}
 	}
@@ -349,8 +573,13 @@ func getContainersRequestingCache(pod *corev1.Pod) []corev1.Container {
 }
 
 func getMaxCache(container *corev1.Container) (int, error) {
+	return getCacheLimit(container, l3Cache)
+}
+
+// getCacheLimit returns the number of cache ways of resourceName requested by container
+func getCacheLimit(container *corev1.Container, cacheResourceName string) (int, error) {
 	for resourceName, limit := range container.Resources.Limits {
-		if resourceName.String() == l3Cache {
+		if resourceName.String() == cacheResourceName {
 			limitInt, err := strconv.Atoi(limit.String())
 			if err != nil {
 				return 0, err
@@ -361,6 +590,43 @@ func getMaxCache(container *corev1.Container) (int, error) {
 	return 0, nil
 }
 
+// checkCacheWays verifies that the l3 cache max and min of rmdWorkload are covered by the cache ways
+// requested by the container from the RMD pool they are allocated from. Shared pool requests take no
+// ways, guaranteed requests take max ways and best-effort requests min ways.
+func checkCacheWays(rmdWorkload *intelv1alpha1.RmdWorkload, containerInfo containerInformation) error {
+	maxCache := rmdWorkload.Spec.Rdt.Cache.Max
+	minCache := rmdWorkload.Spec.Rdt.Cache.Min
+	switch cachePool(maxCache, minCache) {
+	case sharedPool:
+		return nil
+	case guaranteedPool:
+		if maxCache > containerInfo.maxCache {
+			return fmt.Errorf("%d guaranteed l3 cache ways requested, %d %s in resource limits", maxCache, containerInfo.maxCache, l3Cache)
+		}
+		return nil
+	case besteffortPool:
+		if minCache > containerInfo.besteffortCache {
+			return fmt.Errorf("%d best-effort l3 cache ways requested, %d %s in resource limits", minCache, containerInfo.besteffortCache, l3CacheBesteffort)
+		}
+		return nil
+	}
+	return fmt.Errorf("l3 cache max %d and min %d not supported, best-effort cache needs a max above the min", maxCache, minCache)
+}
+
+// cachePool returns the RMD pool cache of maxCache and minCache ways is allocated from, or an empty
+// string if no pool supports them
+func cachePool(maxCache, minCache int) string {
+	switch {
+	case maxCache == 0 && minCache == 0:
+		return sharedPool
+	case maxCache == minCache:
+		return guaranteedPool
+	case maxCache > minCache && minCache != 0:
+		return besteffortPool
+	}
+	return ""
+}
+
 func exclusiveCPUs(pod *corev1.Pod, container *corev1.Container) bool {
 	if v1qos.GetPodQOS(pod) != corev1.PodQOSGuaranteed {
 		return false
@@ -4,15 +4,17 @@ import (
 	"context"
 	"github.com/intel/rmd-operator/pkg/apis"
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/podresourcesclient"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
-	"os"
+	"k8s.io/client-go/tools/record"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"testing"
 )
 
@@ -35,7 +37,7 @@ func createReconcilePodObject(pod *corev1.Pod) (*ReconcilePod, error) {
 	cl := fake.NewFakeClient(objs...)
 
 	// Create a ReconcileNode object with the scheme and fake client.
-	r := &ReconcilePod{client: cl, scheme: s}
+	r := &ReconcilePod{client: cl, scheme: s, recorder: record.NewFakeRecorder(10)}
 
 	return r, nil
 
@@ -47,6 +49,7 @@ func TestGetAnnotationInfo(t *testing.T) {
 		pod              *corev1.Pod
 		containerName    string
 		expectedWorkload *intelv1alpha1.RmdWorkload
+		expectedErrors   int
 	}{
 		{
 			name:        "test case 1 - no errors, single container containing all annotations",
@@ -214,6 +217,7 @@ func TestGetAnnotationInfo(t *testing.T) {
 					},
 				},
 			},
+			expectedErrors: 1,
 		},
 		{
 			name:        "test case 4 - incorrect type for max cache",
@@ -318,6 +322,7 @@ func TestGetAnnotationInfo(t *testing.T) {
 					},
 				},
 			},
+			expectedErrors: 1,
 		},
 		{
 			name:        "test case 5 - incorrect type for MBA Mbps",
@@ -373,6 +378,7 @@ func TestGetAnnotationInfo(t *testing.T) {
 					},
 				},
 			},
+			expectedErrors: 1,
 		},
 		{
 			name:        "test case 6 - annotations missing container name",
@@ -421,13 +427,181 @@ func TestGetAnnotationInfo(t *testing.T) {
 			containerName:    "nginx1",
 			expectedWorkload: &intelv1alpha1.RmdWorkload{},
 		},
+		{
+			name:        "test case 7 - legacy l2 cache annotations ignored",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					UID:       "f906a249-ab9d-4180-9afa-4075e2058ac7",
+					Annotations: map[string]string{
+						"nginx1_l2_cache_max": "4",
+						"nginx2_l2_cache_max": "6",
+						"nginx2_l2_cache_min": "2",
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "example-node-1.com",
+					Containers: []corev1.Container{
+						{
+							Name: "nginx1",
+						},
+						{
+							Name: "nginx2",
+						},
+					},
+				},
+			},
+			containerName:    "nginx1",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{},
+		},
+		{
+			name:        "test case 8 - namespaced l2 cache annotations returned as errors",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					UID:       "f906a249-ab9d-4180-9afa-4075e2058ac7",
+					Annotations: map[string]string{
+						"rmd.intel.com/nginx2.l2-cache-max": "6",
+						"rmd.intel.com/nginx2.l2-cache-min": "2",
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "example-node-1.com",
+					Containers: []corev1.Container{
+						{
+							Name: "nginx2",
+						},
+					},
+				},
+			},
+			containerName:    "nginx2",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{},
+			expectedErrors:   2,
+		},
+		{
+			name:        "test case 9 - annotations of container with name prefix ignored",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"app_policy":                       "gold",
+						"application_policy":               "silver",
+						"rmd.intel.com/application.policy": "bronze",
+					},
+				},
+			},
+			containerName: "app",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					Policy: "gold",
+				},
+			},
+		},
+		{
+			name:        "test case 10 - namespaced and pod-level annotations override legacy annotations",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"nginx1_policy":                     "gold",
+						"nginx1_mba_percentage":             "70",
+						"rmd.intel.com/containers":          `{"nginx1": {"mbaPercentage": 50, "l3CacheMax": 4}, "nginx2": {"policy": "silver"}}`,
+						"rmd.intel.com/nginx1.l3-cache-max": "6",
+						"rmd.intel.com/nginx1.pstate-ratio": "1.5",
+					},
+				},
+			},
+			containerName: "nginx1",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					Policy: "gold",
+					Rdt: intelv1alpha1.Rdt{
+						Mba: intelv1alpha1.Mba{
+							Percentage: 50,
+						},
+						Cache: intelv1alpha1.Cache{
+							Max: 6,
+						},
+					},
+					Plugins: intelv1alpha1.Plugins{
+						Pstate: intelv1alpha1.Pstate{
+							Ratio: "1.5",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "test case 11 - invalid annotations returned as errors",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"nginx1_mba_percentage":               "seventy",
+						"nginx1_mba_mbps":                     "100",
+						"rmd.intel.com/nginx1.mba-percentage": "200",
+						"rmd.intel.com/nginx1.cache-max":      "2",
+						"rmd.intel.com/containers":            `{"nginx1": {"mbaMbps": -1}}`,
+					},
+				},
+			},
+			containerName: "nginx1",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					Rdt: intelv1alpha1.Rdt{
+						Mba: intelv1alpha1.Mba{
+							Mbps: 100,
+						},
+					},
+				},
+			},
+			expectedErrors: 4,
+		},
+		{
+			name:        "test case 12 - l3 cache max and min annotations",
+			rmdWorkload: &intelv1alpha1.RmdWorkload{},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+					Annotations: map[string]string{
+						"rmd.intel.com/nginx1.l3-cache-max": "4",
+						"rmd.intel.com/nginx1.l3-cache-min": "2",
+					},
+				},
+			},
+			containerName: "nginx1",
+			expectedWorkload: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{
+							Max: 4,
+							Min: 2,
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range tcases {
-		getAnnotationInfo(tc.rmdWorkload, tc.pod, tc.containerName)
+		errs := getAnnotationInfo(tc.rmdWorkload, tc.pod, tc.containerName)
 
 		if !reflect.DeepEqual(tc.rmdWorkload, tc.expectedWorkload) {
 			t.Errorf("%s: Failed. Expected: %v, Got: %v\n", tc.name, tc.expectedWorkload, tc.rmdWorkload)
 		}
+		if len(errs) != tc.expectedErrors {
+			t.Errorf("%s: Failed. Expected %v errors, Got: %v\n", tc.name, tc.expectedErrors, errs)
+		}
 	}
 }
 
@@ -636,6 +810,37 @@ func TestGetContainerRequestingCache(t *testing.T) {
 			},
 			containers: []corev1.Container{},
 		},
+		{
+			name: "test case 5 - container requesting best-effort cache",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "nginx1",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceName("intel.com/l3_cache_ways_besteffort"): resource.MustParse("2"),
+								},
+							},
+						},
+					},
+				},
+			},
+			containers: []corev1.Container{
+				{
+					Name: "nginx1",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceName("intel.com/l3_cache_ways_besteffort"): resource.MustParse("2"),
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range tcases {
 		containers := getContainersRequestingCache(tc.pod)
@@ -807,6 +1012,70 @@ func TestGetMaxCache(t *testing.T) {
 	}
 }
 
+func TestCheckCacheWays(t *testing.T) {
+	tcases := []struct {
+		name           string
+		maxCache       int
+		minCache       int
+		guaranteedWays int
+		besteffortWays int
+		expectedErr    bool
+	}{
+		{
+			name:           "test case 1 - guaranteed cache",
+			maxCache:       2,
+			minCache:       2,
+			guaranteedWays: 2,
+		},
+		{
+			name:           "test case 2 - guaranteed cache above requested ways",
+			maxCache:       4,
+			minCache:       4,
+			guaranteedWays: 2,
+			expectedErr:    true,
+		},
+		{
+			name:           "test case 3 - best-effort cache",
+			maxCache:       4,
+			minCache:       2,
+			besteffortWays: 2,
+		},
+		{
+			name:           "test case 4 - best-effort cache with guaranteed ways requested",
+			maxCache:       4,
+			minCache:       2,
+			guaranteedWays: 2,
+			expectedErr:    true,
+		},
+		{
+			name:           "test case 5 - best-effort cache without max",
+			minCache:       2,
+			besteffortWays: 2,
+			expectedErr:    true,
+		},
+		{
+			name: "test case 6 - shared cache",
+		},
+		{
+			name:           "test case 7 - min above max",
+			maxCache:       2,
+			minCache:       4,
+			besteffortWays: 4,
+			expectedErr:    true,
+		},
+	}
+	for _, tc := range tcases {
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		rmdWorkload.Spec.Rdt.Cache.Max = tc.maxCache
+		rmdWorkload.Spec.Rdt.Cache.Min = tc.minCache
+		containerInfo := containerInformation{maxCache: tc.guaranteedWays, besteffortCache: tc.besteffortWays}
+		err := checkCacheWays(rmdWorkload, containerInfo)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
 func TestExclusiveCPUs(t *testing.T) {
 	tcases := []struct {
 		name      string
@@ -945,193 +1214,536 @@ func TestExclusiveCPUs(t *testing.T) {
 
 }
 
-func TestGetNodeAgent(t *testing.T) {
+func TestReconcileNodeScope(t *testing.T) {
 	tcases := []struct {
-		name                  string
-		pod                   *corev1.Pod
-		nodeAgentlist         *corev1.PodList
-		nodeAgentpodName      string
-		namespacelist         *corev1.NamespaceList
-		expectedNamespaceName types.NamespacedName
+		name                 string
+		pod                  *corev1.Pod
+		expectedRmdWorkloads int
 	}{
 		{
-			name: "test case 1",
+			name: "test case 1 - pod on other node ignored",
 			pod: &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "pod-1",
 					Namespace: "default",
 				},
-			},
-			nodeAgentlist: &corev1.PodList{
-				Items: []corev1.Pod{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-xyz",
-							Namespace: "default",
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "container1",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceName(l3Cache): *resource.NewQuantity(2, resource.DecimalSI),
+								},
+							},
 						},
 					},
+					NodeName: "example-node-2.com",
 				},
-			},
-			nodeAgentpodName: "rmd-node-agent-xyz",
-			namespacelist: &corev1.NamespaceList{
-				Items: []corev1.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "default",
-							Namespace: "default",
-						},
-					},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
 				},
 			},
-			expectedNamespaceName: types.NamespacedName{
-				Name:      "rmd-node-agent-xyz",
-				Namespace: "default",
-			},
+			expectedRmdWorkloads: 0,
 		},
 		{
-			name: "test case 2",
+			name: "test case 2 - pod on node not requesting cache",
 			pod: &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "pod-1",
 					Namespace: "default",
 				},
-			},
-			nodeAgentlist: &corev1.PodList{
-				Items: []corev1.Pod{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node1",
-							Namespace: "default",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node2",
-							Namespace: "default",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node3",
-							Namespace: "default",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node4",
-							Namespace: "default",
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "container1",
 						},
 					},
+					NodeName: "example-node-1.com",
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
 				},
 			},
-			nodeAgentpodName: "rmd-node-agent-node3",
-			namespacelist: &corev1.NamespaceList{
-				Items: []corev1.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "default",
-							Namespace: "default",
-						},
-					},
+			expectedRmdWorkloads: 0,
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcilePodObject(tc.pod)
+		if err != nil {
+			t.Fatalf("error creating ReconcilePod object: (%v)", err)
+		}
+		r.nodeName = "example-node-1.com"
+
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: tc.pod.GetObjectMeta().GetName(), Namespace: tc.pod.GetObjectMeta().GetNamespace()}}
+		_, err = r.Reconcile(request)
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+		rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+		err = r.client.List(context.TODO(), rmdWorkloads)
+		if err != nil {
+			t.Fatalf("error listing RmdWorkloads: (%v)", err)
+		}
+		if len(rmdWorkloads.Items) != tc.expectedRmdWorkloads {
+			t.Errorf("%v failed: Expected %v RmdWorkloads, got %v", tc.name, tc.expectedRmdWorkloads, len(rmdWorkloads.Items))
+		}
+	}
+}
+
+func TestReconcileContainerTermination(t *testing.T) {
+	cacheContainer := func(name string) corev1.Container {
+		return corev1.Container{
+			Name: name,
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceName(l3Cache): *resource.NewQuantity(2, resource.DecimalSI),
 				},
 			},
-			expectedNamespaceName: types.NamespacedName{
-				Name:      "rmd-node-agent-node3",
+		}
+	}
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+	tcases := []struct {
+		name                 string
+		phase                corev1.PodPhase
+		initContainerStatus  []corev1.ContainerStatus
+		containerStatus      []corev1.ContainerStatus
+		expectedRmdWorkloads []string
+	}{
+		{
+			name:                 "test case 1 - pod succeeded",
+			phase:                corev1.PodSucceeded,
+			initContainerStatus:  []corev1.ContainerStatus{{Name: "init1", State: terminated}},
+			containerStatus:      []corev1.ContainerStatus{{Name: "container1", State: terminated}},
+			expectedRmdWorkloads: []string{"pod-unowned-rmd-workload-container1"},
+		},
+		{
+			name:                 "test case 2 - pod failed",
+			phase:                corev1.PodFailed,
+			expectedRmdWorkloads: []string{"pod-unowned-rmd-workload-container1"},
+		},
+		{
+			name:                "test case 3 - init container finished",
+			phase:               corev1.PodRunning,
+			initContainerStatus: []corev1.ContainerStatus{{Name: "init1", State: terminated}},
+			containerStatus:     []corev1.ContainerStatus{{Name: "container1", State: running}},
+			expectedRmdWorkloads: []string{
+				"pod-1-rmd-workload-container1",
+				"pod-unowned-rmd-workload-container1",
+			},
+		},
+		{
+			name:                "test case 4 - container waiting for restart",
+			phase:               corev1.PodRunning,
+			initContainerStatus: []corev1.ContainerStatus{{Name: "init1", State: terminated}},
+			containerStatus: []corev1.ContainerStatus{
+				{Name: "container1", State: waiting, LastTerminationState: terminated},
+			},
+			expectedRmdWorkloads: []string{"pod-unowned-rmd-workload-container1"},
+		},
+		{
+			name:                "test case 5 - container restarted",
+			phase:               corev1.PodRunning,
+			initContainerStatus: []corev1.ContainerStatus{{Name: "init1", State: terminated}},
+			containerStatus: []corev1.ContainerStatus{
+				{Name: "container1", State: running, LastTerminationState: terminated, RestartCount: 1},
+			},
+			expectedRmdWorkloads: []string{
+				"pod-1-rmd-workload-container1",
+				"pod-unowned-rmd-workload-container1",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-1",
 				Namespace: "default",
+				UID:       "pod-1-uid",
+			},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{cacheContainer("init1")},
+				Containers:     []corev1.Container{cacheContainer("container1")},
+				NodeName:       "example-node-1.com",
 			},
+			Status: corev1.PodStatus{
+				Phase:                 tc.phase,
+				InitContainerStatuses: tc.initContainerStatus,
+				ContainerStatuses:     tc.containerStatus,
+			},
+		}
+		r, err := createReconcilePodObject(pod)
+		if err != nil {
+			t.Fatalf("error creating ReconcilePod object: (%v)", err)
+		}
+		r.nodeName = "example-node-1.com"
+		isController := true
+		ownerReferences := []metav1.OwnerReference{
+			{APIVersion: "v1", Kind: "Pod", Name: "pod-1", UID: "pod-1-uid", Controller: &isController},
+		}
+		existingWorkloads := []*intelv1alpha1.RmdWorkload{
+			{ObjectMeta: metav1.ObjectMeta{Name: "pod-1-rmd-workload", Namespace: "default", OwnerReferences: ownerReferences}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "pod-1-rmd-workload-init1", Namespace: "default", OwnerReferences: ownerReferences}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "pod-1-rmd-workload-container1", Namespace: "default", OwnerReferences: ownerReferences}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "pod-unowned-rmd-workload-container1", Namespace: "default"}},
+		}
+		for _, rmdWorkload := range existingWorkloads {
+			err = r.client.Create(context.TODO(), rmdWorkload)
+			if err != nil {
+				t.Fatalf("error creating RmdWorkload: (%v)", err)
+			}
+		}
+
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-1", Namespace: "default"}}
+		_, err = r.Reconcile(request)
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+		rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+		err = r.client.List(context.TODO(), rmdWorkloads)
+		if err != nil {
+			t.Fatalf("error listing RmdWorkloads: (%v)", err)
+		}
+		rmdWorkloadNames := make([]string, 0)
+		for _, rmdWorkload := range rmdWorkloads.Items {
+			rmdWorkloadNames = append(rmdWorkloadNames, rmdWorkload.GetObjectMeta().GetName())
+		}
+		if !reflect.DeepEqual(rmdWorkloadNames, tc.expectedRmdWorkloads) {
+			t.Errorf("%v failed: Expected RmdWorkloads %v, got %v", tc.name, tc.expectedRmdWorkloads, rmdWorkloadNames)
+		}
+	}
+}
+
+func TestReconcileInvalidAnnotations(t *testing.T) {
+	tcases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedPolicy string
+		expectedEvents []string
+	}{
+		{
+			name:           "test case 1 - valid annotations",
+			annotations:    map[string]string{"rmd.intel.com/container-1.policy": "gold"},
+			expectedPolicy: "gold",
 		},
 		{
-			name: "test case 3",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "pod-1",
-					Namespace: "default",
-				},
+			name: "test case 2 - invalid annotations reported on pod",
+			annotations: map[string]string{
+				"container-1_policy":                       "gold",
+				"rmd.intel.com/container-1.mba-percentage": "0",
 			},
-			nodeAgentlist: &corev1.PodList{
-				Items: []corev1.Pod{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node1",
-							Namespace: "default",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node2",
-							Namespace: "test",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "rmd-node-agent-node3",
-							Namespace: "prod",
+			expectedPolicy: "gold",
+			expectedEvents: []string{
+				"Warning InvalidRmdAnnotation annotation rmd.intel.com/container-1.mba-percentage: invalid value 0, must be between 1 and 100",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		pod := alignmentTestPod()
+		pod.SetAnnotations(tc.annotations)
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding to scheme: (%v)", err)
+		}
+		recorder := record.NewFakeRecorder(10)
+		r := &ReconcilePod{
+			client:   fake.NewFakeClientWithScheme(s, []runtime.Object{pod}...),
+			scheme:   s,
+			recorder: recorder,
+			cpuDiscoverer: &podresourcesclient.PodResourcesClient{
+				Client: &fakeListerClient{
+					listResponse: &podresourcesclient.ListPodResourcesResponse{
+						PodResources: []*podresourcesclient.PodResources{
+							{
+								Name:      "pod-1",
+								Namespace: "default",
+								Containers: []*podresourcesclient.ContainerResources{
+									{Name: "container-1", CpuIds: []int64{0, 1}},
+								},
+							},
 						},
 					},
 				},
 			},
-			nodeAgentpodName: "rmd-node-agent-node3",
-			namespacelist: &corev1.NamespaceList{
-				Items: []corev1.Namespace{
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "default",
-							Namespace: "default",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "test",
-							Namespace: "test",
-						},
-					},
-					{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "prod",
-							Namespace: "prod",
+			nodeName: "example-node-1.com",
+		}
+
+		_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-1", Namespace: "default"}})
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "pod-1-rmd-workload-container-1", Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Fatalf("%v failed: error getting RmdWorkload: (%v)", tc.name, err)
+		}
+		if rmdWorkload.Spec.Policy != tc.expectedPolicy {
+			t.Errorf("%v failed: Expected policy %v, got %v", tc.name, tc.expectedPolicy, rmdWorkload.Spec.Policy)
+		}
+		events := make([]string, 0)
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		if len(events) != len(tc.expectedEvents) {
+			t.Errorf("%v failed: Expected events %v, got %v", tc.name, tc.expectedEvents, events)
+			continue
+		}
+		for i := range events {
+			if events[i] != tc.expectedEvents[i] {
+				t.Errorf("%v failed: Expected event %v, got %v", tc.name, tc.expectedEvents[i], events[i])
+			}
+		}
+	}
+}
+
+func TestReconcileExistingWorkload(t *testing.T) {
+	tcases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedCacheMax int
+		expectedCacheMin int
+	}{
+		{
+			name: "test case 1 - annotations unchanged",
+			annotations: map[string]string{
+				"rmd.intel.com/container-1.l3-cache-max": "3",
+				"rmd.intel.com/container-1.l3-cache-min": "2",
+			},
+			expectedCacheMax: 3,
+			expectedCacheMin: 2,
+		},
+		{
+			name: "test case 2 - cache min changed",
+			annotations: map[string]string{
+				"rmd.intel.com/container-1.l3-cache-max": "3",
+				"rmd.intel.com/container-1.l3-cache-min": "1",
+			},
+			expectedCacheMax: 3,
+			expectedCacheMin: 1,
+		},
+	}
+	for _, tc := range tcases {
+		pod := alignmentTestPod()
+		pod.SetAnnotations(tc.annotations)
+		// Best-effort cache of at most 3 ways and at least the min
+		resources := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+			l3CacheBesteffort:     resource.MustParse("2"),
+		}
+		pod.Spec.Containers[0].Resources = corev1.ResourceRequirements{Requests: resources, Limits: resources}
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding to scheme: (%v)", err)
+		}
+		isController := true
+		existingWorkload := &intelv1alpha1.RmdWorkload{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-1-rmd-workload-container-1",
+				Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: "Pod", Name: "pod-1", UID: pod.GetObjectMeta().GetUID(), Controller: &isController},
+				},
+			},
+			Spec: intelv1alpha1.RmdWorkloadSpec{
+				CoreIds:      []string{"0", "1"},
+				Nodes:        []string{"example-node-1.com"},
+				NodeSelector: map[string]string{},
+				Rdt: intelv1alpha1.Rdt{
+					Cache: intelv1alpha1.Cache{Max: 3, Min: 2},
+				},
+			},
+			Status: intelv1alpha1.RmdWorkloadStatus{
+				WorkloadStates: map[string]intelv1alpha1.WorkloadState{
+					"example-node-1.com": {Status: "Successful"},
+				},
+			},
+		}
+		r := &ReconcilePod{
+			client:   fake.NewFakeClientWithScheme(s, []runtime.Object{pod, existingWorkload}...),
+			scheme:   s,
+			recorder: record.NewFakeRecorder(10),
+			cpuDiscoverer: &podresourcesclient.PodResourcesClient{
+				Client: &fakeListerClient{
+					listResponse: &podresourcesclient.ListPodResourcesResponse{
+						PodResources: []*podresourcesclient.PodResources{
+							{
+								Name:      "pod-1",
+								Namespace: "default",
+								Containers: []*podresourcesclient.ContainerResources{
+									{Name: "container-1", CpuIds: []int64{0, 1}},
+								},
+							},
 						},
 					},
 				},
 			},
-			expectedNamespaceName: types.NamespacedName{
-				Name:      "rmd-node-agent-node3",
-				Namespace: "prod",
+			nodeName: "example-node-1.com",
+		}
+
+		_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-1", Namespace: "default"}})
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+		rmdWorkload := &intelv1alpha1.RmdWorkload{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "pod-1-rmd-workload-container-1", Namespace: "default"}, rmdWorkload)
+		if err != nil {
+			t.Fatalf("%v failed: error getting RmdWorkload: (%v)", tc.name, err)
+		}
+		if rmdWorkload.Spec.Rdt.Cache.Max != tc.expectedCacheMax || rmdWorkload.Spec.Rdt.Cache.Min != tc.expectedCacheMin {
+			t.Errorf("%v failed: Expected cache max %v and min %v, got max %v and min %v", tc.name, tc.expectedCacheMax, tc.expectedCacheMin, rmdWorkload.Spec.Rdt.Cache.Max, rmdWorkload.Spec.Rdt.Cache.Min)
+		}
+		if !reflect.DeepEqual(rmdWorkload.Status.WorkloadStates, existingWorkload.Status.WorkloadStates) {
+			t.Errorf("%v failed: Expected workload states %v, got %v", tc.name, existingWorkload.Status.WorkloadStates, rmdWorkload.Status.WorkloadStates)
+		}
+	}
+}
+
+func TestReconcilePodWorkload(t *testing.T) {
+	cacheContainer := func(name string, cacheWays int64) corev1.Container {
+		resources := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+			l3Cache:               *resource.NewQuantity(cacheWays, resource.DecimalSI),
+		}
+		return corev1.Container{
+			Name:      name,
+			Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources},
+		}
+	}
+	tcases := []struct {
+		name                 string
+		annotations          map[string]string
+		expectedRmdWorkloads []string
+		expectedCoreIDs      []string
+		expectedCache        int
+		expectedEvents       []string
+	}{
+		{
+			name:                 "test case 1 - containers sharing a pod workload",
+			annotations:          map[string]string{"rmd.intel.com/workload-mode": "pod"},
+			expectedRmdWorkloads: []string{"pod-1-rmd-workload"},
+			expectedCoreIDs:      []string{"0", "1", "2", "3"},
+			expectedCache:        3,
+		},
+		{
+			name: "test case 2 - containers with differing policies",
+			annotations: map[string]string{
+				"rmd.intel.com/workload-mode":     "pod",
+				"rmd.intel.com/container1.policy": "gold",
+			},
+			expectedRmdWorkloads: []string{},
+			expectedEvents: []string{
+				"Warning InvalidPodRmdWorkload container container2 requests policy, MBA or P-State settings differing from container container1",
+			},
+		},
+		{
+			name:                 "test case 3 - workload per container",
+			annotations:          map[string]string{"rmd.intel.com/workload-mode": "container"},
+			expectedRmdWorkloads: []string{"pod-1-rmd-workload-container1", "pod-1-rmd-workload-container2"},
+			expectedCoreIDs:      []string{"2", "3"},
+			expectedCache:        1,
+		},
+		{
+			name:                 "test case 4 - invalid workload mode",
+			annotations:          map[string]string{"rmd.intel.com/workload-mode": "node"},
+			expectedRmdWorkloads: []string{"pod-1-rmd-workload-container1", "pod-1-rmd-workload-container2"},
+			expectedCoreIDs:      []string{"2", "3"},
+			expectedCache:        1,
+			expectedEvents: []string{
+				`Warning InvalidRmdAnnotation annotation rmd.intel.com/workload-mode: unknown workload mode "node", must be container or pod`,
 			},
 		},
 	}
 	for _, tc := range tcases {
-		// Create a ReconcileNode object with the scheme and fake client.
-		r, err := createReconcilePodObject(tc.pod)
-		if err != nil {
-			t.Fatalf("error creating ReconcilePod object: (%v)", err)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-1",
+				Namespace:   "default",
+				UID:         "pod-1-uid",
+				Annotations: tc.annotations,
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   "example-node-1.com",
+				Containers: []corev1.Container{cacheContainer("container1", 2), cacheContainer("container2", 1)},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
 		}
-		for i := range tc.namespacelist.Items {
-			err = r.client.Create(context.TODO(), &tc.namespacelist.Items[i])
-			if err != nil {
-				t.Fatalf("could not create namespace: (%v)", err)
-			}
+		s := scheme.Scheme
+		if err := apis.AddToScheme(s); err != nil {
+			t.Fatalf("error adding to scheme: (%v)", err)
 		}
-		for i := range tc.nodeAgentlist.Items {
-			err = r.client.Create(context.TODO(), &tc.nodeAgentlist.Items[i])
-			if err != nil {
-				t.Fatalf("could not create node agent pod: (%v)", err)
-			}
+		isController := true
+		ownerReferences := []metav1.OwnerReference{
+			{APIVersion: "v1", Kind: "Pod", Name: "pod-1", UID: "pod-1-uid", Controller: &isController},
+		}
+		objects := []runtime.Object{
+			pod,
+			// RmdWorkloads of the workload mode the pod used before
+			&intelv1alpha1.RmdWorkload{ObjectMeta: metav1.ObjectMeta{Name: "pod-1-rmd-workload", Namespace: "default", OwnerReferences: ownerReferences}},
+			&intelv1alpha1.RmdWorkload{ObjectMeta: metav1.ObjectMeta{Name: "pod-1-rmd-workload-container1", Namespace: "default", OwnerReferences: ownerReferences}},
+		}
+		recorder := record.NewFakeRecorder(10)
+		r := &ReconcilePod{
+			client:   fake.NewFakeClientWithScheme(s, objects...),
+			scheme:   s,
+			recorder: recorder,
+			cpuDiscoverer: &podresourcesclient.PodResourcesClient{
+				Client: &fakeListerClient{
+					listResponse: &podresourcesclient.ListPodResourcesResponse{
+						PodResources: []*podresourcesclient.PodResources{
+							{
+								Name:      "pod-1",
+								Namespace: "default",
+								Containers: []*podresourcesclient.ContainerResources{
+									{Name: "container1", CpuIds: []int64{0, 1}},
+									{Name: "container2", CpuIds: []int64{2, 3}},
+								},
+							},
+						},
+					},
+				},
+			},
+			nodeName: "example-node-1.com",
 		}
-		os.Setenv("POD_NAME", tc.nodeAgentpodName)
 
-		nodeAgent, err := r.getNodeAgentPod()
+		_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: "pod-1", Namespace: "default"}})
 		if err != nil {
-			t.Errorf("r.getNodeAgentPod: (%v)", err)
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
 		}
-
-		nodeAgentNamespacedName := types.NamespacedName{
-			Name:      nodeAgent.GetObjectMeta().GetName(),
-			Namespace: nodeAgent.GetObjectMeta().GetNamespace(),
+		rmdWorkloads := &intelv1alpha1.RmdWorkloadList{}
+		err = r.client.List(context.TODO(), rmdWorkloads)
+		if err != nil {
+			t.Fatalf("error listing RmdWorkloads: (%v)", err)
 		}
-
-		if !reflect.DeepEqual(nodeAgentNamespacedName, tc.expectedNamespaceName) {
-			t.Errorf("Failed: %v - Expected \n%v\n, got\n %v\n", tc.name, tc.expectedNamespaceName, nodeAgentNamespacedName)
-
+		rmdWorkloadNames := make([]string, 0)
+		for _, rmdWorkload := range rmdWorkloads.Items {
+			rmdWorkloadNames = append(rmdWorkloadNames, rmdWorkload.GetObjectMeta().GetName())
+		}
+		if !reflect.DeepEqual(rmdWorkloadNames, tc.expectedRmdWorkloads) {
+			t.Errorf("%v failed: Expected RmdWorkloads %v, got %v", tc.name, tc.expectedRmdWorkloads, rmdWorkloadNames)
+		}
+		if len(rmdWorkloads.Items) != 0 {
+			rmdWorkload := rmdWorkloads.Items[len(rmdWorkloads.Items)-1]
+			if !reflect.DeepEqual(rmdWorkload.Spec.CoreIds, tc.expectedCoreIDs) {
+				t.Errorf("%v failed: Expected cores %v, got %v", tc.name, tc.expectedCoreIDs, rmdWorkload.Spec.CoreIds)
+			}
+			if rmdWorkload.Spec.Rdt.Cache.Max != tc.expectedCache || rmdWorkload.Spec.Rdt.Cache.Min != tc.expectedCache {
+				t.Errorf("%v failed: Expected cache %v, got max %v and min %v", tc.name, tc.expectedCache, rmdWorkload.Spec.Rdt.Cache.Max, rmdWorkload.Spec.Rdt.Cache.Min)
+			}
+			if !metav1.IsControlledBy(&rmdWorkload, pod) {
+				t.Errorf("%v failed: Expected RmdWorkload %v to be controlled by the pod", tc.name, rmdWorkload.GetObjectMeta().GetName())
+			}
+		}
+		events := make([]string, 0)
+		for len(recorder.Events) > 0 {
+			events = append(events, <-recorder.Events)
+		}
+		if len(events) != len(tc.expectedEvents) || (len(events) != 0 && !reflect.DeepEqual(events, tc.expectedEvents)) {
+			t.Errorf("%v failed: Expected events %v, got %v", tc.name, tc.expectedEvents, events)
 		}
 	}
 }
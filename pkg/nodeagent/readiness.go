@@ -0,0 +1,115 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RmdAppliedCondition is the pod condition set by the node agent for pods listing it in their
+// readiness gates. It is True once the RmdWorkloads of all containers requesting cache are applied
+// by RMD on the pod's node.
+const RmdAppliedCondition corev1.PodConditionType = "intel.com/rmd-applied"
+
+const (
+	appliedStatusConst     = "Successful"
+	rdtAppliedReason       = "RdtApplied"
+	noCacheRequestedReason = "NoCacheRequested"
+	rdtNotAppliedReason    = "RdtNotApplied"
+	rdtFailedReason        = "RdtFailed"
+)
+
+// hasRmdAppliedGate returns true if pod lists RmdAppliedCondition in its readiness gates
+func hasRmdAppliedGate(pod *corev1.Pod) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == RmdAppliedCondition {
+			return true
+		}
+	}
+	return false
+}
+
+// rmdAppliedCondition returns the RmdAppliedCondition of pod given the RmdWorkloads of its containers.
+// The condition is False while any RmdWorkload is not yet applied on the pod's node, the reason tells
+// apart RmdWorkloads still waiting for RMD from RmdWorkloads that were rejected.
+func rmdAppliedCondition(pod *corev1.Pod, rmdWorkloads []*intelv1alpha1.RmdWorkload) corev1.PodCondition {
+	condition := corev1.PodCondition{Type: RmdAppliedCondition}
+	if len(rmdWorkloads) == 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = noCacheRequestedReason
+		return condition
+	}
+
+	failed := make([]string, 0)
+	notApplied := make([]string, 0)
+	for _, rmdWorkload := range rmdWorkloads {
+		rmdWorkloadName := rmdWorkload.GetObjectMeta().GetName()
+		workloadState, ok := rmdWorkload.Status.WorkloadStates[pod.Spec.NodeName]
+		switch {
+		case !ok || workloadState.Status == "":
+			notApplied = append(notApplied, rmdWorkloadName)
+		case workloadState.Status != appliedStatusConst:
+			report := fmt.Sprintf("%s %s", rmdWorkloadName, workloadState.Status)
+			if workloadState.Response != "" {
+				report = fmt.Sprintf("%s: %s", report, workloadState.Response)
+			}
+			failed = append(failed, report)
+		}
+	}
+	sort.Strings(failed)
+	sort.Strings(notApplied)
+
+	switch {
+	case len(failed) != 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = rdtFailedReason
+		condition.Message = strings.Join(failed, ", ")
+	case len(notApplied) != 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = rdtNotAppliedReason
+		condition.Message = fmt.Sprintf("waiting for RmdWorkloads %s", strings.Join(notApplied, ", "))
+	default:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = rdtAppliedReason
+	}
+	return condition
+}
+
+// setRmdAppliedCondition updates the RmdAppliedCondition of pod. The pod status is only written if
+// the condition changed. LastTransitionTime is kept unless the condition status changed.
+func (r *ReconcilePod) setRmdAppliedCondition(pod *corev1.Pod, rmdWorkloads []*intelv1alpha1.RmdWorkload) error {
+	condition := rmdAppliedCondition(pod, rmdWorkloads)
+	for i, existing := range pod.Status.Conditions {
+		if existing.Type != RmdAppliedCondition {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			return nil
+		}
+		condition.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != condition.Status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		pod.Status.Conditions[i] = condition
+		return r.updatePodStatus(pod, condition)
+	}
+	condition.LastTransitionTime = metav1.Now()
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	return r.updatePodStatus(pod, condition)
+}
+
+func (r *ReconcilePod) updatePodStatus(pod *corev1.Pod, condition corev1.PodCondition) error {
+	logger := log.WithName("updatePodStatus")
+	logger.Info("Set pod condition", "pod", pod.GetObjectMeta().GetName(), "condition", condition.Type, "status", condition.Status, "reason", condition.Reason)
+	err := r.client.Status().Update(context.TODO(), pod)
+	if err != nil {
+		logger.Error(err, "Failed to update pod status")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package nodeagent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func gatedPod(conditions ...corev1.PodCondition) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+		},
+		Spec: corev1.PodSpec{
+			NodeName:       "example-node-1.com",
+			ReadinessGates: []corev1.PodReadinessGate{{ConditionType: RmdAppliedCondition}},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: conditions,
+		},
+	}
+}
+
+func podRmdWorkload(container string, states map[string]intelv1alpha1.WorkloadState) *intelv1alpha1.RmdWorkload {
+	return &intelv1alpha1.RmdWorkload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1-rmd-workload-" + container,
+			Namespace: "default",
+		},
+		Status: intelv1alpha1.RmdWorkloadStatus{WorkloadStates: states},
+	}
+}
+
+func TestRmdAppliedCondition(t *testing.T) {
+	tcases := []struct {
+		name            string
+		rmdWorkloads    []*intelv1alpha1.RmdWorkload
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:           "test case 1 - no cache requested",
+			rmdWorkloads:   []*intelv1alpha1.RmdWorkload{},
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: "NoCacheRequested",
+		},
+		{
+			name: "test case 2 - all workloads applied",
+			rmdWorkloads: []*intelv1alpha1.RmdWorkload{
+				podRmdWorkload("container1", map[string]intelv1alpha1.WorkloadState{"example-node-1.com": {Status: "Successful"}}),
+				podRmdWorkload("container2", map[string]intelv1alpha1.WorkloadState{"example-node-1.com": {Status: "Successful"}}),
+			},
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: "RdtApplied",
+		},
+		{
+			name: "test case 3 - workload not yet applied",
+			rmdWorkloads: []*intelv1alpha1.RmdWorkload{
+				podRmdWorkload("container1", map[string]intelv1alpha1.WorkloadState{"example-node-1.com": {Status: "Successful"}}),
+				podRmdWorkload("container2", nil),
+			},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedReason:  "RdtNotApplied",
+			expectedMessage: "waiting for RmdWorkloads pod-1-rmd-workload-container2",
+		},
+		{
+			name: "test case 4 - workload applied on other node only",
+			rmdWorkloads: []*intelv1alpha1.RmdWorkload{
+				podRmdWorkload("container1", map[string]intelv1alpha1.WorkloadState{"example-node-2.com": {Status: "Successful"}}),
+			},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedReason:  "RdtNotApplied",
+			expectedMessage: "waiting for RmdWorkloads pod-1-rmd-workload-container1",
+		},
+		{
+			name: "test case 5 - workload rejected",
+			rmdWorkloads: []*intelv1alpha1.RmdWorkload{
+				podRmdWorkload("container1", map[string]intelv1alpha1.WorkloadState{
+					"example-node-1.com": {Status: "Pending", Response: "Fail: insufficient cache capacity"},
+				}),
+				podRmdWorkload("container2", nil),
+			},
+			expectedStatus:  corev1.ConditionFalse,
+			expectedReason:  "RdtFailed",
+			expectedMessage: "pod-1-rmd-workload-container1 Pending: Fail: insufficient cache capacity",
+		},
+	}
+	for _, tc := range tcases {
+		condition := rmdAppliedCondition(gatedPod(), tc.rmdWorkloads)
+		if condition.Type != RmdAppliedCondition || condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason || condition.Message != tc.expectedMessage {
+			t.Errorf("%v failed: Expected %v %v %q, got %+v", tc.name, tc.expectedStatus, tc.expectedReason, tc.expectedMessage, condition)
+		}
+	}
+}
+
+func TestSetRmdAppliedCondition(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	applied := []*intelv1alpha1.RmdWorkload{
+		podRmdWorkload("container1", map[string]intelv1alpha1.WorkloadState{"example-node-1.com": {Status: "Successful"}}),
+	}
+	tcases := []struct {
+		name                   string
+		pod                    *corev1.Pod
+		expectedStatus         corev1.ConditionStatus
+		expectedTransitionTime bool
+	}{
+		{
+			name:           "test case 1 - condition added",
+			pod:            gatedPod(corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse}),
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "test case 2 - condition changed",
+			pod: gatedPod(corev1.PodCondition{
+				Type:               RmdAppliedCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             "RdtNotApplied",
+				LastTransitionTime: transitionTime,
+			}),
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "test case 3 - condition unchanged",
+			pod: gatedPod(corev1.PodCondition{
+				Type:               RmdAppliedCondition,
+				Status:             corev1.ConditionTrue,
+				Reason:             "RdtApplied",
+				LastTransitionTime: transitionTime,
+			}),
+			expectedStatus:         corev1.ConditionTrue,
+			expectedTransitionTime: true,
+		},
+	}
+	for _, tc := range tcases {
+		r, err := createReconcilePodObject(tc.pod)
+		if err != nil {
+			t.Fatalf("error creating ReconcilePod object: (%v)", err)
+		}
+		err = r.setRmdAppliedCondition(tc.pod, applied)
+		if err != nil {
+			t.Errorf("%v failed: unexpected error: (%v)", tc.name, err)
+		}
+
+		pod := &corev1.Pod{}
+		err = r.client.Get(context.TODO(), types.NamespacedName{Name: "pod-1", Namespace: "default"}, pod)
+		if err != nil {
+			t.Fatalf("error getting pod: (%v)", err)
+		}
+		found := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type != RmdAppliedCondition {
+				continue
+			}
+			found = true
+			if condition.Status != tc.expectedStatus {
+				t.Errorf("%v failed: Expected status %v, got %v", tc.name, tc.expectedStatus, condition.Status)
+			}
+			if condition.LastTransitionTime.Equal(&transitionTime) != tc.expectedTransitionTime {
+				t.Errorf("%v failed: Expected transition time kept %v, got %v", tc.name, tc.expectedTransitionTime, condition.LastTransitionTime)
+			}
+		}
+		if !found {
+			t.Errorf("%v failed: Expected condition %v, got %v", tc.name, RmdAppliedCondition, pod.Status.Conditions)
+		}
+	}
+}
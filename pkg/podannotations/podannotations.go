@@ -0,0 +1,262 @@
+// Package podannotations parses the RMD settings requested for the containers of a pod through
+// pod annotations.
+package podannotations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Prefix is the prefix of the annotations requesting RMD settings for a single container,
+	// e.g. rmd.intel.com/<container>.mba-percentage
+	Prefix = "rmd.intel.com/"
+	// ContainersKey is the pod-level annotation holding the RMD settings of all containers of the
+	// pod as a JSON object keyed by container name
+	ContainersKey = "rmd.intel.com/containers"
+	// WorkloadModeKey is the pod-level annotation selecting whether an RmdWorkload is created for each
+	// container of the pod or a single RmdWorkload is shared by all containers
+	WorkloadModeKey = "rmd.intel.com/workload-mode"
+)
+
+// Workload modes of the WorkloadModeKey annotation
+const (
+	// ContainerWorkloadMode creates an RmdWorkload for each container of the pod, the default
+	ContainerWorkloadMode = "container"
+	// PodWorkloadMode creates a single RmdWorkload for all containers of the pod
+	PodWorkloadMode = "pod"
+)
+
+// Settings that can be requested for a container with the prefixed annotations
+const (
+	PolicyKey           = "policy"
+	MbaPercentageKey    = "mba-percentage"
+	MbaMbpsKey          = "mba-mbps"
+	PstateRatioKey      = "pstate-ratio"
+	PstateMonitoringKey = "pstate-monitoring"
+	L3CacheMaxKey       = "l3-cache-max"
+	L3CacheMinKey       = "l3-cache-min"
+)
+
+// legacyKeys maps the suffixes of the legacy "<container>_<suffix>" annotations to settings
+var legacyKeys = map[string]string{
+	"policy":            PolicyKey,
+	"mba_percentage":    MbaPercentageKey,
+	"mba_mbps":          MbaMbpsKey,
+	"pstate_ratio":      PstateRatioKey,
+	"pstate_monitoring": PstateMonitoringKey,
+}
+
+// Container holds the RMD settings requested for a container. Settings not requested are nil.
+type Container struct {
+	Policy           *string `json:"policy,omitempty"`
+	MbaPercentage    *int    `json:"mbaPercentage,omitempty"`
+	MbaMbps          *int    `json:"mbaMbps,omitempty"`
+	PstateRatio      *string `json:"pstateRatio,omitempty"`
+	PstateMonitoring *string `json:"pstateMonitoring,omitempty"`
+	L3CacheMax       *int    `json:"l3CacheMax,omitempty"`
+	L3CacheMin       *int    `json:"l3CacheMin,omitempty"`
+}
+
+// ForContainer returns the RMD settings requested for container containerName of pod. Settings are
+// read from the legacy "<container>_<suffix>" annotations, the pod-level ContainersKey annotation and
+// the "rmd.intel.com/<container>.<setting>" annotations, later sources take precedence. Invalid
+// annotations are skipped and returned as errors.
+func ForContainer(pod *corev1.Pod, containerName string) (Container, []error) {
+	container := Container{}
+	errs := make([]error, 0)
+	annotations := pod.GetObjectMeta().GetAnnotations()
+
+	legacy := Container{}
+	for _, suffix := range sortedKeys(legacyKeys) {
+		annotation := fmt.Sprintf("%s_%s", containerName, suffix)
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		if err := legacy.set(legacyKeys[suffix], value); err != nil {
+			errs = append(errs, fmt.Errorf("annotation %s: %v", annotation, err))
+		}
+	}
+	container.merge(legacy)
+
+	if value, ok := annotations[ContainersKey]; ok {
+		fromJSON, err := containerFromJSON(value, containerName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("annotation %s: %v", ContainersKey, err))
+		}
+		container.merge(fromJSON)
+	}
+
+	prefixed := Container{}
+	containerPrefix := fmt.Sprintf("%s%s.", Prefix, containerName)
+	for _, annotation := range sortedKeys(annotations) {
+		if !strings.HasPrefix(annotation, containerPrefix) {
+			continue
+		}
+		if err := prefixed.set(strings.TrimPrefix(annotation, containerPrefix), annotations[annotation]); err != nil {
+			errs = append(errs, fmt.Errorf("annotation %s: %v", annotation, err))
+		}
+	}
+	container.merge(prefixed)
+
+	return container, errs
+}
+
+// WorkloadMode returns the workload mode requested for pod. Pods without the WorkloadModeKey annotation
+// use ContainerWorkloadMode, as do pods with an invalid mode which is returned as an error.
+func WorkloadMode(pod *corev1.Pod) (string, error) {
+	mode, ok := pod.GetObjectMeta().GetAnnotations()[WorkloadModeKey]
+	if !ok {
+		return ContainerWorkloadMode, nil
+	}
+	switch mode {
+	case ContainerWorkloadMode, PodWorkloadMode:
+		return mode, nil
+	}
+	return ContainerWorkloadMode, fmt.Errorf("annotation %s: unknown workload mode %q, must be %s or %s", WorkloadModeKey, mode, ContainerWorkloadMode, PodWorkloadMode)
+}
+
+// containerFromJSON returns the settings of containerName from the pod-level JSON annotation value.
+// Invalid settings are left out of the returned settings.
+func containerFromJSON(value, containerName string) (Container, error) {
+	containers := make(map[string]Container)
+	decoder := json.NewDecoder(bytes.NewBufferString(value))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&containers); err != nil {
+		return Container{}, err
+	}
+	container := containers[containerName]
+	invalid := make([]string, 0)
+	for field, err := range container.validate() {
+		invalid = append(invalid, fmt.Sprintf("%s %v", field, err))
+	}
+	if len(invalid) != 0 {
+		sort.Strings(invalid)
+		return container, fmt.Errorf("container %s: %s", containerName, strings.Join(invalid, ", "))
+	}
+	return container, nil
+}
+
+// set parses value as setting key
+func (c *Container) set(key, value string) error {
+	switch key {
+	case PolicyKey:
+		return setString(&c.Policy, value)
+	case MbaPercentageKey:
+		return setInt(&c.MbaPercentage, value, 1, 100)
+	case MbaMbpsKey:
+		return setInt(&c.MbaMbps, value, 1, math.MaxInt32)
+	case PstateRatioKey:
+		return setString(&c.PstateRatio, value)
+	case PstateMonitoringKey:
+		return setString(&c.PstateMonitoring, value)
+	case L3CacheMaxKey:
+		return setInt(&c.L3CacheMax, value, 0, math.MaxInt32)
+	case L3CacheMinKey:
+		return setInt(&c.L3CacheMin, value, 0, math.MaxInt32)
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
+
+// validate checks the settings decoded from JSON. Invalid settings are reset to nil and returned by
+// JSON field name.
+func (c *Container) validate() map[string]error {
+	invalid := make(map[string]error)
+	checkString(&c.Policy, "policy", invalid)
+	checkInt(&c.MbaPercentage, "mbaPercentage", 1, 100, invalid)
+	checkInt(&c.MbaMbps, "mbaMbps", 1, math.MaxInt32, invalid)
+	checkString(&c.PstateRatio, "pstateRatio", invalid)
+	checkString(&c.PstateMonitoring, "pstateMonitoring", invalid)
+	checkInt(&c.L3CacheMax, "l3CacheMax", 0, math.MaxInt32, invalid)
+	checkInt(&c.L3CacheMin, "l3CacheMin", 0, math.MaxInt32, invalid)
+	return invalid
+}
+
+// merge overwrites the settings of c with those requested in other
+func (c *Container) merge(other Container) {
+	if other.Policy != nil {
+		c.Policy = other.Policy
+	}
+	if other.MbaPercentage != nil {
+		c.MbaPercentage = other.MbaPercentage
+	}
+	if other.MbaMbps != nil {
+		c.MbaMbps = other.MbaMbps
+	}
+	if other.PstateRatio != nil {
+		c.PstateRatio = other.PstateRatio
+	}
+	if other.PstateMonitoring != nil {
+		c.PstateMonitoring = other.PstateMonitoring
+	}
+	if other.L3CacheMax != nil {
+		c.L3CacheMax = other.L3CacheMax
+	}
+	if other.L3CacheMin != nil {
+		c.L3CacheMin = other.L3CacheMin
+	}
+}
+
+func setString(field **string, value string) error {
+	if value == "" {
+		return fmt.Errorf("value must not be empty")
+	}
+	*field = &value
+	return nil
+}
+
+func setInt(field **int, value string, min, max int) error {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid value %q, must be an integer", value)
+	}
+	if err := inRange(number, min, max); err != nil {
+		return err
+	}
+	*field = &number
+	return nil
+}
+
+func checkString(field **string, name string, invalid map[string]error) {
+	if *field != nil && **field == "" {
+		invalid[name] = fmt.Errorf("value must not be empty")
+		*field = nil
+	}
+}
+
+func checkInt(field **int, name string, min, max int, invalid map[string]error) {
+	if *field == nil {
+		return
+	}
+	if err := inRange(**field, min, max); err != nil {
+		invalid[name] = err
+		*field = nil
+	}
+}
+
+func inRange(number, min, max int) error {
+	if number < min || number > max {
+		if max == math.MaxInt32 {
+			return fmt.Errorf("invalid value %d, must be at least %d", number, min)
+		}
+		return fmt.Errorf("invalid value %d, must be between %d and %d", number, min, max)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
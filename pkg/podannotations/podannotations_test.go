@@ -0,0 +1,227 @@
+package podannotations
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestForContainer(t *testing.T) {
+	tcases := []struct {
+		name              string
+		annotations       map[string]string
+		containerName     string
+		expectedContainer Container
+		expectedErrors    []string
+	}{
+		{
+			name: "test case 1 - legacy annotations",
+			annotations: map[string]string{
+				"nginx_policy":            "gold",
+				"nginx_mba_percentage":    "70",
+				"nginx_mba_mbps":          "100",
+				"nginx_pstate_ratio":      "1.5",
+				"nginx_pstate_monitoring": "on",
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				Policy:           stringPtr("gold"),
+				MbaPercentage:    intPtr(70),
+				MbaMbps:          intPtr(100),
+				PstateRatio:      stringPtr("1.5"),
+				PstateMonitoring: stringPtr("on"),
+			},
+		},
+		{
+			name: "test case 2 - namespaced annotations",
+			annotations: map[string]string{
+				"rmd.intel.com/nginx.policy":            "gold",
+				"rmd.intel.com/nginx.mba-percentage":    "70",
+				"rmd.intel.com/nginx.mba-mbps":          "100",
+				"rmd.intel.com/nginx.pstate-ratio":      "1.5",
+				"rmd.intel.com/nginx.pstate-monitoring": "on",
+				"rmd.intel.com/nginx.l3-cache-max":      "6",
+				"rmd.intel.com/nginx.l3-cache-min":      "3",
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				Policy:           stringPtr("gold"),
+				MbaPercentage:    intPtr(70),
+				MbaMbps:          intPtr(100),
+				PstateRatio:      stringPtr("1.5"),
+				PstateMonitoring: stringPtr("on"),
+				L3CacheMax:       intPtr(6),
+				L3CacheMin:       intPtr(3),
+			},
+		},
+		{
+			name: "test case 3 - pod-level annotation",
+			annotations: map[string]string{
+				ContainersKey: `{"nginx": {"policy": "gold", "mbaPercentage": 70, "l3CacheMax": 0}, "redis": {"policy": "silver"}}`,
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				Policy:        stringPtr("gold"),
+				MbaPercentage: intPtr(70),
+				L3CacheMax:    intPtr(0),
+			},
+		},
+		{
+			name: "test case 4 - annotations of other containers sharing the name prefix ignored",
+			annotations: map[string]string{
+				"application_policy":                 "gold",
+				"app_mba_percentage_policy":          "silver",
+				"rmd.intel.com/application.policy":   "bronze",
+				"rmd.intel.com/app-1.mba-percentage": "50",
+			},
+			containerName: "app",
+		},
+		{
+			name: "test case 5 - precedence of annotation schemes",
+			annotations: map[string]string{
+				"nginx_policy":                       "gold",
+				"nginx_mba_percentage":               "70",
+				"nginx_mba_mbps":                     "100",
+				ContainersKey:                        `{"nginx": {"mbaPercentage": 50, "mbaMbps": 200}}`,
+				"rmd.intel.com/nginx.mba-percentage": "30",
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				Policy:        stringPtr("gold"),
+				MbaPercentage: intPtr(30),
+				MbaMbps:       intPtr(200),
+			},
+		},
+		{
+			name: "test case 6 - invalid values",
+			annotations: map[string]string{
+				"nginx_policy":                       "",
+				"nginx_mba_mbps":                     "fast",
+				"rmd.intel.com/nginx.mba-percentage": "101",
+			},
+			containerName: "nginx",
+			expectedErrors: []string{
+				`annotation nginx_mba_mbps: invalid value "fast", must be an integer`,
+				"annotation nginx_policy: value must not be empty",
+				"annotation rmd.intel.com/nginx.mba-percentage: invalid value 101, must be between 1 and 100",
+			},
+		},
+		{
+			name: "test case 7 - unknown namespaced setting",
+			annotations: map[string]string{
+				"rmd.intel.com/nginx.mba_percentage": "50",
+			},
+			containerName: "nginx",
+			expectedErrors: []string{
+				`annotation rmd.intel.com/nginx.mba_percentage: unknown setting "mba_percentage"`,
+			},
+		},
+		{
+			name: "test case 8 - invalid pod-level annotation",
+			annotations: map[string]string{
+				"nginx_policy": "gold",
+				ContainersKey:  `{"nginx": {"mbaPercent": 50}}`,
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				Policy: stringPtr("gold"),
+			},
+			expectedErrors: []string{
+				`annotation rmd.intel.com/containers: json: unknown field "mbaPercent"`,
+			},
+		},
+		{
+			name: "test case 9 - invalid values in pod-level annotation",
+			annotations: map[string]string{
+				ContainersKey: `{"nginx": {"policy": "", "mbaPercentage": 0, "mbaMbps": 100}}`,
+			},
+			containerName: "nginx",
+			expectedContainer: Container{
+				MbaMbps: intPtr(100),
+			},
+			expectedErrors: []string{
+				"annotation rmd.intel.com/containers: container nginx: mbaPercentage invalid value 0, must be between 1 and 100, policy value must not be empty",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-1",
+				Namespace:   "default",
+				Annotations: tc.annotations,
+			},
+		}
+		container, errs := ForContainer(pod, tc.containerName)
+		if !reflect.DeepEqual(container, tc.expectedContainer) {
+			t.Errorf("%v failed: Expected %+v, got %+v", tc.name, tc.expectedContainer, container)
+		}
+		messages := make([]string, 0)
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		if strings.Join(messages, "\n") != strings.Join(tc.expectedErrors, "\n") {
+			t.Errorf("%v failed: Expected errors %v, got %v", tc.name, tc.expectedErrors, messages)
+		}
+	}
+}
+
+func TestWorkloadMode(t *testing.T) {
+	tcases := []struct {
+		name          string
+		annotations   map[string]string
+		expectedMode  string
+		expectedError string
+	}{
+		{
+			name:         "test case 1 - no workload mode",
+			expectedMode: ContainerWorkloadMode,
+		},
+		{
+			name:         "test case 2 - pod workload mode",
+			annotations:  map[string]string{WorkloadModeKey: "pod"},
+			expectedMode: PodWorkloadMode,
+		},
+		{
+			name:         "test case 3 - container workload mode",
+			annotations:  map[string]string{WorkloadModeKey: "container"},
+			expectedMode: ContainerWorkloadMode,
+		},
+		{
+			name:          "test case 4 - invalid workload mode",
+			annotations:   map[string]string{WorkloadModeKey: "Pod"},
+			expectedMode:  ContainerWorkloadMode,
+			expectedError: `annotation rmd.intel.com/workload-mode: unknown workload mode "Pod", must be container or pod`,
+		},
+	}
+	for _, tc := range tcases {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-1",
+				Namespace:   "default",
+				Annotations: tc.annotations,
+			},
+		}
+		mode, err := WorkloadMode(pod)
+		if mode != tc.expectedMode {
+			t.Errorf("%v failed: Expected mode %v, got %v", tc.name, tc.expectedMode, mode)
+		}
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != tc.expectedError {
+			t.Errorf("%v failed: Expected error %q, got %q", tc.name, tc.expectedError, message)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package podresourcesclient
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// The k8s.io/kubelet podresources v1 package used by this module predates the container memory
+// of the kubelet v1 PodResourcesLister service. The messages below mirror pkg/apis/podresources/v1/api.proto of later releases, devices and topology reuse
+// the generated types. Field numbers must be kept in line with api.proto.
+
+const listMethod = "/v1.PodResourcesLister/List"
+
+// ListPodResourcesResponse is the response returned by List function
+type ListPodResourcesResponse struct {
+	PodResources []*PodResources `protobuf:"bytes,1,rep,name=pod_resources,json=podResources,proto3" json:"pod_resources,omitempty"`
+}
+
+func (m *ListPodResourcesResponse) Reset()         { *m = ListPodResourcesResponse{} }
+func (m *ListPodResourcesResponse) String() string { return proto.CompactTextString(m) }
+func (*ListPodResourcesResponse) ProtoMessage()    {}
+
+// PodResources contains information about the node resources assigned to a pod
+type PodResources struct {
+	Name       string                `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace  string                `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Containers []*ContainerResources `protobuf:"bytes,3,rep,name=containers,proto3" json:"containers,omitempty"`
+}
+
+func (m *PodResources) Reset()         { *m = PodResources{} }
+func (m *PodResources) String() string { return proto.CompactTextString(m) }
+func (*PodResources) ProtoMessage()    {}
+
+// ContainerResources contains information about the resources assigned to a container
+type ContainerResources struct {
+	Name    string                              `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Devices []*podresourcesapi.ContainerDevices `protobuf:"bytes,2,rep,name=devices,proto3" json:"devices,omitempty"`
+	CpuIds  []int64                             `protobuf:"varint,3,rep,packed,name=cpu_ids,json=cpuIds,proto3" json:"cpu_ids,omitempty"`
+	Memory  []*ContainerMemory                  `protobuf:"bytes,4,rep,name=memory,proto3" json:"memory,omitempty"`
+}
+
+func (m *ContainerResources) Reset()         { *m = ContainerResources{} }
+func (m *ContainerResources) String() string { return proto.CompactTextString(m) }
+func (*ContainerResources) ProtoMessage()    {}
+
+// ContainerMemory contains information about memory and hugepages assigned to a container
+type ContainerMemory struct {
+	MemoryType string                        `protobuf:"bytes,1,opt,name=memory_type,json=memoryType,proto3" json:"memory_type,omitempty"`
+	Size_      uint64                        `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Topology   *podresourcesapi.TopologyInfo `protobuf:"bytes,3,opt,name=topology,proto3" json:"topology,omitempty"`
+}
+
+func (m *ContainerMemory) Reset()         { *m = ContainerMemory{} }
+func (m *ContainerMemory) String() string { return proto.CompactTextString(m) }
+func (*ContainerMemory) ProtoMessage()    {}
+
+// PodResourcesListerClient is the client API of the kubelet v1 PodResourcesLister service
+type PodResourcesListerClient interface {
+	List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*ListPodResourcesResponse, error)
+}
+
+type podResourcesListerClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewPodResourcesListerClient returns a PodResourcesListerClient using cc
+func NewPodResourcesListerClient(cc *grpc.ClientConn) PodResourcesListerClient {
+	return &podResourcesListerClient{cc}
+}
+
+func (c *podResourcesListerClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*ListPodResourcesResponse, error) {
+	out := new(ListPodResourcesResponse)
+	err := c.cc.Invoke(ctx, listMethod, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -13,17 +13,54 @@ import (
 	"time"
 )
 
+const (
+	// DefaultSocket is the kubelet PodResources API socket
+	DefaultSocket = "unix:///var/lib/kubelet/pod-resources/kubelet.sock"
+	// DefaultTimeout is the timeout for connecting to the kubelet PodResources API socket
+	DefaultTimeout = 10 * time.Second
+)
+
 var maxMessage = 1024 * 1024 * 4 // size in bytes => 4MB
-var socket = "unix:///var/lib/kubelet/pod-resources/kubelet.sock"
-var timeout = 10 * time.Second
 
 // PodResourcesClient stores a client to the Kubelet PodResources API server
 type PodResourcesClient struct {
-	Client podresourcesapi.PodResourcesListerClient
+	Client PodResourcesListerClient
+}
+
+// Resources are node resources assigned to a container
+type Resources struct {
+	// CPUs are the exclusive CPUs in ascending order
+	CPUs []string
+	// Devices are the devices of each device plugin resource, e.g. intel.com/l3_cache_ways
+	Devices map[string][]Device
+	// Memory is the memory and hugepages pinned by the memory manager
+	Memory []Memory
+	// NUMANodes are the NUMA nodes of the devices and memory in ascending order
+	NUMANodes []int64
+}
+
+// Device is a device plugin device with the NUMA nodes it is attached to. NUMANodes is empty if
+// the device plugin does not report topology.
+type Device struct {
+	ID        string
+	NUMANodes []int64
+}
+
+// Memory is an amount of memory of memoryType on NUMA nodes
+type Memory struct {
+	MemoryType string
+	Size       uint64
+	NUMANodes  []int64
 }
 
-// NewPodResourcesClient returns a new client to the Kubelet PodResources API server
+// NewPodResourcesClient returns a new client to the Kubelet PodResources API server at DefaultSocket
 func NewPodResourcesClient() (*PodResourcesClient, error) {
+	return NewPodResourcesClientForSocket(DefaultSocket, DefaultTimeout)
+}
+
+// NewPodResourcesClientForSocket returns a new client to the Kubelet PodResources API server at socket,
+// connecting within timeout
+func NewPodResourcesClientForSocket(socket string, timeout time.Duration) (*PodResourcesClient, error) {
 	logger := logf.Log.WithName("NewPodResourcesClient")
 	podResourcesClient := &PodResourcesClient{}
 	client, err := getV1Client(socket, timeout, maxMessage)
@@ -36,7 +73,7 @@ func NewPodResourcesClient() (*PodResourcesClient, error) {
 }
 
 // getV1Client returns a client for the PodResourcesLister grpc service
-func getV1Client(socket string, connectionTimeout time.Duration, maxMsgSize int) (podresourcesapi.PodResourcesListerClient, error) {
+func getV1Client(socket string, connectionTimeout time.Duration, maxMsgSize int) (PodResourcesListerClient, error) {
 	addr, dialer, err := util.GetAddressAndDialer(socket)
 	if err != nil {
 		return nil, err
@@ -48,37 +85,95 @@ func getV1Client(socket string, connectionTimeout time.Duration, maxMsgSize int)
 	if err != nil {
 		return nil, errors.NewServiceUnavailable(fmt.Sprintf("error dialing socket %s: %v", socket, err))
 	}
-	return podresourcesapi.NewPodResourcesListerClient(conn), nil
+	return NewPodResourcesListerClient(conn), nil
 }
 
-func (p *PodResourcesClient) listPodResources() (*podresourcesapi.ListPodResourcesResponse, error) {
+func (p *PodResourcesClient) listPodResources() (*ListPodResourcesResponse, error) {
 	logger := logf.Log.WithName("listPodResources")
 	req := podresourcesapi.ListPodResourcesRequest{}
 	resp, err := p.Client.List(context.TODO(), &req)
 	if err != nil {
 		logger.Error(err, "Can't receive response from endpoint")
-		return &podresourcesapi.ListPodResourcesResponse{}, err
+		return &ListPodResourcesResponse{}, err
 	}
 	logger.Info("received response from endpoint", "resp", resp)
 	return resp, nil
 }
 
-// GetContainerCPUs returns a []string of CPUs allocated to the container
-func (p *PodResourcesClient) GetContainerCPUs(podName, containerName string) ([]string, error) {
+// GetContainerResources returns the resources allocated to container containerName of pod podName in namespace
+func (p *PodResourcesClient) GetContainerResources(namespace, podName, containerName string) (*Resources, error) {
 	podresourcesResponse, err := p.listPodResources()
 	if err != nil {
 		return nil, err
 	}
 	for _, podresource := range podresourcesResponse.PodResources {
-		if podresource.Name == podName {
-			for _, container := range podresource.Containers {
-				if container.Name == containerName {
-					return cpuIDsToStringSlice(container.CpuIds), nil
-				}
+		if podresource.Namespace != namespace || podresource.Name != podName {
+			continue
+		}
+		for _, container := range podresource.Containers {
+			if container.Name == containerName {
+				return toResources(container.CpuIds, container.Devices, container.Memory), nil
 			}
 		}
 	}
-	return nil, errors.NewServiceUnavailable(fmt.Sprintf("cpus for Pod:%v Container:%v not found", podName, containerName))
+	return nil, errors.NewServiceUnavailable(fmt.Sprintf("resources for Pod:%v/%v Container:%v not found", namespace, podName, containerName))
+}
+
+// GetContainerCPUs returns a []string of CPUs allocated to the container
+func (p *PodResourcesClient) GetContainerCPUs(namespace, podName, containerName string) ([]string, error) {
+	resources, err := p.GetContainerResources(namespace, podName, containerName)
+	if err != nil {
+		return nil, err
+	}
+	return resources.CPUs, nil
+}
+
+// toResources converts podresources CPUs, devices and memory to Resources
+func toResources(cpuIds []int64, devices []*podresourcesapi.ContainerDevices, memory []*ContainerMemory) *Resources {
+	resources := &Resources{
+		CPUs:    cpuIDsToStringSlice(cpuIds),
+		Devices: make(map[string][]Device),
+		Memory:  make([]Memory, 0),
+	}
+	numaNodes := make(map[int64]bool)
+	for _, device := range devices {
+		nodes := topologyNodes(device.Topology)
+		for _, deviceID := range device.DeviceIds {
+			resources.Devices[device.ResourceName] = append(resources.Devices[device.ResourceName], Device{ID: deviceID, NUMANodes: nodes})
+		}
+		for _, node := range nodes {
+			numaNodes[node] = true
+		}
+	}
+	for _, devices := range resources.Devices {
+		sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	}
+	for _, containerMemory := range memory {
+		nodes := topologyNodes(containerMemory.Topology)
+		resources.Memory = append(resources.Memory, Memory{MemoryType: containerMemory.MemoryType, Size: containerMemory.Size_, NUMANodes: nodes})
+		for _, node := range nodes {
+			numaNodes[node] = true
+		}
+	}
+	resources.NUMANodes = make([]int64, 0)
+	for node := range numaNodes {
+		resources.NUMANodes = append(resources.NUMANodes, node)
+	}
+	sort.Slice(resources.NUMANodes, func(i, j int) bool { return resources.NUMANodes[i] < resources.NUMANodes[j] })
+	return resources
+}
+
+// topologyNodes returns the NUMA node IDs of topology in ascending order
+func topologyNodes(topology *podresourcesapi.TopologyInfo) []int64 {
+	nodes := make([]int64, 0)
+	if topology == nil {
+		return nodes
+	}
+	for _, node := range topology.Nodes {
+		nodes = append(nodes, node.ID)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes
 }
 
 // cpuIDsToStringSlice returns a string in cpuset format
@@ -0,0 +1,158 @@
+package podresourcesclient
+
+import (
+	"context"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+	"reflect"
+	"testing"
+)
+
+// fakeListerClient returns a fixed List response
+type fakeListerClient struct {
+	listResponse *ListPodResourcesResponse
+}
+
+func (f *fakeListerClient) List(ctx context.Context, in *podresourcesapi.ListPodResourcesRequest, opts ...grpc.CallOption) (*ListPodResourcesResponse, error) {
+	return f.listResponse, nil
+}
+
+func numaTopology(ids ...int64) *podresourcesapi.TopologyInfo {
+	topology := &podresourcesapi.TopologyInfo{}
+	for _, id := range ids {
+		topology.Nodes = append(topology.Nodes, &podresourcesapi.NUMANode{ID: id})
+	}
+	return topology
+}
+
+func createPodResourcesClient() *PodResourcesClient {
+	return &PodResourcesClient{
+		Client: &fakeListerClient{
+			listResponse: &ListPodResourcesResponse{
+				PodResources: []*PodResources{
+					{
+						Name:      "pod-1",
+						Namespace: "default",
+						Containers: []*ContainerResources{
+							{
+								Name:   "container-1",
+								CpuIds: []int64{5, 4},
+								Devices: []*podresourcesapi.ContainerDevices{
+									{ResourceName: "intel.com/l3_cache_ways", DeviceIds: []string{"way-3", "way-2"}, Topology: numaTopology(1)},
+								},
+								Memory: []*ContainerMemory{
+									{MemoryType: "hugepages-1Gi", Size_: 1073741824, Topology: numaTopology(1)},
+								},
+							},
+						},
+					},
+					{
+						Name:      "pod-1",
+						Namespace: "test",
+						Containers: []*ContainerResources{
+							{Name: "container-1", CpuIds: []int64{0, 1}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetContainerResources(t *testing.T) {
+	p := createPodResourcesClient()
+	tcases := []struct {
+		name              string
+		namespace         string
+		podName           string
+		containerName     string
+		expectedResources *Resources
+		expectedError     bool
+	}{
+		{
+			name:          "test case 1 - cpus, devices and memory",
+			namespace:     "default",
+			podName:       "pod-1",
+			containerName: "container-1",
+			expectedResources: &Resources{
+				CPUs:      []string{"4", "5"},
+				Devices:   map[string][]Device{"intel.com/l3_cache_ways": {{ID: "way-2", NUMANodes: []int64{1}}, {ID: "way-3", NUMANodes: []int64{1}}}},
+				Memory:    []Memory{{MemoryType: "hugepages-1Gi", Size: 1073741824, NUMANodes: []int64{1}}},
+				NUMANodes: []int64{1},
+			},
+		},
+		{
+			name:          "test case 2 - same pod name in other namespace",
+			namespace:     "test",
+			podName:       "pod-1",
+			containerName: "container-1",
+			expectedResources: &Resources{
+				CPUs:      []string{"0", "1"},
+				Devices:   map[string][]Device{},
+				Memory:    []Memory{},
+				NUMANodes: []int64{},
+			},
+		},
+		{
+			name:          "test case 3 - pod not in namespace",
+			namespace:     "kube-system",
+			podName:       "pod-1",
+			containerName: "container-1",
+			expectedError: true,
+		},
+		{
+			name:          "test case 4 - container not found",
+			namespace:     "default",
+			podName:       "pod-1",
+			containerName: "container-2",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tcases {
+		resources, err := p.GetContainerResources(tc.namespace, tc.podName, tc.containerName)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("%v failed: Expected error %v, got %v", tc.name, tc.expectedError, err)
+		}
+		if !reflect.DeepEqual(resources, tc.expectedResources) {
+			t.Errorf("%v failed: Expected %+v, got %+v", tc.name, tc.expectedResources, resources)
+		}
+	}
+}
+
+// TestListPodResourcesResponseWire checks that a List response encoded with the generated
+// podresources types is decoded by the mirrored messages.
+func TestListPodResourcesResponseWire(t *testing.T) {
+	generated := &podresourcesapi.ListPodResourcesResponse{
+		PodResources: []*podresourcesapi.PodResources{
+			{
+				Name:      "pod-1",
+				Namespace: "default",
+				Containers: []*podresourcesapi.ContainerResources{
+					{
+						Name:   "container-1",
+						CpuIds: []int64{4, 5},
+						Devices: []*podresourcesapi.ContainerDevices{
+							{ResourceName: "intel.com/l3_cache_ways", DeviceIds: []string{"way-2"}, Topology: numaTopology(1)},
+						},
+					},
+				},
+			},
+		},
+	}
+	data, err := gogoproto.Marshal(generated)
+	if err != nil {
+		t.Fatalf("error marshalling response: (%v)", err)
+	}
+	mirrored := &ListPodResourcesResponse{}
+	err = proto.Unmarshal(data, mirrored)
+	if err != nil {
+		t.Fatalf("error unmarshalling response: (%v)", err)
+	}
+	container := mirrored.PodResources[0].Containers[0]
+	if mirrored.PodResources[0].Namespace != "default" || container.Name != "container-1" || !reflect.DeepEqual(container.CpuIds, []int64{4, 5}) ||
+		container.Devices[0].DeviceIds[0] != "way-2" || container.Devices[0].Topology.Nodes[0].ID != 1 {
+		t.Errorf("Expected %v, got %v", generated, mirrored)
+	}
+}
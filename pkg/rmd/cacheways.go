@@ -0,0 +1,88 @@
+package rmd
+
+import (
+	"fmt"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// CacheRequestedInBytes returns true if the cache of the workload is requested by size instead of ways.
+func CacheRequestedInBytes(cache intelv1alpha1.Cache) bool {
+	return cache.MaxBytes != nil || cache.MinBytes != nil
+}
+
+// ResolveCacheWays converts the MaxBytes and MinBytes of cache to max and min ways on the node
+// described by allCacheInfo. The smallest l3 way size on the node is used so that the requested
+// size is met on every cache when rounding up.
+func ResolveCacheWays(cache intelv1alpha1.Cache, allCacheInfo rmdCache.Infos) (int, int, error) {
+	if !CacheRequestedInBytes(cache) {
+		return cache.Max, cache.Min, nil
+	}
+	if cache.Max != 0 || cache.Min != 0 {
+		return 0, 0, errors.NewBadRequest("cache max and min cannot be combined with maxBytes and minBytes")
+	}
+	if cache.MaxBytes == nil {
+		return 0, 0, errors.NewBadRequest("cache minBytes requires maxBytes")
+	}
+
+	var waySize, numWays uint32
+	for _, info := range allCacheInfo.Caches {
+		if info.WaySize == 0 {
+			continue
+		}
+		if waySize == 0 || info.WaySize < waySize {
+			waySize = info.WaySize
+		}
+		if numWays == 0 || info.NumWays < numWays {
+			numWays = info.NumWays
+		}
+	}
+	if waySize == 0 {
+		return 0, 0, errors.NewServiceUnavailable("l3 cache way size not available")
+	}
+
+	maxWays, err := bytesToWays(*cache.MaxBytes, waySize, numWays, cache.Rounding)
+	if err != nil {
+		return 0, 0, err
+	}
+	minWays := maxWays
+	if cache.MinBytes != nil {
+		minWays, err = bytesToWays(*cache.MinBytes, waySize, numWays, cache.Rounding)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if minWays > maxWays {
+		return 0, 0, errors.NewBadRequest(fmt.Sprintf("cache minBytes %s resolves to more ways than maxBytes %s", cache.MinBytes.String(), cache.MaxBytes.String()))
+	}
+	return maxWays, minWays, nil
+}
+
+// bytesToWays converts size to a number of ways of waySize bytes according to rounding.
+func bytesToWays(size resource.Quantity, waySize, numWays uint32, rounding intelv1alpha1.CacheRounding) (int, error) {
+	bytes := size.Value()
+	if bytes <= 0 {
+		return 0, errors.NewBadRequest(fmt.Sprintf("cache size %s not supported", size.String()))
+	}
+	var ways int64
+	switch rounding {
+	case intelv1alpha1.RoundUp, "":
+		ways = (bytes + int64(waySize) - 1) / int64(waySize)
+	case intelv1alpha1.RoundDown:
+		ways = bytes / int64(waySize)
+	case intelv1alpha1.RoundNearest:
+		ways = (bytes + int64(waySize)/2) / int64(waySize)
+	default:
+		return 0, errors.NewBadRequest(fmt.Sprintf("cache rounding %s not supported", rounding))
+	}
+	if ways == 0 {
+		return 0, errors.NewBadRequest(fmt.Sprintf("cache size %s is less than one way of %d bytes", size.String(), waySize))
+	}
+	if ways > int64(numWays) {
+		return 0, errors.NewBadRequest(fmt.Sprintf("cache size %s exceeds %d ways of %d bytes", size.String(), numWays, waySize))
+	}
+	return int(ways), nil
+}
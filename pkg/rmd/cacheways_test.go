@@ -0,0 +1,100 @@
+package rmd
+
+import (
+	"testing"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestResolveCacheWays(t *testing.T) {
+	// 1.5MiB ways, 11 ways per cache.
+	allCacheInfo := rmdCache.Infos{
+		Num: 2,
+		Caches: map[uint32]rmdCache.Info{
+			0: {ID: 0, NumWays: 11, WaySize: 1572864},
+			1: {ID: 1, NumWays: 11, WaySize: 1572864},
+		},
+	}
+	quantity := func(size string) *resource.Quantity {
+		q := resource.MustParse(size)
+		return &q
+	}
+	tcases := []struct {
+		name         string
+		cache        intelv1alpha1.Cache
+		allCacheInfo rmdCache.Infos
+		expectedMax  int
+		expectedMin  int
+		expectedErr  bool
+	}{
+		{
+			name:         "test case 1 - ways requested",
+			cache:        intelv1alpha1.Cache{Max: 2, Min: 2},
+			allCacheInfo: allCacheInfo,
+			expectedMax:  2,
+			expectedMin:  2,
+		},
+		{
+			name:         "test case 2 - round up by default, min defaults to max",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("4Mi")},
+			allCacheInfo: allCacheInfo,
+			expectedMax:  3,
+			expectedMin:  3,
+		},
+		{
+			name:         "test case 3 - round down",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("8Mi"), MinBytes: quantity("4Mi"), Rounding: intelv1alpha1.RoundDown},
+			allCacheInfo: allCacheInfo,
+			expectedMax:  5,
+			expectedMin:  2,
+		},
+		{
+			name:         "test case 4 - round nearest",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("4Mi"), Rounding: intelv1alpha1.RoundNearest},
+			allCacheInfo: allCacheInfo,
+			expectedMax:  3,
+			expectedMin:  3,
+		},
+		{
+			name:         "test case 5 - round down to zero ways",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("1Mi"), Rounding: intelv1alpha1.RoundDown},
+			allCacheInfo: allCacheInfo,
+			expectedErr:  true,
+		},
+		{
+			name:         "test case 6 - size exceeds cache",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("20Mi")},
+			allCacheInfo: allCacheInfo,
+			expectedErr:  true,
+		},
+		{
+			name:         "test case 7 - ways and bytes combined",
+			cache:        intelv1alpha1.Cache{Max: 2, Min: 2, MaxBytes: quantity("4Mi")},
+			allCacheInfo: allCacheInfo,
+			expectedErr:  true,
+		},
+		{
+			name:         "test case 8 - way size not available",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("4Mi")},
+			allCacheInfo: rmdCache.Infos{},
+			expectedErr:  true,
+		},
+		{
+			name:         "test case 9 - unknown rounding",
+			cache:        intelv1alpha1.Cache{MaxBytes: quantity("4Mi"), Rounding: "Sideways"},
+			allCacheInfo: allCacheInfo,
+			expectedErr:  true,
+		},
+	}
+	for _, tc := range tcases {
+		maxWays, minWays, err := ResolveCacheWays(tc.cache, tc.allCacheInfo)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error: %v, Error gotten: %v\n", tc.name, tc.expectedErr, err)
+		}
+		if maxWays != tc.expectedMax || minWays != tc.expectedMin {
+			t.Errorf("%v failed: Expected max %v min %v, Got max %v min %v\n", tc.name, tc.expectedMax, tc.expectedMin, maxWays, minWays)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package rmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdMba "github.com/intel/rmd/modules/mba"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+const maxMbaPercentage = 100
+
+// ResolveCoreIDs returns the cores targeted by workloadCR on the node described by allCacheInfo.
+// If AllCores is set, all cores sharing an l3 cache on the node minus ReservedCoreIds are returned.
+func ResolveCoreIDs(workloadCR *intelv1alpha1.RmdWorkload, allCacheInfo rmdCache.Infos) ([]string, error) {
+	if !workloadCR.Spec.AllCores {
+		return workloadCR.Spec.CoreIds, nil
+	}
+	allCoresCPUSet, err := hostCPUs(allCacheInfo)
+	if err != nil {
+		return nil, err
+	}
+	if len(workloadCR.Spec.ReservedCoreIds) != 0 {
+		rsvdCPUSet, err := cpuset.Parse(strings.Join(workloadCR.Spec.ReservedCoreIds, ","))
+		if err != nil {
+			return nil, err
+		}
+		allCoresCPUSet = allCoresCPUSet.Difference(rsvdCPUSet)
+	}
+	return []string{allCoresCPUSet.String()}, nil
+}
+
+// cachePool returns the RMD cache pool a max/min cache request is allocated from and the
+// number of ways that must be free in that pool for the request to succeed.
+func cachePool(maxCache, minCache uint32) (string, uint32, error) {
+	switch {
+	case maxCache == 0:
+		return "shared", 0, nil
+	case maxCache == minCache:
+		return guaranteedPool, maxCache, nil
+	case maxCache > minCache && minCache != 0:
+		return besteffortPool, minCache, nil
+	}
+	return "", 0, errors.NewBadRequest(fmt.Sprintf("cache max %d and min %d not supported", maxCache, minCache))
+}
+
+// CheckCacheCapacity verifies that the l3 cache ways requested by workloadCR fit into the free ways of
+// the matching RMD pool on every cache shared by coreIDs. Ways held by existingWorkload are credited
+// back so that an update is not rejected because of its own allocation. Policy based and shared pool
+// requests are not checked as RMD does not reserve ways for them up front.
+func CheckCacheCapacity(workloadCR *intelv1alpha1.RmdWorkload, coreIDs []string, allCacheInfo rmdCache.Infos, existingWorkload *rmdtypes.RDTWorkLoad) error {
+	if workloadCR.Spec.Policy != "" || workloadCR.Spec.Rdt.Cache.Max == 0 {
+		return nil
+	}
+	pool, requestedWays, err := cachePool(uint32(workloadCR.Spec.Rdt.Cache.Max), uint32(workloadCR.Spec.Rdt.Cache.Min))
+	if err != nil {
+		return err
+	}
+	coreCPUSet, err := cpuset.Parse(strings.Join(coreIDs, ","))
+	if err != nil {
+		return err
+	}
+
+	existingCPUSet, creditedWays, err := existingPoolWays(existingWorkload, pool)
+	if err != nil {
+		return err
+	}
+	return checkPoolWays("cache", pool, requestedWays, coreCPUSet, allCacheInfo, existingCPUSet, creditedWays)
+}
+
+// existingPoolWays returns the cores of existingWorkload and the l3 cache ways it holds in pool. Ways
+// already allocated to a workload in the same pool are returned on update.
+func existingPoolWays(existingWorkload *rmdtypes.RDTWorkLoad, pool string) (cpuset.CPUSet, uint32, error) {
+	if existingWorkload == nil || existingWorkload.Rdt.Cache.Max == nil || existingWorkload.Rdt.Cache.Min == nil {
+		return cpuset.NewCPUSet(), 0, nil
+	}
+	existingPool, existingWays, err := cachePool(*existingWorkload.Rdt.Cache.Max, *existingWorkload.Rdt.Cache.Min)
+	if err != nil || existingPool != pool {
+		return cpuset.NewCPUSet(), 0, nil
+	}
+	existingCPUSet, err := cpuset.Parse(strings.Join(existingWorkload.CoreIDs, ","))
+	if err != nil {
+		return cpuset.NewCPUSet(), 0, err
+	}
+	return existingCPUSet, existingWays, nil
+}
+
+// checkPoolWays verifies that requestedWays are free in pool on every cache shared by coreCPUSet.
+// creditedWays are added to the free ways of caches shared by existingCPUSet.
+func checkPoolWays(level, pool string, requestedWays uint32, coreCPUSet cpuset.CPUSet, allCacheInfo rmdCache.Infos, existingCPUSet cpuset.CPUSet, creditedWays uint32) error {
+	cacheIDs := make([]int, 0)
+	for cacheID := range allCacheInfo.Caches {
+		cacheIDs = append(cacheIDs, int(cacheID))
+	}
+	sort.Ints(cacheIDs)
+
+	shortfalls := make([]string, 0)
+	for _, cacheID := range cacheIDs {
+		cache := allCacheInfo.Caches[uint32(cacheID)]
+		shareCPUSet, err := cpuset.Parse(cache.ShareCPUList)
+		if err != nil {
+			return err
+		}
+		if shareCPUSet.Intersection(coreCPUSet).IsEmpty() {
+			continue
+		}
+		poolWays, err := cpuset.Parse(cache.AvailableWaysPool[pool])
+		if err != nil {
+			return err
+		}
+		availableWays := uint32(poolWays.Size())
+		if !shareCPUSet.Intersection(existingCPUSet).IsEmpty() {
+			availableWays = availableWays + creditedWays
+		}
+		if availableWays < requestedWays {
+			shortfalls = append(shortfalls, fmt.Sprintf("cache %d: requested %d %s ways, %d available", cacheID, requestedWays, pool, availableWays))
+		}
+	}
+	if len(shortfalls) != 0 {
+		return errors.NewBadRequest(fmt.Sprintf("insufficient %s capacity: %s", level, strings.Join(shortfalls, "; ")))
+	}
+	return nil
+}
+
+// FreeGuaranteedWays returns the number of guaranteed l3 cache ways free on all caches of the node
+// described by allCacheInfo.
+func FreeGuaranteedWays(allCacheInfo rmdCache.Infos) int {
+	freeWays := 0
+	for _, cache := range allCacheInfo.Caches {
+		poolWays, err := cpuset.Parse(cache.AvailableWaysPool[guaranteedPool])
+		if err != nil {
+			continue
+		}
+		freeWays += poolWays.Size()
+	}
+	return freeWays
+}
+
+// CheckMbaCapacity verifies that the MBA requested by workloadCR can be applied on a node with mbaInfo.
+func CheckMbaCapacity(workloadCR *intelv1alpha1.RmdWorkload, mbaInfo rmdMba.Info) error {
+	mba := workloadCR.Spec.Rdt.Mba
+	if mba.Percentage == 0 && mba.Mbps == 0 {
+		return nil
+	}
+	if !mbaInfo.Mba || !mbaInfo.MbaOn {
+		return errors.NewBadRequest("insufficient MBA capacity: MBA not supported or not enabled")
+	}
+	if workloadCR.Spec.Policy == "" && (workloadCR.Spec.Rdt.Cache.Max == 0 || workloadCR.Spec.Rdt.Cache.Max != workloadCR.Spec.Rdt.Cache.Min) {
+		return errors.NewBadRequest("insufficient MBA capacity: MBA only supported for guaranteed cache requests")
+	}
+	if mba.Percentage != 0 && (mba.Percentage < mbaInfo.MbaMin || mba.Percentage > maxMbaPercentage) {
+		return errors.NewBadRequest(fmt.Sprintf("insufficient MBA capacity: percentage %d outside range %d-%d", mba.Percentage, mbaInfo.MbaMin, maxMbaPercentage))
+	}
+	return nil
+}
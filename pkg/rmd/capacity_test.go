@@ -0,0 +1,264 @@
+package rmd
+
+import (
+	"reflect"
+	"testing"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdMba "github.com/intel/rmd/modules/mba"
+	rmdtypes "github.com/intel/rmd/modules/workload/types"
+)
+
+func capacityTestCacheInfo() rmdCache.Infos {
+	return rmdCache.Infos{
+		Num: 2,
+		Caches: map[uint32]rmdCache.Info{
+			0: {
+				ID:           0,
+				ShareCPUList: "0-7",
+				AvailableWaysPool: map[string]string{
+					"guaranteed": "0-10",
+					"besteffort": "11-13",
+				},
+			},
+			1: {
+				ID:           1,
+				ShareCPUList: "8-15",
+				AvailableWaysPool: map[string]string{
+					"guaranteed": "0-3",
+					"besteffort": "",
+				},
+			},
+		},
+	}
+}
+
+func TestResolveCoreIDs(t *testing.T) {
+	tcases := []struct {
+		name            string
+		workloadCR      *intelv1alpha1.RmdWorkload
+		expectedCoreIDs []string
+		expectedErr     bool
+	}{
+		{
+			name: "test case 1 - core IDs",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreIds: []string{"0", "1"},
+				},
+			},
+			expectedCoreIDs: []string{"0", "1"},
+		},
+		{
+			name: "test case 2 - all cores",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					AllCores: true,
+				},
+			},
+			expectedCoreIDs: []string{"0-15"},
+		},
+		{
+			name: "test case 3 - all cores with reserved cores",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					AllCores:        true,
+					ReservedCoreIds: []string{"0-1", "8"},
+				},
+			},
+			expectedCoreIDs: []string{"2-7,9-15"},
+		},
+		{
+			name: "test case 4 - invalid reserved cores",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					AllCores:        true,
+					ReservedCoreIds: []string{"a"},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		coreIDs, err := ResolveCoreIDs(tc.workloadCR, capacityTestCacheInfo())
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Case %v - Expected error %v, got %v", tc.name, tc.expectedErr, err)
+		}
+		if !tc.expectedErr && !reflect.DeepEqual(coreIDs, tc.expectedCoreIDs) {
+			t.Errorf("Case %v - Expected core IDs %v, got %v", tc.name, tc.expectedCoreIDs, coreIDs)
+		}
+	}
+}
+
+func TestCheckCacheCapacity(t *testing.T) {
+	existingMax := uint32(4)
+	existingMin := uint32(4)
+	existingWorkload := &rmdtypes.RDTWorkLoad{
+		UUID:    "rmd-workload-1",
+		CoreIDs: []string{"8", "9"},
+	}
+	existingWorkload.Rdt.Cache.Max = &existingMax
+	existingWorkload.Rdt.Cache.Min = &existingMin
+
+	tcases := []struct {
+		name             string
+		cache            intelv1alpha1.Cache
+		policy           string
+		coreIDs          []string
+		existingWorkload *rmdtypes.RDTWorkLoad
+		expectedErr      bool
+	}{
+		{
+			name:    "test case 1 - guaranteed request fits",
+			cache:   intelv1alpha1.Cache{Max: 11, Min: 11},
+			coreIDs: []string{"0", "1"},
+		},
+		{
+			name:        "test case 2 - guaranteed request exceeds pool",
+			cache:       intelv1alpha1.Cache{Max: 20, Min: 20},
+			coreIDs:     []string{"0", "1"},
+			expectedErr: true,
+		},
+		{
+			name:        "test case 3 - guaranteed request exceeds pool on second cache only",
+			cache:       intelv1alpha1.Cache{Max: 6, Min: 6},
+			coreIDs:     []string{"0", "8"},
+			expectedErr: true,
+		},
+		{
+			name:    "test case 4 - besteffort request fits min ways",
+			cache:   intelv1alpha1.Cache{Max: 6, Min: 3},
+			coreIDs: []string{"0-3"},
+		},
+		{
+			name:        "test case 5 - besteffort request exceeds pool",
+			cache:       intelv1alpha1.Cache{Max: 6, Min: 2},
+			coreIDs:     []string{"8"},
+			expectedErr: true,
+		},
+		{
+			name:    "test case 6 - shared request not checked",
+			cache:   intelv1alpha1.Cache{Max: 0, Min: 0},
+			coreIDs: []string{"8"},
+		},
+		{
+			name:    "test case 7 - policy request not checked",
+			cache:   intelv1alpha1.Cache{Max: 20, Min: 20},
+			policy:  "gold",
+			coreIDs: []string{"0"},
+		},
+		{
+			name:             "test case 8 - update credits existing allocation",
+			cache:            intelv1alpha1.Cache{Max: 6, Min: 6},
+			coreIDs:          []string{"8", "9"},
+			existingWorkload: existingWorkload,
+		},
+		{
+			name:             "test case 9 - update exceeds pool with existing allocation",
+			cache:            intelv1alpha1.Cache{Max: 9, Min: 9},
+			coreIDs:          []string{"8", "9"},
+			existingWorkload: existingWorkload,
+			expectedErr:      true,
+		},
+		{
+			name:        "test case 10 - min greater than max",
+			cache:       intelv1alpha1.Cache{Max: 2, Min: 4},
+			coreIDs:     []string{"0"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		workloadCR := &intelv1alpha1.RmdWorkload{}
+		workloadCR.Spec.Rdt.Cache = tc.cache
+		workloadCR.Spec.Policy = tc.policy
+		err := CheckCacheCapacity(workloadCR, tc.coreIDs, capacityTestCacheInfo(), tc.existingWorkload)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Case %v - Expected error %v, got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestFreeGuaranteedWays(t *testing.T) {
+	tcases := []struct {
+		name         string
+		allCacheInfo rmdCache.Infos
+		expected     int
+	}{
+		{
+			name:         "test case 1 - ways summed over caches",
+			allCacheInfo: capacityTestCacheInfo(),
+			expected:     15,
+		},
+		{
+			name:         "test case 2 - no cache info",
+			allCacheInfo: rmdCache.Infos{},
+			expected:     0,
+		},
+	}
+	for _, tc := range tcases {
+		freeWays := FreeGuaranteedWays(tc.allCacheInfo)
+		if freeWays != tc.expected {
+			t.Errorf("Case %v - Expected %v, got %v", tc.name, tc.expected, freeWays)
+		}
+	}
+}
+
+func TestCheckMbaCapacity(t *testing.T) {
+	tcases := []struct {
+		name        string
+		rdt         intelv1alpha1.Rdt
+		mbaInfo     rmdMba.Info
+		expectedErr bool
+	}{
+		{
+			name: "test case 1 - no MBA requested",
+			rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+			},
+		},
+		{
+			name: "test case 2 - MBA supported",
+			rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+				Mba:   intelv1alpha1.Mba{Percentage: 50},
+			},
+			mbaInfo: rmdMba.Info{Mba: true, MbaOn: true, MbaStep: 10, MbaMin: 10},
+		},
+		{
+			name: "test case 3 - MBA not enabled",
+			rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+				Mba:   intelv1alpha1.Mba{Percentage: 50},
+			},
+			mbaInfo:     rmdMba.Info{Mba: true},
+			expectedErr: true,
+		},
+		{
+			name: "test case 4 - MBA with besteffort cache",
+			rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 4, Min: 2},
+				Mba:   intelv1alpha1.Mba{Percentage: 50},
+			},
+			mbaInfo:     rmdMba.Info{Mba: true, MbaOn: true, MbaStep: 10, MbaMin: 10},
+			expectedErr: true,
+		},
+		{
+			name: "test case 5 - MBA percentage below node minimum",
+			rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+				Mba:   intelv1alpha1.Mba{Percentage: 5},
+			},
+			mbaInfo:     rmdMba.Info{Mba: true, MbaOn: true, MbaStep: 10, MbaMin: 10},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tcases {
+		workloadCR := &intelv1alpha1.RmdWorkload{}
+		workloadCR.Spec.Rdt = tc.rdt
+		err := CheckMbaCapacity(workloadCR, tc.mbaInfo)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("Case %v - Expected error %v, got %v", tc.name, tc.expectedErr, err)
+		}
+	}
+}
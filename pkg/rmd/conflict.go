@@ -0,0 +1,32 @@
+package rmd
+
+import (
+	"strings"
+
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+// FindCoreConflicts returns the owners in coreOwnership whose cores overlap coreIDs, mapped to the
+// overlapping cores in cpuset format. coreOwnership maps an owner (ie RmdWorkload) to its core IDs.
+// Owners with invalid core IDs are skipped, so a single malformed owner cannot block the check of
+// every other workload. An error is only returned if coreIDs are invalid.
+func FindCoreConflicts(coreIDs []string, coreOwnership map[string][]string) (map[string]string, error) {
+	logger := log.WithName("FindCoreConflicts")
+	conflicts := make(map[string]string)
+	coreCPUSet, err := cpuset.Parse(strings.Join(coreIDs, ","))
+	if err != nil {
+		return nil, err
+	}
+	for owner, ownedCoreIDs := range coreOwnership {
+		ownedCPUSet, err := cpuset.Parse(strings.Join(ownedCoreIDs, ","))
+		if err != nil {
+			logger.Info("Skipping owner with invalid core IDs.", "owner", owner, "coreIds", ownedCoreIDs, "Error:", err)
+			continue
+		}
+		overlap := coreCPUSet.Intersection(ownedCPUSet)
+		if !overlap.IsEmpty() {
+			conflicts[owner] = overlap.String()
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,66 @@
+package rmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindCoreConflicts(t *testing.T) {
+	tcases := []struct {
+		name              string
+		coreIDs           []string
+		coreOwnership     map[string][]string
+		expectedConflicts map[string]string
+		expectedErr       bool
+	}{
+		{
+			name:    "test case 1 - no overlap",
+			coreIDs: []string{"0", "1"},
+			coreOwnership: map[string][]string{
+				"default/rmd-workload-1": {"2-3"},
+			},
+			expectedConflicts: map[string]string{},
+		},
+		{
+			name:    "test case 2 - overlap with two owners",
+			coreIDs: []string{"1-4"},
+			coreOwnership: map[string][]string{
+				"default/rmd-workload-1": {"0", "1"},
+				"default/rmd-workload-2": {"3-7"},
+				"default/rmd-workload-3": {"8"},
+			},
+			expectedConflicts: map[string]string{
+				"default/rmd-workload-1": "1",
+				"default/rmd-workload-2": "3-4",
+			},
+		},
+		{
+			name:    "test case 3 - invalid core IDs",
+			coreIDs: []string{"a"},
+			coreOwnership: map[string][]string{
+				"default/rmd-workload-1": {"0"},
+			},
+			expectedErr: true,
+		},
+		{
+			name:    "test case 4 - owner with invalid core IDs skipped",
+			coreIDs: []string{"0-3"},
+			coreOwnership: map[string][]string{
+				"default/rmd-workload-1": {"a"},
+				"default/rmd-workload-2": {"3"},
+			},
+			expectedConflicts: map[string]string{
+				"default/rmd-workload-2": "3",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		conflicts, err := FindCoreConflicts(tc.coreIDs, tc.coreOwnership)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error: %v, Error gotten: %v\n", tc.name, tc.expectedErr, err)
+		}
+		if !tc.expectedErr && !reflect.DeepEqual(tc.expectedConflicts, conflicts) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedConflicts, conflicts)
+		}
+	}
+}
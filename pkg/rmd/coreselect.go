@@ -0,0 +1,72 @@
+package rmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+// SelectCores picks CoreCount cores for workloadCR that share a single l3 cache on the node described
+// by allCacheInfo. Caches are considered in ID order and must match the NumaNode and CacheID of the
+// workload if specified. Cores in unavailableCores (ie owned by other workloads) are never selected
+// and the cache must have enough free ways in the workload's pool.
+func SelectCores(workloadCR *intelv1alpha1.RmdWorkload, allCacheInfo rmdCache.Infos, unavailableCores cpuset.CPUSet) ([]string, error) {
+	coreCount := workloadCR.Spec.CoreCount
+	if coreCount <= 0 {
+		return nil, errors.NewBadRequest(fmt.Sprintf("coreCount %d not supported", coreCount))
+	}
+	if workloadCR.Spec.AllCores || len(workloadCR.Spec.CoreIds) != 0 {
+		return nil, errors.NewBadRequest("coreCount cannot be combined with coreIds or allCores")
+	}
+
+	pool := ""
+	var requiredWays uint32
+	if workloadCR.Spec.Policy == "" && workloadCR.Spec.Rdt.Cache.Max != 0 {
+		var err error
+		pool, requiredWays, err = cachePool(uint32(workloadCR.Spec.Rdt.Cache.Max), uint32(workloadCR.Spec.Rdt.Cache.Min))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cacheIDs := make([]int, 0)
+	for cacheID := range allCacheInfo.Caches {
+		cacheIDs = append(cacheIDs, int(cacheID))
+	}
+	sort.Ints(cacheIDs)
+
+	for _, cacheID := range cacheIDs {
+		cache := allCacheInfo.Caches[uint32(cacheID)]
+		if workloadCR.Spec.CacheID != nil && *workloadCR.Spec.CacheID != cacheID {
+			continue
+		}
+		if workloadCR.Spec.NumaNode != nil && strconv.Itoa(*workloadCR.Spec.NumaNode) != cache.Node {
+			continue
+		}
+		shareCPUSet, err := cpuset.Parse(cache.ShareCPUList)
+		if err != nil {
+			return nil, err
+		}
+		freeCPUs := shareCPUSet.Difference(unavailableCores).ToSlice()
+		if len(freeCPUs) < coreCount {
+			continue
+		}
+		if pool != "" {
+			poolWays, err := cpuset.Parse(cache.AvailableWaysPool[pool])
+			if err != nil {
+				return nil, err
+			}
+			if uint32(poolWays.Size()) < requiredWays {
+				continue
+			}
+		}
+		return []string{cpuset.NewCPUSet(freeCPUs[:coreCount]...).String()}, nil
+	}
+
+	return nil, errors.NewBadRequest(fmt.Sprintf("no l3 cache with %d free cores and enough free cache ways", coreCount))
+}
@@ -0,0 +1,116 @@
+package rmd
+
+import (
+	"reflect"
+	"testing"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	rmdCache "github.com/intel/rmd/modules/cache"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+)
+
+func TestSelectCores(t *testing.T) {
+	cacheInfo := capacityTestCacheInfo()
+	for cacheID, cache := range cacheInfo.Caches {
+		cache.Node = map[uint32]string{0: "0", 1: "1"}[cacheID]
+		cacheInfo.Caches[cacheID] = cache
+	}
+	numaNode1 := 1
+	cacheID0 := 0
+	tcases := []struct {
+		name             string
+		workloadCR       *intelv1alpha1.RmdWorkload
+		allCacheInfo     rmdCache.Infos
+		unavailableCores cpuset.CPUSet
+		expectedCoreIDs  []string
+		expectedErr      bool
+	}{
+		{
+			name: "test case 1 - first free cores on first cache",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 4,
+				},
+			},
+			allCacheInfo:     cacheInfo,
+			unavailableCores: cpuset.NewCPUSet(0, 2),
+			expectedCoreIDs:  []string{"1,3-5"},
+		},
+		{
+			name: "test case 2 - first cache has too few free cores",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 4,
+				},
+			},
+			allCacheInfo:     cacheInfo,
+			unavailableCores: cpuset.NewCPUSet(0, 1, 2, 3, 4),
+			expectedCoreIDs:  []string{"8-11"},
+		},
+		{
+			name: "test case 3 - first cache has too few free ways",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					Rdt: intelv1alpha1.Rdt{
+						Cache: intelv1alpha1.Cache{Max: 3, Min: 3},
+					},
+				},
+			},
+			allCacheInfo: rmdCache.Infos{
+				Num: 2,
+				Caches: map[uint32]rmdCache.Info{
+					0: {ID: 0, ShareCPUList: "0-7", AvailableWaysPool: map[string]string{"guaranteed": "0-1"}},
+					1: {ID: 1, ShareCPUList: "8-15", AvailableWaysPool: map[string]string{"guaranteed": "0-3"}},
+				},
+			},
+			unavailableCores: cpuset.NewCPUSet(),
+			expectedCoreIDs:  []string{"8-9"},
+		},
+		{
+			name: "test case 4 - restricted to NUMA node",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					NumaNode:  &numaNode1,
+				},
+			},
+			allCacheInfo:     cacheInfo,
+			unavailableCores: cpuset.NewCPUSet(8),
+			expectedCoreIDs:  []string{"9-10"},
+		},
+		{
+			name: "test case 5 - restricted cache has too few free cores",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 4,
+					CacheID:   &cacheID0,
+				},
+			},
+			allCacheInfo:     cacheInfo,
+			unavailableCores: cpuset.NewCPUSet(0, 1, 2, 3, 4),
+			expectedErr:      true,
+		},
+		{
+			name: "test case 6 - coreCount combined with coreIds",
+			workloadCR: &intelv1alpha1.RmdWorkload{
+				Spec: intelv1alpha1.RmdWorkloadSpec{
+					CoreCount: 2,
+					CoreIds:   []string{"0"},
+				},
+			},
+			allCacheInfo:     cacheInfo,
+			unavailableCores: cpuset.NewCPUSet(),
+			expectedErr:      true,
+		},
+	}
+	for _, tc := range tcases {
+		coreIDs, err := SelectCores(tc.workloadCR, tc.allCacheInfo, tc.unavailableCores)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("%v failed: Expected error: %v, Error gotten: %v\n", tc.name, tc.expectedErr, err)
+		}
+		if !reflect.DeepEqual(tc.expectedCoreIDs, coreIDs) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedCoreIDs, coreIDs)
+		}
+	}
+}
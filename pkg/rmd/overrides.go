@@ -0,0 +1,52 @@
+package rmd
+
+import (
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ApplyOverrides returns workloadCR as it applies to the node with nodeName and nodeLabels. Overrides
+// targeting the node are applied in order. The returned workload is a copy if any override applies.
+func ApplyOverrides(workloadCR *intelv1alpha1.RmdWorkload, nodeName string, nodeLabels map[string]string) *intelv1alpha1.RmdWorkload {
+	nodeWorkload := workloadCR
+	for _, override := range workloadCR.Spec.Overrides {
+		if !overrideTargetsNode(override, nodeName, nodeLabels) {
+			continue
+		}
+		if nodeWorkload == workloadCR {
+			nodeWorkload = workloadCR.DeepCopy()
+		}
+		// Explicit cores replace any core selection of the workload.
+		if len(override.CoreIds) != 0 {
+			nodeWorkload.Spec.CoreIds = override.CoreIds
+			nodeWorkload.Spec.AllCores = false
+			nodeWorkload.Spec.CoreCount = 0
+		}
+		if len(override.ReservedCoreIds) != 0 {
+			nodeWorkload.Spec.ReservedCoreIds = override.ReservedCoreIds
+		}
+		if override.Cache != nil {
+			nodeWorkload.Spec.Rdt.Cache = *override.Cache
+		}
+		if override.Mba != nil {
+			nodeWorkload.Spec.Rdt.Mba = *override.Mba
+		}
+		if override.Pstate != nil {
+			nodeWorkload.Spec.Plugins.Pstate = *override.Pstate
+		}
+	}
+	return nodeWorkload
+}
+
+// overrideTargetsNode returns true if the node is listed in the override or its labels match the override NodeSelector.
+func overrideTargetsNode(override intelv1alpha1.RmdWorkloadOverride, nodeName string, nodeLabels map[string]string) bool {
+	for _, overrideNodeName := range override.Nodes {
+		if overrideNodeName == nodeName {
+			return true
+		}
+	}
+	if len(override.NodeSelector) == 0 {
+		return false
+	}
+	return labels.SelectorFromSet(labels.Set(override.NodeSelector)).Matches(labels.Set(nodeLabels))
+}
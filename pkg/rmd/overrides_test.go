@@ -0,0 +1,93 @@
+package rmd
+
+import (
+	"reflect"
+	"testing"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	workloadCR := &intelv1alpha1.RmdWorkload{
+		Spec: intelv1alpha1.RmdWorkloadSpec{
+			CoreIds: []string{"0-3"},
+			Rdt: intelv1alpha1.Rdt{
+				Cache: intelv1alpha1.Cache{Max: 2, Min: 2},
+				Mba:   intelv1alpha1.Mba{Percentage: 50},
+			},
+			Plugins: intelv1alpha1.Plugins{
+				Pstate: intelv1alpha1.Pstate{Ratio: "1.5"},
+			},
+			Nodes: []string{"example-node-1.com", "example-node-2.com", "example-node-3.com"},
+			Overrides: []intelv1alpha1.RmdWorkloadOverride{
+				{
+					Nodes:   []string{"example-node-2.com"},
+					CoreIds: []string{"0-7"},
+					Cache:   &intelv1alpha1.Cache{Max: 4, Min: 4},
+				},
+				{
+					NodeSelector: map[string]string{"cpu-rdt.RDTMBA": "false"},
+					Mba:          &intelv1alpha1.Mba{},
+					Pstate:       &intelv1alpha1.Pstate{Ratio: "2.0"},
+				},
+				{
+					Nodes: []string{"example-node-3.com"},
+					Cache: &intelv1alpha1.Cache{Max: 6, Min: 6},
+				},
+			},
+		},
+	}
+	tcases := []struct {
+		name         string
+		nodeName     string
+		nodeLabels   map[string]string
+		expectedSpec func() intelv1alpha1.RmdWorkloadSpec
+	}{
+		{
+			name:     "test case 1 - no override targets node",
+			nodeName: "example-node-1.com",
+			expectedSpec: func() intelv1alpha1.RmdWorkloadSpec {
+				return workloadCR.Spec
+			},
+		},
+		{
+			name:     "test case 2 - override by node name",
+			nodeName: "example-node-2.com",
+			expectedSpec: func() intelv1alpha1.RmdWorkloadSpec {
+				spec := *workloadCR.Spec.DeepCopy()
+				spec.CoreIds = []string{"0-7"}
+				spec.Rdt.Cache = intelv1alpha1.Cache{Max: 4, Min: 4}
+				return spec
+			},
+		},
+		{
+			name:       "test case 3 - overrides by node selector and node name, later override wins",
+			nodeName:   "example-node-3.com",
+			nodeLabels: map[string]string{"cpu-rdt.RDTMBA": "false"},
+			expectedSpec: func() intelv1alpha1.RmdWorkloadSpec {
+				spec := *workloadCR.Spec.DeepCopy()
+				spec.Rdt.Mba = intelv1alpha1.Mba{}
+				spec.Plugins.Pstate = intelv1alpha1.Pstate{Ratio: "2.0"}
+				spec.Rdt.Cache = intelv1alpha1.Cache{Max: 6, Min: 6}
+				return spec
+			},
+		},
+		{
+			name:       "test case 4 - node selector does not match",
+			nodeName:   "example-node-1.com",
+			nodeLabels: map[string]string{"cpu-rdt.RDTMBA": "true"},
+			expectedSpec: func() intelv1alpha1.RmdWorkloadSpec {
+				return workloadCR.Spec
+			},
+		},
+	}
+	for _, tc := range tcases {
+		nodeWorkload := ApplyOverrides(workloadCR, tc.nodeName, tc.nodeLabels)
+		if !reflect.DeepEqual(tc.expectedSpec(), nodeWorkload.Spec) {
+			t.Errorf("%v failed: Expected:  %v, Got:  %v\n", tc.name, tc.expectedSpec(), nodeWorkload.Spec)
+		}
+	}
+	if !reflect.DeepEqual(workloadCR.Spec.CoreIds, []string{"0-3"}) || workloadCR.Spec.Rdt.Cache.Max != 2 {
+		t.Errorf("ApplyOverrides modified the original workload: %v", workloadCR.Spec)
+	}
+}
@@ -9,14 +9,15 @@ import (
 	"encoding/json"
 	"fmt"
 	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/metrics"
 	rmdCache "github.com/intel/rmd/modules/cache"
+	rmdMba "github.com/intel/rmd/modules/mba"
 	rmdtypes "github.com/intel/rmd/modules/workload/types"
 	"io/ioutil"
 	"k8s.io/apimachinery/pkg/api/errors"
 	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
 	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
 	"net/http"
-	"reflect"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"strconv"
 	"strings"
@@ -35,6 +36,7 @@ const (
 	tlsServerName   = "rmd-nameserver"
 	localHostAdd    = "127.0.0.1"
 	guaranteedPool  = "guaranteed"
+	besteffortPool  = "besteffort"
 )
 
 var certPath = "/etc/certs/public/cert.pem"
@@ -76,8 +78,10 @@ func NewOperatorRmdClient() (*OperatorRmdClient, error) {
 	}
 
 	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsConfig,
+		Transport: &metrics.InstrumentedTransport{
+			Next: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
 		},
 	}
 	rmdClient := &OperatorRmdClient{
@@ -104,7 +108,9 @@ func verifyKeyLength(cert tls.Certificate) error {
 
 // NewDefaultOperatorRmdClient returns a default client for testing and debugging
 func NewDefaultOperatorRmdClient() *OperatorRmdClient {
-	defaultClient := &http.Client{}
+	defaultClient := &http.Client{
+		Transport: &metrics.InstrumentedTransport{},
+	}
 	rmdClient := &OperatorRmdClient{
 		client: defaultClient,
 	}
@@ -172,8 +178,73 @@ func UpdateNodeStatusWorkload(workload *rmdtypes.RDTWorkLoad) (intelv1alpha1.Wor
 	return workloadMap, nil
 }
 
-// GetGuaranteedCacheWayPools returns available l3 cache ways for Node Status update
-func (rc *OperatorRmdClient) GetGuaranteedCacheWayPools() (map[string]*pluginapi.Device, error) {
+// UpdateNodeStatusCache returns the inventory of a cache reported by RMD for RmdNodeState status
+func UpdateNodeStatusCache(cache rmdCache.Info) intelv1alpha1.CacheMap {
+	cacheMap := make(intelv1alpha1.CacheMap)
+	cacheMap["Num Ways"] = strconv.Itoa(int(cache.NumWays))
+	cacheMap["Way Size"] = strconv.Itoa(int(cache.WaySize))
+	if cache.NumClasses != 0 {
+		cacheMap["Num Classes"] = strconv.Itoa(int(cache.NumClasses))
+	}
+	if cache.ShareCPUList != "" {
+		cacheMap["Share CPU List"] = cache.ShareCPUList
+	}
+	for _, pool := range []string{guaranteedPool, besteffortPool, "shared"} {
+		if ways, ok := cache.AvailableWaysPool[pool]; ok {
+			cacheMap[fmt.Sprintf("Available %s%s Ways", strings.ToUpper(pool[:1]), pool[1:])] = ways
+		}
+	}
+	return cacheMap
+}
+
+// UpdateNodeStatusCaches returns the cache inventory of allCacheInfo per cache ID for Node Status update
+func UpdateNodeStatusCaches(allCacheInfo rmdCache.Infos) map[string]intelv1alpha1.CacheMap {
+	if len(allCacheInfo.Caches) == 0 {
+		return nil
+	}
+	caches := make(map[string]intelv1alpha1.CacheMap)
+	for cacheID, cache := range allCacheInfo.Caches {
+		caches[strconv.Itoa(int(cacheID))] = UpdateNodeStatusCache(cache)
+	}
+	return caches
+}
+
+// CacheInfoFromNodeStatus returns the cache inventory recorded in an RmdNodeState by
+// UpdateNodeStatusCaches as reported by RMD. Caches with an invalid ID are skipped.
+func CacheInfoFromNodeStatus(caches map[string]intelv1alpha1.CacheMap) rmdCache.Infos {
+	allCacheInfo := rmdCache.Infos{Caches: make(map[uint32]rmdCache.Info)}
+	for id, cacheMap := range caches {
+		cacheID, err := strconv.Atoi(id)
+		if err != nil {
+			continue
+		}
+		cache := rmdCache.Info{
+			ID:                uint32(cacheID),
+			ShareCPUList:      cacheMap["Share CPU List"],
+			AvailableWaysPool: make(map[string]string),
+		}
+		if numWays, err := strconv.Atoi(cacheMap["Num Ways"]); err == nil {
+			cache.NumWays = uint32(numWays)
+		}
+		if waySize, err := strconv.Atoi(cacheMap["Way Size"]); err == nil {
+			cache.WaySize = uint32(waySize)
+		}
+		if numClasses, err := strconv.Atoi(cacheMap["Num Classes"]); err == nil {
+			cache.NumClasses = uint32(numClasses)
+		}
+		for _, pool := range []string{guaranteedPool, besteffortPool, "shared"} {
+			if ways, ok := cacheMap[fmt.Sprintf("Available %s%s Ways", strings.ToUpper(pool[:1]), pool[1:])]; ok {
+				cache.AvailableWaysPool[pool] = ways
+			}
+		}
+		allCacheInfo.Caches[uint32(cacheID)] = cache
+	}
+	allCacheInfo.Num = uint32(len(allCacheInfo.Caches))
+	return allCacheInfo
+}
+
+// GetCacheWayPools returns the available l3 cache ways of pool as devices for the device plugin
+func (rc *OperatorRmdClient) GetCacheWayPools(pool string) (map[string]*pluginapi.Device, error) {
 	devices := make(map[string]*pluginapi.Device)
 	addressPrefix := rc.GetAddressPrefix()
 	var address string
@@ -201,8 +272,8 @@ func (rc *OperatorRmdClient) GetGuaranteedCacheWayPools() (map[string]*pluginapi
 
 	for _, cache := range allCacheInfo.Caches {
 		var cacheWaysSlice []int
-		for pool, cacheWays := range cache.AvailableWaysPool {
-			if pool == guaranteedPool {
+		for poolName, cacheWays := range cache.AvailableWaysPool {
+			if poolName == pool {
 				cacheWaysSlice = cpuset.MustParse(cacheWays).ToSlice()
 			}
 		}
@@ -252,35 +323,110 @@ func (rc *OperatorRmdClient) GetAvailableCacheWays(address string) (int64, error
 	return availableWays, nil
 }
 
-// GetAllCPUS returns available l3 cache ways for Node Status update
-func (rc *OperatorRmdClient) getAllCPUs(address string) (string, error) {
-	logger := log.WithName("getAllCPUs")
+// GetCacheInfo returns l3 cache information reported by RMD instance
+func (rc *OperatorRmdClient) GetCacheInfo(address string) (rmdCache.Infos, error) {
+	return rc.getCacheInfoByLevel(address, "l3")
+}
 
-	httpString := fmt.Sprintf("%s%s", address, "/v1/cache/l3")
+// GetL2CacheInfo returns l2 cache information reported by RMD instance. An error is returned
+// if the RMD instance does not support l2 cache allocation.
+func (rc *OperatorRmdClient) GetL2CacheInfo(address string) (rmdCache.Infos, error) {
+	return rc.getCacheInfoByLevel(address, "l2")
+}
+
+func (rc *OperatorRmdClient) getCacheInfoByLevel(address, level string) (rmdCache.Infos, error) {
+	allCacheInfo := rmdCache.Infos{}
+	httpString := fmt.Sprintf("%s%s%s", address, "/v1/cache/", level)
 	resp, err := rc.client.Get(httpString)
 	if err != nil {
-		return "", err
+		return allCacheInfo, err
 	}
 	defer resp.Body.Close()
 	receivedJSON, err := ioutil.ReadAll(resp.Body) //This reads raw request body
 	if err != nil {
-		return "", err
+		return allCacheInfo, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return allCacheInfo, errors.NewServiceUnavailable(fmt.Sprintf("%s cache info not available: %s", level, strings.TrimSpace(string(receivedJSON))))
 	}
-	allCacheInfo := rmdCache.Infos{}
 	err = json.Unmarshal([]byte(receivedJSON), &allCacheInfo)
+	if err != nil {
+		return allCacheInfo, err
+	}
+	return allCacheInfo, nil
+}
+
+// GetCachesSummary returns the cache allocation features reported by RMD instance, including
+// whether CDP is supported and enabled.
+func (rc *OperatorRmdClient) GetCachesSummary(address string) (rmdCache.CachesSummary, error) {
+	cachesSummary := rmdCache.CachesSummary{}
+	httpString := fmt.Sprintf("%s%s", address, "/v1/cache")
+	resp, err := rc.client.Get(httpString)
+	if err != nil {
+		return cachesSummary, err
+	}
+	defer resp.Body.Close()
+	receivedJSON, err := ioutil.ReadAll(resp.Body) //This reads raw request body
+	if err != nil {
+		return cachesSummary, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return cachesSummary, errors.NewServiceUnavailable(fmt.Sprintf("cache summary not available: %s", strings.TrimSpace(string(receivedJSON))))
+	}
+	err = json.Unmarshal([]byte(receivedJSON), &cachesSummary)
+	if err != nil {
+		return cachesSummary, err
+	}
+	return cachesSummary, nil
+}
+
+// GetMbaInfo returns MBA support information reported by RMD instance
+func (rc *OperatorRmdClient) GetMbaInfo(address string) (rmdMba.Info, error) {
+	mbaInfo := rmdMba.Info{}
+	httpString := fmt.Sprintf("%s%s", address, "/v1/mba")
+	resp, err := rc.client.Get(httpString)
+	if err != nil {
+		return mbaInfo, err
+	}
+	defer resp.Body.Close()
+	receivedJSON, err := ioutil.ReadAll(resp.Body) //This reads raw request body
+	if err != nil {
+		return mbaInfo, err
+	}
+	err = json.Unmarshal([]byte(receivedJSON), &mbaInfo)
+	if err != nil {
+		return mbaInfo, err
+	}
+	return mbaInfo, nil
+}
+
+// GetAllCPUS returns available l3 cache ways for Node Status update
+func (rc *OperatorRmdClient) getAllCPUs(address string) (string, error) {
+	logger := log.WithName("getAllCPUs")
+
+	allCacheInfo, err := rc.GetCacheInfo(address)
+	if err != nil {
+		return "", err
+	}
+	hostCPUSet, err := hostCPUs(allCacheInfo)
 	if err != nil {
 		return "", err
 	}
+	logger.Info("All CPUs discovered on host", "hostCPUSet", hostCPUSet.String())
+	return hostCPUSet.String(), nil
+}
+
+// hostCPUs returns the union of CPUs sharing each l3 cache on the host
+func hostCPUs(allCacheInfo rmdCache.Infos) (cpuset.CPUSet, error) {
 	hostCPUSet := cpuset.NewCPUSet()
 	for _, cache := range allCacheInfo.Caches {
 		cacheCPUSet, err := cpuset.Parse(cache.ShareCPUList)
 		if err != nil {
-			return "", err
+			return cpuset.NewCPUSet(), err
 		}
 		hostCPUSet = hostCPUSet.Union(cacheCPUSet)
 	}
-	logger.Info("All CPUs discovered on host", "hostCPUSet", hostCPUSet.String())
-	return hostCPUSet.String(), nil
+	return hostCPUSet, nil
 }
 
 // GetWorkloads returns all active workloads on RMD instance
@@ -495,8 +641,10 @@ func FindWorkloadByName(workloads []*rmdtypes.RDTWorkLoad, workloadName string)
 
 // GetAddressPrefix returns correct address prefix based on rmdClient
 func (rc *OperatorRmdClient) GetAddressPrefix() string {
-	if reflect.DeepEqual(rc.client, http.DefaultClient) {
-		return httpPrefix
+	if transport, ok := rc.client.Transport.(*metrics.InstrumentedTransport); ok {
+		if next, ok := transport.Next.(*http.Transport); ok && next.TLSClientConfig != nil {
+			return httpsPrefix
+		}
 	}
-	return httpsPrefix
+	return httpPrefix
 }
@@ -535,3 +535,80 @@ func TestVerifyKeyLength(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateNodeStatusCache(t *testing.T) {
+	tcases := []struct {
+		name             string
+		cache            rmdCache.Info
+		expectedCacheMap intelv1alpha1.CacheMap
+	}{
+		{
+			name: "test case 1 - all pools available",
+			cache: rmdCache.Info{
+				NumWays:      8,
+				WaySize:      131072,
+				ShareCPUList: "0-1",
+				AvailableWaysPool: map[string]string{
+					"guaranteed": "0-3",
+					"besteffort": "4-5",
+					"shared":     "6-7",
+				},
+			},
+			expectedCacheMap: intelv1alpha1.CacheMap{
+				"Num Ways":                  "8",
+				"Way Size":                  "131072",
+				"Share CPU List":            "0-1",
+				"Available Guaranteed Ways": "0-3",
+				"Available Besteffort Ways": "4-5",
+				"Available Shared Ways":     "6-7",
+			},
+		},
+		{
+			name: "test case 2 - no pools available",
+			cache: rmdCache.Info{
+				NumWays: 4,
+				WaySize: 65536,
+			},
+			expectedCacheMap: intelv1alpha1.CacheMap{
+				"Num Ways": "4",
+				"Way Size": "65536",
+			},
+		},
+	}
+	for _, tc := range tcases {
+		cacheMap := UpdateNodeStatusCache(tc.cache)
+		if !reflect.DeepEqual(cacheMap, tc.expectedCacheMap) {
+			t.Errorf("Case %v: Expected %v, got %v", tc.name, tc.expectedCacheMap, cacheMap)
+		}
+	}
+}
+
+func TestCacheInfoFromNodeStatus(t *testing.T) {
+	allCacheInfo := rmdCache.Infos{
+		Num: 2,
+		Caches: map[uint32]rmdCache.Info{
+			0: {
+				ID:           0,
+				NumWays:      11,
+				WaySize:      1441792,
+				NumClasses:   16,
+				ShareCPUList: "0-3",
+				AvailableWaysPool: map[string]string{
+					"guaranteed": "2-9",
+					"shared":     "10",
+				},
+			},
+			1: {
+				ID:                1,
+				NumWays:           11,
+				WaySize:           1441792,
+				ShareCPUList:      "4-7",
+				AvailableWaysPool: map[string]string{},
+			},
+		},
+	}
+	result := CacheInfoFromNodeStatus(UpdateNodeStatusCaches(allCacheInfo))
+	if !reflect.DeepEqual(result, allCacheInfo) {
+		t.Errorf("Expected %v, got %v", allCacheInfo, result)
+	}
+}
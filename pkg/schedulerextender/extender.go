@@ -0,0 +1,403 @@
+package schedulerextender
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	"github.com/intel/rmd-operator/pkg/podannotations"
+	"github.com/intel/rmd-operator/pkg/rmd"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
+	extenderv1 "k8s.io/kubernetes/pkg/scheduler/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Paths of the filter and prioritize verbs of the scheduler extender
+const (
+	FilterPath     = "/filter"
+	PrioritizePath = "/prioritize"
+)
+
+const (
+	rmdNodeStatePrefix    = "rmd-node-state-"
+	rmdNodeStateNamespace = "default"
+	l3Cache               = "intel.com/l3_cache_ways"
+	l3CacheBesteffort     = "intel.com/l3_cache_ways_besteffort"
+	cosNameKey            = "Cos Name"
+	guaranteedPool        = "guaranteed"
+	besteffortPool        = "besteffort"
+	shutdownTimeout       = 5 * time.Second
+)
+
+var log = logf.Log.WithName("scheduler_extender")
+
+// Extender is a scheduler extender that places pods requesting l3 cache ways on nodes where RMD can
+// allocate the ways on a single l3 cache, ie a single NUMA node, using the RmdNodeState inventory
+type Extender struct {
+	client  client.Client
+	address string
+}
+
+// blank assignment to verify that Extender implements manager.Runnable
+var _ manager.Runnable = &Extender{}
+
+// Add adds a scheduler extender serving on port to the Manager. The extender is started and stopped
+// with the Manager.
+func Add(mgr manager.Manager, port int) error {
+	return mgr.Add(NewExtender(mgr.GetClient(), fmt.Sprintf(":%d", port)))
+}
+
+// NewExtender returns a scheduler extender reading RmdNodeStates with c and serving on address
+func NewExtender(c client.Client, address string) *Extender {
+	return &Extender{client: c, address: address}
+}
+
+// Start serves the filter and prioritize verbs until stop is closed
+func (e *Extender) Start(stop <-chan struct{}) error {
+	logger := log.WithName("Start")
+	server := &http.Server{Addr: e.address, Handler: e.Handler()}
+	errChan := make(chan error, 1)
+	go func() {
+		logger.Info("Serving scheduler extender", "address", e.address)
+		errChan <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errChan:
+		return err
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
+
+// Handler returns the HTTP handler of the filter and prioritize verbs
+func (e *Extender) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(FilterPath, func(w http.ResponseWriter, r *http.Request) {
+		args := &extenderv1.ExtenderArgs{}
+		if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+			writeResponse(w, &extenderv1.ExtenderFilterResult{Error: err.Error()})
+			return
+		}
+		writeResponse(w, e.Filter(args))
+	})
+	mux.HandleFunc(PrioritizePath, func(w http.ResponseWriter, r *http.Request) {
+		args := &extenderv1.ExtenderArgs{}
+		if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeResponse(w, e.Prioritize(args))
+	})
+	return mux
+}
+
+func writeResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error(err, "Failed to write scheduler extender response")
+	}
+}
+
+// Filter removes the nodes of args on which RMD cannot apply the RDT request of the pod. All nodes are
+// kept for pods not requesting l3 cache ways.
+func (e *Extender) Filter(args *extenderv1.ExtenderArgs) *extenderv1.ExtenderFilterResult {
+	result := &extenderv1.ExtenderFilterResult{FailedNodes: make(extenderv1.FailedNodesMap)}
+	request := getPodRequest(args.Pod)
+	nodeNames := argsNodeNames(args)
+	fits := make(map[string]bool)
+	for _, nodeName := range nodeNames {
+		if request.workloads == 0 {
+			fits[nodeName] = true
+			continue
+		}
+		_, err := e.fitNode(request, nodeName)
+		if err != nil {
+			result.FailedNodes[nodeName] = err.Error()
+			continue
+		}
+		fits[nodeName] = true
+	}
+
+	if args.NodeNames != nil {
+		filteredNames := make([]string, 0)
+		for _, nodeName := range nodeNames {
+			if fits[nodeName] {
+				filteredNames = append(filteredNames, nodeName)
+			}
+		}
+		result.NodeNames = &filteredNames
+	}
+	if args.Nodes != nil {
+		filteredNodes := &corev1.NodeList{}
+		for _, node := range args.Nodes.Items {
+			if fits[node.GetObjectMeta().GetName()] {
+				filteredNodes.Items = append(filteredNodes.Items, node)
+			}
+		}
+		result.Nodes = filteredNodes
+	}
+	return result
+}
+
+// Prioritize scores the nodes of args by the guaranteed l3 cache ways left free on the fullest cache
+// the pod is placed on, favouring nodes with the most free ways. Nodes are scored 0 for pods
+// not requesting l3 cache ways.
+func (e *Extender) Prioritize(args *extenderv1.ExtenderArgs) *extenderv1.HostPriorityList {
+	request := getPodRequest(args.Pod)
+	priorities := make(extenderv1.HostPriorityList, 0)
+	for _, nodeName := range argsNodeNames(args) {
+		score := 0
+		if request.workloads != 0 {
+			score, _ = e.fitNode(request, nodeName)
+		}
+		priorities = append(priorities, extenderv1.HostPriority{Host: nodeName, Score: score})
+	}
+	return &priorities
+}
+
+func argsNodeNames(args *extenderv1.ExtenderArgs) []string {
+	if args.NodeNames != nil {
+		return *args.NodeNames
+	}
+	nodeNames := make([]string, 0)
+	if args.Nodes != nil {
+		for _, node := range args.Nodes.Items {
+			nodeNames = append(nodeNames, node.GetObjectMeta().GetName())
+		}
+	}
+	return nodeNames
+}
+
+// podRequest is the RDT request of a pod. As in the node agent, an RmdWorkload is created for each
+// container requesting l3 cache ways, or one for all containers in pod workload mode, and the
+// container's MBA annotations are applied to it.
+type podRequest struct {
+	// workloads is the number of RmdWorkloads applied at the same time, each one needs a COS
+	workloads int
+	// initRequests are the l3 cache requests of the init containers, which run one at a time
+	initRequests []cacheRequest
+	// requests are the l3 cache requests of the app containers, which are allocated at the same time.
+	// In pod workload mode requests holds the single request of the pod.
+	requests []cacheRequest
+	mba      bool
+	// mbaPercentage is the smallest MBA percentage requested by a container, 0 if none
+	mbaPercentage int
+}
+
+// cacheRequest is a request for l3 cache ways that must be allocated on a single cache
+type cacheRequest struct {
+	cacheWays      int
+	besteffortWays int
+}
+
+func getPodRequest(pod *corev1.Pod) podRequest {
+	request := podRequest{}
+	if pod == nil {
+		return request
+	}
+	initRequests := make([]cacheRequest, 0)
+	appRequests := make([]cacheRequest, 0)
+	for i, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		limit, ok := container.Resources.Limits[corev1.ResourceName(l3Cache)]
+		besteffortLimit, besteffortOk := container.Resources.Limits[corev1.ResourceName(l3CacheBesteffort)]
+		if !ok && !besteffortOk {
+			continue
+		}
+		containerRequest := cacheRequest{cacheWays: int(limit.Value()), besteffortWays: int(besteffortLimit.Value())}
+		if i < len(pod.Spec.InitContainers) {
+			initRequests = append(initRequests, containerRequest)
+		} else {
+			appRequests = append(appRequests, containerRequest)
+		}
+		// Invalid annotations are reported by the node agent once the pod is scheduled
+		settings, _ := podannotations.ForContainer(pod, container.Name)
+		if settings.MbaPercentage != nil {
+			request.mba = true
+			if request.mbaPercentage == 0 || *settings.MbaPercentage < request.mbaPercentage {
+				request.mbaPercentage = *settings.MbaPercentage
+			}
+		}
+		if settings.MbaMbps != nil {
+			request.mba = true
+		}
+	}
+	if len(initRequests) == 0 && len(appRequests) == 0 {
+		return request
+	}
+
+	// As for the effective requests of kube-scheduler, init containers run one at a time before the
+	// app containers, so a pod needs the larger of its largest init container request and the sum of
+	// its app container requests.
+	if mode, _ := podannotations.WorkloadMode(pod); mode == podannotations.PodWorkloadMode {
+		// All containers share a single RmdWorkload and COS in pod workload mode.
+		podRequest := cacheRequest{}
+		for _, appRequest := range appRequests {
+			podRequest.cacheWays += appRequest.cacheWays
+			podRequest.besteffortWays += appRequest.besteffortWays
+		}
+		for _, initRequest := range initRequests {
+			if initRequest.cacheWays > podRequest.cacheWays {
+				podRequest.cacheWays = initRequest.cacheWays
+			}
+			if initRequest.besteffortWays > podRequest.besteffortWays {
+				podRequest.besteffortWays = initRequest.besteffortWays
+			}
+		}
+		request.workloads = 1
+		request.requests = []cacheRequest{podRequest}
+		return request
+	}
+	request.workloads = len(appRequests)
+	if request.workloads == 0 {
+		request.workloads = 1
+	}
+	request.initRequests = initRequests
+	request.requests = appRequests
+	return request
+}
+
+// fitNode returns the score of nodeName for request, or an error describing why RMD on the node
+// cannot satisfy the request
+func (e *Extender) fitNode(request podRequest, nodeName string) (int, error) {
+	rmdNodeState := &intelv1alpha1.RmdNodeState{}
+	err := e.client.Get(context.TODO(), types.NamespacedName{Name: rmdNodeStatePrefix + nodeName, Namespace: rmdNodeStateNamespace}, rmdNodeState)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return 0, fmt.Errorf("RMD not running on node")
+		}
+		return 0, err
+	}
+	return fitNodeState(request, rmdNodeState.Status)
+}
+
+// fitNodeState returns the score of a node with status for request, or an error describing why RMD
+// cannot satisfy the request
+func fitNodeState(request podRequest, status intelv1alpha1.RmdNodeStateStatus) (int, error) {
+	if request.mba {
+		if !status.MbaSupported || !status.MbaEnabled {
+			return 0, fmt.Errorf("MBA not supported or not enabled")
+		}
+		if request.mbaPercentage != 0 && request.mbaPercentage < status.MbaMin {
+			return 0, fmt.Errorf("MBA percentage %d below minimum %d", request.mbaPercentage, status.MbaMin)
+		}
+	}
+
+	allCacheInfo := rmd.CacheInfoFromNodeStatus(status.L3Caches)
+	if len(allCacheInfo.Caches) == 0 {
+		return 0, fmt.Errorf("l3 cache inventory not reported")
+	}
+
+	// Classes of service are shared by all caches of the node.
+	cosNames := make(map[string]bool)
+	for _, workload := range status.Workloads {
+		if workload[cosNameKey] != "" {
+			cosNames[workload[cosNameKey]] = true
+		}
+	}
+	cacheIDs := make([]int, 0)
+	numClasses := 0
+	for cacheID, cache := range allCacheInfo.Caches {
+		cacheIDs = append(cacheIDs, int(cacheID))
+		if cache.NumClasses != 0 && (numClasses == 0 || int(cache.NumClasses) < numClasses) {
+			numClasses = int(cache.NumClasses)
+		}
+	}
+	sort.Ints(cacheIDs)
+	if numClasses != 0 && numClasses-len(cosNames) < request.workloads {
+		return 0, fmt.Errorf("requested %d classes of service, %d available", request.workloads, numClasses-len(cosNames))
+	}
+
+	free := make([]cacheFree, 0)
+	for _, cacheID := range cacheIDs {
+		cache := allCacheInfo.Caches[uint32(cacheID)]
+		cacheWays := cacheFree{numWays: int(cache.NumWays)}
+		if ways, err := cpuset.Parse(cache.AvailableWaysPool[guaranteedPool]); err == nil {
+			cacheWays.ways = ways.Size()
+		}
+		if ways, err := cpuset.Parse(cache.AvailableWaysPool[besteffortPool]); err == nil {
+			cacheWays.besteffortWays = ways.Size()
+		}
+		free = append(free, cacheWays)
+	}
+
+	// The node is scored by the fullest cache a request is placed on.
+	score := schedulerapi.MaxPriority
+	placedScore := func(r cacheRequest, cache cacheFree) {
+		cacheScore := (cache.ways - r.cacheWays) * schedulerapi.MaxPriority / cache.numWays
+		if cacheScore < score {
+			score = cacheScore
+		}
+	}
+	// Init containers run alone, each one only needs to fit on a single cache.
+	for _, initRequest := range request.initRequests {
+		i, err := placeRequest(initRequest, free)
+		if err != nil {
+			return 0, err
+		}
+		placedScore(initRequest, free[i])
+	}
+	// The requests of the app containers are placed together, largest first.
+	requests := append([]cacheRequest{}, request.requests...)
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].cacheWays > requests[j].cacheWays
+	})
+	for _, appRequest := range requests {
+		i, err := placeRequest(appRequest, free)
+		if err != nil {
+			return 0, err
+		}
+		placedScore(appRequest, free[i])
+		free[i].ways -= appRequest.cacheWays
+		free[i].besteffortWays -= appRequest.besteffortWays
+	}
+	return score, nil
+}
+
+// cacheFree holds the free l3 cache ways of a cache
+type cacheFree struct {
+	numWays        int
+	ways           int
+	besteffortWays int
+}
+
+// placeRequest returns the index of the cache of free with the most free guaranteed ways that r fits
+// on, or an error if r does not fit on a single cache
+func placeRequest(r cacheRequest, free []cacheFree) (int, error) {
+	best := -1
+	maxFreeWays := 0
+	maxFreeBesteffortWays := 0
+	for i, cache := range free {
+		if cache.ways > maxFreeWays {
+			maxFreeWays = cache.ways
+		}
+		if cache.besteffortWays > maxFreeBesteffortWays {
+			maxFreeBesteffortWays = cache.besteffortWays
+		}
+		if cache.ways < r.cacheWays || cache.besteffortWays < r.besteffortWays || cache.numWays == 0 {
+			continue
+		}
+		if best < 0 || cache.ways > free[best].ways {
+			best = i
+		}
+	}
+	if best < 0 {
+		if r.besteffortWays != 0 {
+			return -1, fmt.Errorf("requested %d guaranteed and %d best-effort l3 cache ways, at most %d and %d available on a single cache",
+				r.cacheWays, r.besteffortWays, maxFreeWays, maxFreeBesteffortWays)
+		}
+		return -1, fmt.Errorf("requested %d guaranteed l3 cache ways, at most %d available on a single cache", r.cacheWays, maxFreeWays)
+	}
+	return best, nil
+}
@@ -0,0 +1,332 @@
+package schedulerextender
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/intel/rmd-operator/pkg/apis"
+	intelv1alpha1 "github.com/intel/rmd-operator/pkg/apis/intel/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	extenderv1 "k8s.io/kubernetes/pkg/scheduler/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func createExtender(t *testing.T) *Extender {
+	s := scheme.Scheme
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatalf("error adding scheme: (%v)", err)
+	}
+	objects := []runtime.Object{
+		// 6 free guaranteed ways and 2 free best-effort ways on cache 0, 2 guaranteed ways on cache 1,
+		// 14 of 16 classes of service available
+		&intelv1alpha1.RmdNodeState{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-1.com", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-1.com"},
+			Status: intelv1alpha1.RmdNodeStateStatus{
+				Workloads: map[string]intelv1alpha1.WorkloadMap{
+					"rmd-workload-1": {"Cos Name": "0-1-guarantee"},
+					"rmd-workload-2": {"Cos Name": "2-3-guarantee"},
+				},
+				L3Caches: map[string]intelv1alpha1.CacheMap{
+					"0": {"Num Ways": "10", "Num Classes": "16", "Available Guaranteed Ways": "4-9", "Available Besteffort Ways": "2-3"},
+					"1": {"Num Ways": "10", "Num Classes": "16", "Available Guaranteed Ways": "8-9"},
+				},
+				MbaSupported: true,
+				MbaEnabled:   true,
+				MbaMin:       10,
+			},
+		},
+		// 10 free guaranteed ways, no MBA, 1 of 2 classes of service available
+		&intelv1alpha1.RmdNodeState{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-2.com", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-2.com"},
+			Status: intelv1alpha1.RmdNodeStateStatus{
+				Workloads: map[string]intelv1alpha1.WorkloadMap{
+					"rmd-workload-3": {"Cos Name": "0-guarantee"},
+				},
+				L3Caches: map[string]intelv1alpha1.CacheMap{
+					"0": {"Num Ways": "10", "Num Classes": "2", "Available Guaranteed Ways": "0-9"},
+				},
+				L2Caches: map[string]intelv1alpha1.CacheMap{
+					"0": {"Num Ways": "8"},
+				},
+			},
+		},
+		// RMD running, cache inventory not yet reported
+		&intelv1alpha1.RmdNodeState{
+			ObjectMeta: metav1.ObjectMeta{Name: "rmd-node-state-example-node-3.com", Namespace: "default"},
+			Spec:       intelv1alpha1.RmdNodeStateSpec{Node: "example-node-3.com"},
+		},
+	}
+	return NewExtender(fake.NewFakeClient(objects...), ":0")
+}
+
+func createPod(cacheWays []int64, annotations map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", Annotations: annotations},
+	}
+	names := []string{"container1", "container2"}
+	for i, ways := range cacheWays {
+		container := corev1.Container{Name: names[i]}
+		if ways != 0 {
+			container.Resources.Limits = corev1.ResourceList{l3Cache: *resource.NewQuantity(ways, resource.DecimalSI)}
+		}
+		pod.Spec.Containers = append(pod.Spec.Containers, container)
+	}
+	return pod
+}
+
+func createInitPod(initCacheWays, cacheWays int64, annotations map[string]string) *corev1.Pod {
+	pod := createPod([]int64{cacheWays}, annotations)
+	pod.Spec.InitContainers = []corev1.Container{
+		{
+			Name: "init-container1",
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{l3Cache: *resource.NewQuantity(initCacheWays, resource.DecimalSI)},
+			},
+		},
+	}
+	return pod
+}
+
+func createBesteffortPod(cacheWays int64, annotations map[string]string) *corev1.Pod {
+	pod := createPod([]int64{0}, annotations)
+	pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{l3CacheBesteffort: *resource.NewQuantity(cacheWays, resource.DecimalSI)}
+	return pod
+}
+
+var allNodes = []string{"example-node-1.com", "example-node-2.com", "example-node-3.com", "example-node-4.com"}
+
+func TestFilter(t *testing.T) {
+	tcases := []struct {
+		name          string
+		pod           *corev1.Pod
+		expectedNodes []string
+		expectedFails extenderv1.FailedNodesMap
+	}{
+		{
+			name:          "test case 1 - pod not requesting cache",
+			pod:           createPod([]int64{0}, nil),
+			expectedNodes: allNodes,
+			expectedFails: extenderv1.FailedNodesMap{},
+		},
+		{
+			name:          "test case 2 - single container",
+			pod:           createPod([]int64{6}, nil),
+			expectedNodes: []string{"example-node-1.com", "example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 3 - ways of each container not fitting on a cache",
+			pod:           createPod([]int64{4, 3}, nil),
+			expectedNodes: []string{},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-1.com": "requested 3 guaranteed l3 cache ways, at most 2 available on a single cache",
+				"example-node-2.com": "requested 2 classes of service, 1 available",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 4 - mba requested",
+			pod:           createPod([]int64{2}, map[string]string{"container1_mba_percentage": "50"}),
+			expectedNodes: []string{"example-node-1.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-2.com": "MBA not supported or not enabled",
+				"example-node-3.com": "MBA not supported or not enabled",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 5 - mba percentage below minimum",
+			pod:           createPod([]int64{2}, map[string]string{"container1_mba_percentage": "5"}),
+			expectedNodes: []string{},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-1.com": "MBA percentage 5 below minimum 10",
+				"example-node-2.com": "MBA not supported or not enabled",
+				"example-node-3.com": "MBA not supported or not enabled",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 6 - legacy l2 cache annotations ignored",
+			pod:           createPod([]int64{2}, map[string]string{"container1_l2_cache_max": "2"}),
+			expectedNodes: []string{"example-node-1.com", "example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 7 - ways of all containers sharing a pod workload",
+			pod:           createPod([]int64{2, 3}, map[string]string{"rmd.intel.com/workload-mode": "pod"}),
+			expectedNodes: []string{"example-node-1.com", "example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 8 - ways of containers on separate caches",
+			pod:           createPod([]int64{5, 2}, nil),
+			expectedNodes: []string{"example-node-1.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-2.com": "requested 2 classes of service, 1 available",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 9 - ways of all containers sharing a pod workload on one cache",
+			pod:           createPod([]int64{5, 2}, map[string]string{"rmd.intel.com/workload-mode": "pod"}),
+			expectedNodes: []string{"example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-1.com": "requested 7 guaranteed l3 cache ways, at most 6 available on a single cache",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 10 - init container ways not added to container ways",
+			pod:           createInitPod(6, 6, nil),
+			expectedNodes: []string{"example-node-1.com", "example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 11 - init container ways larger than container ways in pod workload",
+			pod:           createInitPod(8, 2, map[string]string{"rmd.intel.com/workload-mode": "pod"}),
+			expectedNodes: []string{"example-node-2.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-1.com": "requested 8 guaranteed l3 cache ways, at most 6 available on a single cache",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 12 - best-effort cache requested",
+			pod:           createBesteffortPod(2, map[string]string{"rmd.intel.com/container1.l3-cache-max": "4"}),
+			expectedNodes: []string{"example-node-1.com"},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-2.com": "requested 0 guaranteed and 2 best-effort l3 cache ways, at most 10 and 0 available on a single cache",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+		{
+			name:          "test case 13 - best-effort cache ways not available",
+			pod:           createBesteffortPod(3, map[string]string{"rmd.intel.com/container1.l3-cache-max": "4"}),
+			expectedNodes: []string{},
+			expectedFails: extenderv1.FailedNodesMap{
+				"example-node-1.com": "requested 0 guaranteed and 3 best-effort l3 cache ways, at most 6 and 2 available on a single cache",
+				"example-node-2.com": "requested 0 guaranteed and 3 best-effort l3 cache ways, at most 10 and 0 available on a single cache",
+				"example-node-3.com": "l3 cache inventory not reported",
+				"example-node-4.com": "RMD not running on node",
+			},
+		},
+	}
+	extender := createExtender(t)
+	for _, tc := range tcases {
+		nodeNames := append([]string{}, allNodes...)
+		result := extender.Filter(&extenderv1.ExtenderArgs{Pod: tc.pod, NodeNames: &nodeNames})
+		if result.NodeNames == nil || !reflect.DeepEqual(*result.NodeNames, tc.expectedNodes) {
+			t.Errorf("%v failed: Expected nodes %v, got %v", tc.name, tc.expectedNodes, result.NodeNames)
+		}
+		if !reflect.DeepEqual(result.FailedNodes, tc.expectedFails) {
+			t.Errorf("%v failed: Expected failed nodes %v, got %v", tc.name, tc.expectedFails, result.FailedNodes)
+		}
+	}
+}
+
+func TestPrioritize(t *testing.T) {
+	tcases := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected extenderv1.HostPriorityList
+	}{
+		{
+			name: "test case 1 - pod not requesting cache",
+			pod:  createPod([]int64{0}, nil),
+			expected: extenderv1.HostPriorityList{
+				{Host: "example-node-1.com", Score: 0},
+				{Host: "example-node-2.com", Score: 0},
+				{Host: "example-node-3.com", Score: 0},
+				{Host: "example-node-4.com", Score: 0},
+			},
+		},
+		{
+			name: "test case 2 - node with most free ways preferred",
+			pod:  createPod([]int64{2}, nil),
+			expected: extenderv1.HostPriorityList{
+				{Host: "example-node-1.com", Score: 4},
+				{Host: "example-node-2.com", Score: 8},
+				{Host: "example-node-3.com", Score: 0},
+				{Host: "example-node-4.com", Score: 0},
+			},
+		},
+	}
+	extender := createExtender(t)
+	for _, tc := range tcases {
+		nodeNames := append([]string{}, allNodes...)
+		result := extender.Prioritize(&extenderv1.ExtenderArgs{Pod: tc.pod, NodeNames: &nodeNames})
+		if !reflect.DeepEqual(*result, tc.expected) {
+			t.Errorf("%v failed: Expected %v, got %v", tc.name, tc.expected, *result)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	extender := createExtender(t)
+	nodes := &corev1.NodeList{Items: []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "example-node-1.com"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "example-node-4.com"}},
+	}}
+	body, err := json.Marshal(&extenderv1.ExtenderArgs{Pod: createPod([]int64{2}, nil), Nodes: nodes})
+	if err != nil {
+		t.Fatalf("error marshalling extender args: (%v)", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	extender.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, FilterPath, bytes.NewReader(body)))
+	result := &extenderv1.ExtenderFilterResult{}
+	if err := json.NewDecoder(recorder.Body).Decode(result); err != nil {
+		t.Fatalf("error decoding filter result: (%v)", err)
+	}
+	if result.Nodes == nil || len(result.Nodes.Items) != 1 || result.Nodes.Items[0].GetObjectMeta().GetName() != "example-node-1.com" {
+		t.Errorf("Expected filtered nodes [example-node-1.com], got %v", result.Nodes)
+	}
+	if result.FailedNodes["example-node-4.com"] == "" {
+		t.Errorf("Expected example-node-4.com to fail, got %v", result.FailedNodes)
+	}
+
+	recorder = httptest.NewRecorder()
+	extender.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, PrioritizePath, bytes.NewReader(body)))
+	priorities := extenderv1.HostPriorityList{}
+	if err := json.NewDecoder(recorder.Body).Decode(&priorities); err != nil {
+		t.Fatalf("error decoding priorities: (%v)", err)
+	}
+	expected := extenderv1.HostPriorityList{{Host: "example-node-1.com", Score: 4}, {Host: "example-node-4.com", Score: 0}}
+	if !reflect.DeepEqual(priorities, expected) {
+		t.Errorf("Expected priorities %v, got %v", expected, priorities)
+	}
+
+	recorder = httptest.NewRecorder()
+	extender.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, FilterPath, bytes.NewReader([]byte("{"))))
+	result = &extenderv1.ExtenderFilterResult{}
+	if err := json.NewDecoder(recorder.Body).Decode(result); err != nil || result.Error == "" {
+		t.Errorf("Expected filter error for invalid args, got %v (%v)", result, err)
+	}
+}